@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"tiktok-live-logger/pkg/archive"
+	"tiktok-live-logger/pkg/database"
+
+	"github.com/spf13/cobra"
+)
+
+var archiveCmd = &cobra.Command{
+	Use:   "archive [session...]",
+	Short: "Move sessions out of the database into compressed files",
+	Long: `Move the events of finished sessions out of the database into one
+compressed archive file per session, to keep the database small.
+
+Sessions are given by ID, or selected with --streamer, --since and --until,
+e.g. --until 2025-12-31 for everything started before 2026. Each archive holds
+the events as NDJSON, one JSON object per line, compressed with zstd or gzip,
+next to a manifest with the event count and SHA-256 checksum. The manifest of
+a compacted session also holds the rollups its likes and viewer counts were
+compacted to. Archives are
+read back and verified before events are deleted.
+
+Archived sessions stay in the database as stubs with their totals, so list
+still shows them and can open them from the archive. Use restore to import
+them again.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		streamer, _ := cmd.Flags().GetString("streamer")
+		sinceArg, _ := cmd.Flags().GetString("since")
+		untilArg, _ := cmd.Flags().GetString("until")
+		dir, _ := cmd.Flags().GetString("dir")
+		compression, _ := cmd.Flags().GetString("compression")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		if len(args) == 0 && streamer == "" && sinceArg == "" && untilArg == "" {
+			return fmt.Errorf("give session IDs or select sessions with --streamer, --since or --until")
+		}
+		if len(args) > 0 && (streamer != "" || sinceArg != "" || untilArg != "") {
+			return fmt.Errorf("give either session IDs or a range with --streamer, --since and --until")
+		}
+		since, until, err := parseRange(sinceArg, untilArg)
+		if err != nil {
+			return err
+		}
+
+		config, err := LoadConfig()
+		if err != nil {
+			return err
+		}
+		if dir == "" {
+			dir = config.ArchiveDir
+		}
+		if compression == "" {
+			compression = config.ArchiveCompression
+		}
+		// The database remembers where archives are, from any directory
+		if dir, err = filepath.Abs(dir); err != nil {
+			return fmt.Errorf("invalid archive directory: %w", err)
+		}
+
+		// Initialize database
+		db, err := database.NewDB(GetDBPath())
+		if err != nil {
+			return fmt.Errorf("failed to initialize database: %w", err)
+		}
+		defer db.Close()
+
+		var sessions []database.Session
+		if len(args) > 0 {
+			for _, arg := range args {
+				session, err := loadSession(db, arg)
+				if err != nil {
+					return err
+				}
+				if session.EndedAt.IsZero() {
+					return fmt.Errorf("session %d is still being logged", session.ID)
+				}
+				sessions = append(sessions, *session)
+			}
+		} else {
+			all, err := db.GetSessions(strings.TrimPrefix(streamer, "@"))
+			if err != nil {
+				return fmt.Errorf("failed to get sessions: %w", err)
+			}
+			for _, s := range sessionsInRange(all, since, until) {
+				if s.Archive == "" && !s.EndedAt.IsZero() {
+					sessions = append(sessions, s)
+				}
+			}
+		}
+		if len(sessions) == 0 {
+			fmt.Println("No sessions to archive")
+			return nil
+		}
+
+		var total int64
+		for _, session := range sessions {
+			events, err := db.GetStoredEvents(session.ID)
+			if err != nil {
+				return fmt.Errorf("failed to get events: %w", err)
+			}
+			if dryRun {
+				fmt.Printf("Would archive session %d (@%s, %s): %d events\n", session.ID, session.Username,
+					session.StartedAt.Format("2006-01-02 15:04"), len(events))
+				total += int64(len(events))
+				continue
+			}
+
+			compaction, err := db.GetCompaction(session.ID)
+			if err != nil {
+				return fmt.Errorf("failed to get rollups: %w", err)
+			}
+			manifest, err := archive.Write(dir, compression, session, events, compaction)
+			if err != nil {
+				return fmt.Errorf("failed to archive session %d: %w", session.ID, err)
+			}
+			n, err := db.ArchiveSession(session.ID, manifest)
+			if err != nil {
+				return fmt.Errorf("failed to remove archived events of session %d: %w", session.ID, err)
+			}
+			total += n
+			fmt.Printf("Archived session %d (@%s, %s): %d events to %s\n", session.ID, session.Username,
+				session.StartedAt.Format("2006-01-02 15:04"), n, manifest)
+		}
+
+		if dryRun {
+			fmt.Printf("Would archive %d events of %d sessions\n", total, len(sessions))
+			return nil
+		}
+		fmt.Printf("Archived %d events of %d sessions\n", total, len(sessions))
+
+		// Give the freed pages back if the database allows it without a full
+		// vacuum, see clean
+		if _, err := db.IncrementalVacuum(); err != nil {
+			return fmt.Errorf("failed to vacuum database: %w", err)
+		}
+		return nil
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <session|manifest>...",
+	Short: "Import archived sessions back into the database",
+	Long: `Import archived sessions back into the database, given by session ID or
+by the path of an archive manifest, e.g. to restore into another database.
+
+Archives are checked against their manifest's checksum first. Restored
+archive files are deleted unless --keep is given.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keep, _ := cmd.Flags().GetBool("keep")
+
+		// Initialize database
+		db, err := database.NewDB(GetDBPath())
+		if err != nil {
+			return fmt.Errorf("failed to initialize database: %w", err)
+		}
+		defer db.Close()
+
+		for _, arg := range args {
+			path := arg
+			if id, err := strconv.ParseInt(arg, 10, 64); err == nil {
+				session, err := db.GetSession(id)
+				if err != nil {
+					return fmt.Errorf("session %d not found", id)
+				}
+				if session.Archive == "" {
+					return fmt.Errorf("session %d is not archived", id)
+				}
+				path = session.Archive
+			} else if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("invalid session ID or manifest %q: %w", arg, err)
+			}
+
+			manifest, events, err := archive.Read(path)
+			if err != nil {
+				return err
+			}
+			s := manifest.Session
+			id, err := db.RestoreSession(database.Session{
+				ID:        s.ID,
+				Username:  s.Username,
+				StartedAt: s.StartedAt,
+				EndedAt:   s.EndedAt,
+			}, events, manifest.DatabaseCompaction())
+			if err != nil {
+				return fmt.Errorf("failed to restore session %d: %w", s.ID, err)
+			}
+			if !keep {
+				if err := archive.Remove(path); err != nil {
+					return fmt.Errorf("failed to remove archive: %w", err)
+				}
+			}
+			fmt.Printf("Restored session %d (@%s, %s): %d events\n", id, s.Username,
+				s.StartedAt.Format("2006-01-02 15:04"), len(events))
+		}
+		return nil
+	},
+}
+
+func init() {
+	archiveCmd.Flags().String("streamer", "", "Only sessions of this streamer")
+	archiveCmd.Flags().String("since", "", "Only sessions started on or after this date (YYYY-MM-DD or RFC 3339)")
+	archiveCmd.Flags().String("until", "", "Only sessions started before the end of this date (YYYY-MM-DD or RFC 3339)")
+	archiveCmd.Flags().String("dir", "", "Archive directory (default: archive_dir from the config)")
+	archiveCmd.Flags().String("compression", "", "Compression: zstd or gzip (default: archive_compression from the config)")
+	archiveCmd.Flags().Bool("dry-run", false, "Show what would be archived without archiving it")
+
+	restoreCmd.Flags().Bool("keep", false, "Keep the archive files after restoring")
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"tiktok-live-logger/pkg/audience"
+	"tiktok-live-logger/pkg/database"
+
+	"github.com/spf13/cobra"
+)
+
+var audienceCmd = &cobra.Command{
+	Use:   "audience <sessions|retention|churn|overlap> <username> [username]",
+	Short: "Analyze returning viewers",
+	Long: `Analyze which viewers come back, keyed on TikTok viewer IDs so renamed
+viewers are still recognized. Viewers count as participants of a session when
+they chatted or sent gifts. Events logged before viewer IDs were stored are
+not included.
+
+Reports:
+  sessions <username>          new and returning participants per session
+  retention <username>         weekly retention of viewers by the week they first participated
+  churn <username>             top supporters who missed the last --recent sessions
+  overlap <username> <other>   viewers two streamers share
+
+--since and --until restrict the sessions analyzed. For the sessions report,
+viewers are still recognized from sessions before --since.`,
+	Args: cobra.RangeArgs(2, 3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+		sinceArg, _ := cmd.Flags().GetString("since")
+		untilArg, _ := cmd.Flags().GetString("until")
+		weeks, _ := cmd.Flags().GetInt("weeks")
+		n, _ := cmd.Flags().GetInt("top")
+		recent, _ := cmd.Flags().GetInt("recent")
+
+		if format != "text" && format != "csv" {
+			return fmt.Errorf("unknown format %q: must be text or csv", format)
+		}
+		report, username := args[0], args[1]
+		if (report == "overlap") != (len(args) == 3) {
+			if report == "overlap" {
+				return fmt.Errorf("usage: audience overlap <username> <username>")
+			}
+			return fmt.Errorf("usage: audience %s <username>", report)
+		}
+		since, until, err := parseRange(sinceArg, untilArg)
+		if err != nil {
+			return err
+		}
+
+		// Initialize database
+		db, err := database.NewDB(GetDBPath())
+		if err != nil {
+			return fmt.Errorf("failed to initialize database: %w", err)
+		}
+		defer db.Close()
+
+		// load returns a streamer's sessions within the range, newest first,
+		// and all of their participations
+		load := func(username string) ([]database.Session, []database.Participation, error) {
+			sessions, err := db.GetSessions(username)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to get sessions: %w", err)
+			}
+			parts, err := db.GetParticipations(username)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to get participants: %w", err)
+			}
+			return sessionsInRange(sessions, since, until), parts, nil
+		}
+
+		sessions, parts, err := load(username)
+		if err != nil {
+			return err
+		}
+		if len(sessions) == 0 {
+			fmt.Println("No sessions found")
+			return nil
+		}
+
+		percent := func(f float64) string {
+			if format == "csv" {
+				return strconv.FormatFloat(f, 'f', 4, 64)
+			}
+			return fmt.Sprintf("%.0f%%", f*100)
+		}
+
+		var t table
+		var summary string
+		switch report {
+		case "sessions":
+			t.header = []string{"session", "started", "participants", "new", "returning", "returning_rate", "gifters", "returning_gifters"}
+			for _, c := range audience.BySession(sessions, parts) {
+				t.add(c.SessionID, c.StartedAt.Format("2006-01-02 15:04"), c.Participants, c.New, c.Returning,
+					percent(c.ReturningRate()), c.Gifters, c.ReturningGifters)
+			}
+
+		case "retention":
+			t.header = []string{"week", "viewers"}
+			for k := 1; k <= weeks; k++ {
+				t.header = append(t.header, fmt.Sprintf("week_%d", k))
+			}
+			for _, c := range audience.Retention(inSessions(parts, sessions), weeks, time.Now()) {
+				row := []any{c.Week.Format("2006-01-02"), c.Size}
+				for k := 1; k <= weeks; k++ {
+					if k < len(c.Active) {
+						row = append(row, percent(c.Rate(k)))
+					} else {
+						row = append(row, "")
+					}
+				}
+				t.add(row...)
+			}
+
+		case "churn":
+			t.header = []string{"rank", "user_id", "nickname", "sessions", "chats", "diamonds", "last_seen"}
+			for _, s := range audience.Churned(sessions, inSessions(parts, sessions), n, recent) {
+				t.add(s.Rank, s.UserID, s.Nickname, s.Sessions, s.Chats, s.Diamonds, s.LastSeen.Format("2006-01-02"))
+			}
+
+		case "overlap":
+			otherSessions, otherParts, err := load(args[2])
+			if err != nil {
+				return err
+			}
+			o := audience.Compare(inSessions(parts, sessions), inSessions(otherParts, otherSessions))
+			summary = fmt.Sprintf("@%s: %d viewers, @%s: %d viewers, shared: %d (%s of all)\n\n",
+				username, o.A, args[2], o.B, o.Both, percent(o.Jaccard()))
+			t.header = []string{"user_id", "nickname", "sessions_" + username, "sessions_" + args[2],
+				"diamonds_" + username, "diamonds_" + args[2]}
+			for _, v := range o.Shared[:min(n, len(o.Shared))] {
+				t.add(v.UserID, v.Nickname, v.SessionsA, v.SessionsB, v.DiamondsA, v.DiamondsB)
+			}
+
+		default:
+			return fmt.Errorf("unknown report %q: must be sessions, retention, churn or overlap", report)
+		}
+
+		var w io.Writer = os.Stdout
+		if output != "" {
+			file, err := os.Create(output)
+			if err != nil {
+				return fmt.Errorf("failed to create output file: %w", err)
+			}
+			defer file.Close()
+			w = file
+		}
+		if format == "csv" {
+			return t.writeCSV(w)
+		}
+		fmt.Fprint(w, summary)
+		if len(t.rows) == 0 {
+			fmt.Fprintln(w, "No viewers found")
+			return nil
+		}
+		return t.writeText(w)
+	},
+}
+
+// inSessions keeps the participations in the given sessions
+func inSessions(parts []database.Participation, sessions []database.Session) []database.Participation {
+	ids := make(map[int64]bool, len(sessions))
+	for _, s := range sessions {
+		ids[s.ID] = true
+	}
+	var result []database.Participation
+	for _, p := range parts {
+		if ids[p.SessionID] {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+func init() {
+	audienceCmd.Flags().StringP("format", "f", "text", "Output format: text or csv")
+	audienceCmd.Flags().StringP("output", "o", "", "Output file path (default: standard output)")
+	audienceCmd.Flags().String("since", "", "Only sessions started on or after this date (YYYY-MM-DD or RFC 3339)")
+	audienceCmd.Flags().String("until", "", "Only sessions started before the end of this date (YYYY-MM-DD or RFC 3339)")
+	audienceCmd.Flags().Int("weeks", 8, "Weeks to follow each cohort (retention)")
+	audienceCmd.Flags().IntP("top", "n", 20, "Number of supporters to consider (churn) or shared viewers to list (overlap)")
+	audienceCmd.Flags().Int("recent", 3, "Sessions a supporter must have missed to count as churned (churn)")
+}
@@ -2,9 +2,13 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"strconv"
+	"time"
 
 	"tiktok-live-logger/pkg/database"
+	"tiktok-live-logger/pkg/logger"
+	"tiktok-live-logger/pkg/retention"
 
 	"github.com/spf13/cobra"
 )
@@ -12,17 +16,49 @@ import (
 var cleanCmd = &cobra.Command{
 	Use:   "clean [days]",
 	Short: "Clean old logs",
-	Long: `Remove logs older than the specified number of days.
-If no days are specified, defaults to 30 days.`,
+	Long: `Remove old events following the retention rules in the config file.
+
+Rules are matched per streamer and event type, first match wins, and either
+delete events after an age or downsample stats events to one per interval.
+Events no rule matches are kept. Without rules or compaction, or when days
+are given, all events older than that many days are removed
+(default_days_to_keep by default).
+
+With compact_after set, like and stats events of finished sessions older
+than that are replaced by per-minute and per-hour rollups, which stats,
+reports and charts read in their place.
+
+Use --dry-run to see what would be removed. Afterwards the database file is
+shrunk as set by the vacuum mode in the retention config.`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		days := 30
-		if len(args) > 0 {
-			var err error
-			days, err = strconv.Atoi(args[0])
-			if err != nil {
-				return fmt.Errorf("invalid number of days: %w", err)
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		vacuum, _ := cmd.Flags().GetString("vacuum")
+
+		config, err := LoadConfig()
+		if err != nil {
+			return err
+		}
+		cfg := config.Retention
+		if vacuum != "" {
+			cfg.Vacuum = vacuum
+		}
+
+		if len(args) > 0 || (len(cfg.Rules) == 0 && cfg.CompactAfter == 0) {
+			days := config.DefaultDaysToKeep
+			if len(args) > 0 {
+				days, err = strconv.Atoi(args[0])
+				if err != nil {
+					return fmt.Errorf("invalid number of days: %w", err)
+				}
 			}
+			if days <= 0 {
+				return fmt.Errorf("invalid number of days: %d", days)
+			}
+			cfg.Rules = []retention.Rule{{MaxAge: retention.Age(time.Duration(days) * 24 * time.Hour)}}
+		}
+		if err := cfg.Validate(); err != nil {
+			return err
 		}
 
 		// Initialize database
@@ -32,13 +68,134 @@ If no days are specified, defaults to 30 days.`,
 		}
 		defer db.Close()
 
-		// Delete old events
-		rows, err := db.DeleteOldEvents(days)
+		result, err := retention.Apply(db, cfg, time.Now(), dryRun)
 		if err != nil {
-			return fmt.Errorf("failed to delete old events: %w", err)
+			return fmt.Errorf("failed to apply retention rules: %w", err)
 		}
 
-		fmt.Printf("Deleted %d events older than %d days\n", rows, days)
+		var rules table
+		rules.header = []string{"rule", "events", "policy"}
+		for i, r := range cfg.Rules {
+			rules.add(i+1, r.Target(), r.Policy())
+		}
+		if err := rules.writeText(os.Stdout); err != nil {
+			return err
+		}
+		if cfg.CompactAfter > 0 {
+			fmt.Printf("Likes and viewer counts are compacted into rollups after %s\n", cfg.CompactAfter)
+		}
+		fmt.Println()
+
+		if len(result.Actions) == 0 {
+			fmt.Println("Nothing to clean")
+			return nil
+		}
+		var actions table
+		actions.header = []string{"rule", "action", "streamer", "type", "events"}
+		for _, a := range result.Actions {
+			rule := "-"
+			if a.Rule > 0 {
+				rule = strconv.Itoa(a.Rule)
+			}
+			actions.add(rule, a.Kind, "@"+a.Username, a.Type, a.Events)
+		}
+		if err := actions.writeText(os.Stdout); err != nil {
+			return err
+		}
+		fmt.Println()
+
+		if dryRun {
+			fmt.Printf("Would delete %d events, downsample away %d stats events and compact %d events into rollups\n",
+				result.Deleted, result.Downsampled, result.Compacted)
+			return nil
+		}
+		fmt.Printf("Deleted %d events, downsampled away %d stats events, compacted %d events into rollups and removed %d empty sessions\n",
+			result.Deleted, result.Downsampled, result.Compacted, result.Sessions)
+
+		before, _, err := db.Size()
+		if err != nil {
+			return fmt.Errorf("failed to get database size: %w", err)
+		}
+		switch cfg.Vacuum {
+		case retention.VacuumNone:
+			return nil
+		case retention.VacuumFull:
+			if err := db.Vacuum(); err != nil {
+				return fmt.Errorf("failed to vacuum database: %w", err)
+			}
+		default:
+			ok, err := db.IncrementalVacuum()
+			if err != nil {
+				return fmt.Errorf("failed to vacuum database: %w", err)
+			}
+			if !ok {
+				// Switching to incremental auto-vacuum takes one full vacuum
+				fmt.Println("Enabling incremental vacuum with a one-time full vacuum")
+				if err := db.Vacuum(); err != nil {
+					return fmt.Errorf("failed to vacuum database: %w", err)
+				}
+			}
+		}
+		after, _, err := db.Size()
+		if err != nil {
+			return fmt.Errorf("failed to get database size: %w", err)
+		}
+		fmt.Printf("Database size %s → %s\n", formatBytes(before), formatBytes(after))
 		return nil
 	},
-} 
\ No newline at end of file
+}
+
+// formatBytes formats a size as B, KB, MB or GB
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	f := float64(n)
+	for _, suffix := range []string{"KB", "MB", "GB"} {
+		f /= unit
+		if f < unit || suffix == "GB" {
+			return fmt.Sprintf("%.1f %s", f, suffix)
+		}
+	}
+	return ""
+}
+
+// startRetention applies the retention rules every interval until the
+// returned stop is called, for loggers that run for days
+func startRetention(db *database.DB, cfg retention.Config, log *logger.Logger) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Duration(cfg.Interval))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			result, err := retention.Apply(db, cfg, time.Now(), false)
+			if err != nil {
+				log.Warn("Failed to apply retention rules", "error", err)
+				continue
+			}
+			if result.Deleted+result.Downsampled+result.Compacted == 0 {
+				continue
+			}
+			log.Info("Applied retention rules", "deleted", result.Deleted,
+				"downsampled", result.Downsampled, "compacted", result.Compacted, "sessions", result.Sessions)
+			if cfg.Vacuum != retention.VacuumNone {
+				// A full vacuum would block logging, so only free pages here
+				if _, err := db.IncrementalVacuum(); err != nil {
+					log.Warn("Failed to vacuum database", "error", err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+func init() {
+	cleanCmd.Flags().Bool("dry-run", false, "Show what would be removed without removing it")
+	cleanCmd.Flags().String("vacuum", "", "Override the vacuum mode: incremental, full or none")
+}
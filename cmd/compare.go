@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"tiktok-live-logger/pkg/compare"
+	"tiktok-live-logger/pkg/database"
+	"tiktok-live-logger/pkg/ui"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+)
+
+var compareCmd = &cobra.Command{
+	Use:   "compare [username...]",
+	Short: "Compare streamers",
+	Long: `Compare streamers over the sessions they started in a date range, or over
+all of their sessions: hours streamed, average and peak viewers, chat messages
+per minute, gift revenue in diamonds, follows and engagement per viewer.
+
+Engagement per viewer is chat messages, gifts, follows and shares divided by
+the average viewer count. Without usernames every logged streamer is compared.
+
+The table opens in the terminal UI, where it can be sorted by any column, or
+is written as CSV or Markdown with --format.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+		sinceArg, _ := cmd.Flags().GetString("since")
+		untilArg, _ := cmd.Flags().GetString("until")
+
+		if format != "tui" && format != "csv" && format != "md" {
+			return fmt.Errorf("unknown format %q: must be tui, csv or md", format)
+		}
+		since, until, err := parseRange(sinceArg, untilArg)
+		if err != nil {
+			return err
+		}
+
+		// Initialize database
+		db, err := database.NewDB(GetDBPath())
+		if err != nil {
+			return fmt.Errorf("failed to initialize database: %w", err)
+		}
+		defer db.Close()
+
+		usernames := args
+		if len(usernames) == 0 {
+			streamers, err := db.GetStreamerSummaries()
+			if err != nil {
+				return fmt.Errorf("failed to get streamers: %w", err)
+			}
+			for _, s := range streamers {
+				usernames = append(usernames, s.Username)
+			}
+		}
+
+		t := table{header: []string{"streamer", "sessions", "hours", "avg_viewers", "peak_viewers",
+			"chats_per_min", "diamonds", "diamonds_per_hour", "follows", "engagement_per_viewer"}}
+		for _, username := range usernames {
+			summaries, err := db.GetSessionSummaries(strings.TrimPrefix(username, "@"))
+			if err != nil {
+				return fmt.Errorf("failed to get sessions of %s: %w", username, err)
+			}
+			var sessions []database.SessionSummary
+			for _, s := range summaries {
+				if inRange(s.StartedAt, since, until) {
+					sessions = append(sessions, s)
+				}
+			}
+
+			row := compare.Build(strings.TrimPrefix(username, "@"), sessions)
+			t.add("@"+row.Username, row.Sessions, fmt.Sprintf("%.1f", row.Hours()),
+				fmt.Sprintf("%.0f", row.AvgViewers), row.PeakViewers, fmt.Sprintf("%.1f", row.ChatRate()),
+				row.Diamonds, fmt.Sprintf("%.0f", row.DiamondsPerHour()), row.Follows,
+				fmt.Sprintf("%.2f", row.Engagement()))
+		}
+		if len(t.rows) == 0 {
+			fmt.Println("No streamers found")
+			return nil
+		}
+
+		if format == "tui" {
+			title := "Streamer comparison"
+			if sinceArg != "" {
+				title += ", from " + sinceArg
+			}
+			if untilArg != "" {
+				title += ", until " + untilArg
+			}
+			p := tea.NewProgram(ui.NewTableView(title, t.header, t.rows), tea.WithAltScreen())
+			if _, err := p.Run(); err != nil {
+				return fmt.Errorf("failed to run UI: %w", err)
+			}
+			return nil
+		}
+
+		var w io.Writer = os.Stdout
+		if output != "" {
+			file, err := os.Create(output)
+			if err != nil {
+				return fmt.Errorf("failed to create output file: %w", err)
+			}
+			defer file.Close()
+			w = file
+		}
+		if format == "csv" {
+			return t.writeCSV(w)
+		}
+		return t.writeMarkdown(w)
+	},
+}
+
+func init() {
+	compareCmd.Flags().StringP("format", "f", "tui", "Output format: tui, csv or md")
+	compareCmd.Flags().StringP("output", "o", "", "Output file path for csv and md (default: standard output)")
+	compareCmd.Flags().String("since", "", "Only sessions started on or after this date (YYYY-MM-DD or RFC 3339)")
+	compareCmd.Flags().String("until", "", "Only sessions started before the end of this date (YYYY-MM-DD or RFC 3339)")
+}
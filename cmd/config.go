@@ -3,8 +3,22 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+
+	"tiktok-live-logger/pkg/archive"
+	"tiktok-live-logger/pkg/database"
+	"tiktok-live-logger/pkg/logger"
+	"tiktok-live-logger/pkg/moderation"
+	"tiktok-live-logger/pkg/privacy"
+	"tiktok-live-logger/pkg/retention"
+	"tiktok-live-logger/pkg/sentiment"
+	"tiktok-live-logger/pkg/sink"
+	"tiktok-live-logger/pkg/theme"
+	"tiktok-live-logger/pkg/ui"
 
 	"github.com/spf13/cobra"
 )
@@ -12,7 +26,132 @@ import (
 type Config struct {
 	DefaultDaysToKeep int    `json:"default_days_to_keep"`
 	DatabasePath      string `json:"database_path"`
+	// DatabaseDSN is a postgres:// URL to store events in PostgreSQL
+	// instead of SQLite
+	DatabaseDSN       string `json:"database_dsn"`
 	DebugMode         bool   `json:"debug_mode"`
+	LogDir            string `json:"log_dir"`
+	LogFormat         string `json:"log_format"`
+	LogLevel          string `json:"log_level"`
+	LogMaxSizeMB      int    `json:"log_max_size_mb"`
+	LogMaxAgeDays     int    `json:"log_max_age_days"`
+	LogMaxBackups     int    `json:"log_max_backups"`
+	ReportTemplateDir string `json:"report_template_dir"`
+	BufferSize        int    `json:"buffer_size"`
+	// ArchiveDir holds the files of archived sessions
+	ArchiveDir         string `json:"archive_dir"`
+	ArchiveCompression string `json:"archive_compression"`
+	// Theme names a built-in theme or one defined in Themes
+	Theme  string                 `json:"theme"`
+	Themes map[string]theme.Theme `json:"themes,omitempty"`
+
+	Moderation moderation.Config `json:"moderation"`
+	Sentiment  sentiment.Config  `json:"sentiment"`
+	Retention  retention.Config  `json:"retention"`
+	Sinks      sink.Config       `json:"sinks"`
+	Privacy    privacy.Config    `json:"privacy"`
+	Layout     ui.Layout         `json:"layout"`
+}
+
+// ConfigDir returns the directory holding the config file, database and logs
+func ConfigDir() string {
+	return filepath.Join(os.Getenv("HOME"), ".tiktok-live-logger")
+}
+
+func configPath() string {
+	return filepath.Join(ConfigDir(), "config.json")
+}
+
+// DefaultConfig returns the configuration used when no config file exists
+func DefaultConfig() *Config {
+	return &Config{
+		DefaultDaysToKeep:  30,
+		DatabasePath:       filepath.Join(ConfigDir(), "events.db"),
+		DebugMode:          false,
+		LogDir:             filepath.Join(ConfigDir(), "logs"),
+		LogFormat:          "text",
+		LogLevel:           "info",
+		LogMaxSizeMB:       10,
+		LogMaxAgeDays:      14,
+		LogMaxBackups:      5,
+		BufferSize:         ui.DefaultBufferSize,
+		ArchiveDir:         filepath.Join(ConfigDir(), "archive"),
+		ArchiveCompression: archive.Zstd,
+		Theme:              theme.Auto,
+		Moderation:         moderation.DefaultConfig(),
+		Sentiment:          sentiment.DefaultConfig(),
+		Retention:          retention.DefaultConfig(),
+		Sinks:              defaultSinks(),
+		Privacy:            privacy.DefaultConfig(),
+		Layout:             ui.DefaultLayout(),
+	}
+}
+
+func defaultSinks() sink.Config {
+	c := sink.DefaultConfig()
+	c.File.Dir = filepath.Join(ConfigDir(), "events")
+	c.Publish.Outbox = filepath.Join(ConfigDir(), "outbox.db")
+	return c
+}
+
+// LoadConfig reads the config file, falling back to defaults for a missing
+// file or missing keys
+func LoadConfig() (*Config, error) {
+	config := DefaultConfig()
+	data, err := os.ReadFile(configPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return config, nil
+		}
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	return config, nil
+}
+
+// Save writes the config file, creating the config directory if needed
+func (c *Config) Save() error {
+	if err := os.MkdirAll(ConfigDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	// The file holds the privacy salt and possibly database credentials.
+	// WriteFile only sets the mode of new files, so older ones are
+	// tightened too.
+	if err := os.WriteFile(configPath(), data, 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := os.Chmod(configPath(), 0600); err != nil {
+		return fmt.Errorf("failed to restrict config file permissions: %w", err)
+	}
+	return nil
+}
+
+// redacted returns a copy of the config safe to show: the salt would let
+// anyone reading along link pseudonyms to viewers, and URLs may hold
+// passwords
+func (c *Config) redacted() Config {
+	shown := *c
+	if shown.Privacy.Salt != "" {
+		shown.Privacy.Salt = "********"
+	}
+	shown.DatabaseDSN = redactURL(shown.DatabaseDSN)
+	shown.Sinks.Publish.URL = redactURL(shown.Sinks.Publish.URL)
+	return shown
+}
+
+// redactURL masks the password in a URL, leaving other values as they are
+func redactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.User == nil {
+		return value
+	}
+	return u.Redacted()
 }
 
 var configCmd = &cobra.Command{
@@ -21,30 +160,15 @@ var configCmd = &cobra.Command{
 	Long: `View and modify the application configuration.
 The configuration file is stored in ~/.tiktok-live-logger/config.json`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		configDir := filepath.Join(os.Getenv("HOME"), ".tiktok-live-logger")
-		configPath := filepath.Join(configDir, "config.json")
-
-		// Create config directory if it doesn't exist
-		if err := os.MkdirAll(configDir, 0755); err != nil {
-			return fmt.Errorf("failed to create config directory: %w", err)
-		}
-
 		// Load existing config or create default
-		config := &Config{
-			DefaultDaysToKeep: 30,
-			DatabasePath:      filepath.Join(configDir, "events.db"),
-			DebugMode:         false,
-		}
-
-		if data, err := os.ReadFile(configPath); err == nil {
-			if err := json.Unmarshal(data, config); err != nil {
-				return fmt.Errorf("failed to parse config file: %w", err)
-			}
+		config, err := LoadConfig()
+		if err != nil {
+			return err
 		}
 
 		// If no subcommand is provided, show current config
 		if len(args) == 0 {
-			data, err := json.MarshalIndent(config, "", "  ")
+			data, err := json.MarshalIndent(config.redacted(), "", "  ")
 			if err != nil {
 				return fmt.Errorf("failed to marshal config: %w", err)
 			}
@@ -70,19 +194,144 @@ The configuration file is stored in ~/.tiktok-live-logger/config.json`,
 				config.DefaultDaysToKeep = days
 			case "database_path":
 				config.DatabasePath = value
+			case "database_dsn":
+				if value != "" && !database.IsPostgres(value) {
+					return fmt.Errorf("invalid database DSN %q: must be a postgres:// URL", value)
+				}
+				config.DatabaseDSN = value
 			case "debug_mode":
 				config.DebugMode = value == "true"
+			case "log_dir":
+				config.LogDir = value
+			case "log_format":
+				if value != "text" && value != "json" {
+					return fmt.Errorf("invalid log format %q: must be text or json", value)
+				}
+				config.LogFormat = value
+			case "log_level":
+				if _, err := logger.ParseLevel(value); err != nil {
+					return err
+				}
+				config.LogLevel = value
+			case "log_max_size_mb", "log_max_age_days", "log_max_backups":
+				var n int
+				if _, err := fmt.Sscanf(value, "%d", &n); err != nil {
+					return fmt.Errorf("invalid number for %s: %w", key, err)
+				}
+				switch key {
+				case "log_max_size_mb":
+					config.LogMaxSizeMB = n
+				case "log_max_age_days":
+					config.LogMaxAgeDays = n
+				case "log_max_backups":
+					config.LogMaxBackups = n
+				}
+			case "report_template_dir":
+				config.ReportTemplateDir = value
+			case "buffer_size":
+				var size int
+				if _, err := fmt.Sscanf(value, "%d", &size); err != nil || size <= 0 {
+					return fmt.Errorf("invalid buffer size: %s", value)
+				}
+				config.BufferSize = size
+			case "archive_dir":
+				config.ArchiveDir = value
+			case "archive_compression":
+				if value != archive.Zstd && value != archive.Gzip {
+					return fmt.Errorf("invalid archive compression %q: must be zstd or gzip", value)
+				}
+				config.ArchiveCompression = value
+			case "theme":
+				if _, err := theme.Resolve(value, config.Themes); err != nil {
+					return err
+				}
+				config.Theme = value
+			case "moderation_enabled":
+				config.Moderation.Enabled = value == "true"
+			case "moderation_banned_words":
+				config.Moderation.BannedWords = splitList(value)
+			case "moderation_flag_links":
+				config.Moderation.FlagLinks = value == "true"
+			case "sentiment_enabled":
+				config.Sentiment.Enabled = value == "true"
+			case "sentiment_scorer":
+				if _, err := sentiment.New(sentiment.Config{Scorer: value}); err != nil {
+					return err
+				}
+				config.Sentiment.Scorer = value
+			case "sentiment_lexicon_file":
+				config.Sentiment.LexiconFile = value
+			case "retention_interval":
+				interval, err := retention.ParseAge(value)
+				if err != nil {
+					return err
+				}
+				config.Retention.Interval = interval
+			case "retention_compact_after":
+				age, err := retention.ParseAge(value)
+				if err != nil {
+					return err
+				}
+				config.Retention.CompactAfter = age
+			case "retention_vacuum":
+				if err := (retention.Config{Vacuum: value}).Validate(); err != nil {
+					return err
+				}
+				config.Retention.Vacuum = value
+			case "sink_database":
+				config.Sinks.Database = value == "true"
+			case "sink_file":
+				config.Sinks.File.Enabled = value == "true"
+			case "sink_file_dir":
+				config.Sinks.File.Dir = value
+			case "sink_file_max_size_mb", "sink_file_fsync_seconds":
+				var n int
+				if _, err := fmt.Sscanf(value, "%d", &n); err != nil {
+					return fmt.Errorf("invalid number for %s: %w", key, err)
+				}
+				if key == "sink_file_max_size_mb" {
+					config.Sinks.File.MaxSizeMB = n
+				} else {
+					config.Sinks.File.FsyncSeconds = n
+				}
+			case "sink_file_fsync":
+				config.Sinks.File.Fsync = value
+			case "publish_broker":
+				config.Sinks.Publish.Broker = value
+			case "publish_url":
+				config.Sinks.Publish.URL = value
+			case "publish_topic":
+				config.Sinks.Publish.Topic = value
+			case "publish_outbox":
+				config.Sinks.Publish.Outbox = value
+			case "privacy_enabled", "privacy_redact_contacts":
+				on, err := strconv.ParseBool(value)
+				if err != nil {
+					return fmt.Errorf("invalid value for %s: %q is not true or false", key, value)
+				}
+				if key == "privacy_enabled" {
+					config.Privacy.Enabled = on
+				} else {
+					config.Privacy.RedactContacts = on
+				}
+			case "privacy_salt":
+				force, _ := cmd.Flags().GetBool("force")
+				if err := setPrivacySalt(config, value, force); err != nil {
+					return err
+				}
 			default:
 				return fmt.Errorf("unknown config key: %s", key)
 			}
 
-			// Save updated config
-			data, err := json.MarshalIndent(config, "", "  ")
-			if err != nil {
-				return fmt.Errorf("failed to marshal config: %w", err)
+			if strings.HasPrefix(key, "sink_") || strings.HasPrefix(key, "publish_") {
+				if err := config.Sinks.Validate(); err != nil {
+					return err
+				}
 			}
-			if err := os.WriteFile(configPath, data, 0644); err != nil {
-				return fmt.Errorf("failed to write config file: %w", err)
+
+			// Save updated config
+			if err := config.Save(); err != nil {
+				return err
 			}
 			fmt.Println("Configuration updated successfully")
 		default:
@@ -91,4 +340,33 @@ The configuration file is stored in ~/.tiktok-live-logger/config.json`,
 
 		return nil
 	},
-} 
\ No newline at end of file
+}
+
+func init() {
+	configCmd.Flags().Bool("force", false, "Replace an existing privacy_salt")
+}
+
+// setPrivacySalt sets the key of the pseudonyms. Replacing a salt gives
+// every viewer a new pseudonym, cutting them off from the events already
+// stored, so that takes force.
+func setPrivacySalt(config *Config, salt string, force bool) error {
+	if config.Privacy.Salt != "" && salt != config.Privacy.Salt {
+		if !force {
+			return fmt.Errorf("privacy_salt is already set: a new salt gives every viewer a new pseudonym, so stored events no longer match them; use --force to replace it")
+		}
+		fmt.Fprintln(os.Stderr, "Warning: replacing privacy_salt, viewers get new pseudonyms and no longer match the events stored under the old ones")
+	}
+	config.Privacy.Salt = salt
+	return nil
+}
+
+// splitList parses a comma-separated config value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
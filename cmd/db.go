@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"tiktok-live-logger/pkg/database"
+
+	"github.com/spf13/cobra"
+)
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Back up and check the database",
+}
+
+// backupStamp is the timestamp format in rotated backup names
+const backupStamp = "20060102-150405"
+
+var dbBackupCmd = &cobra.Command{
+	Use:   "backup <path>",
+	Short: "Back up the database while it may be in use",
+	Long: `Copy the database to a file with SQLite's online backup API. This is safe
+while log is writing to the database, unlike copying the file.
+
+Given a directory, the backup is named after the database with a timestamp,
+e.g. events-20260131-120000.db. With --keep N, backups are always
+timestamped and only the newest N backups of that name are kept.
+
+The backup is checked with a quick integrity check when done.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keep, _ := cmd.Flags().GetInt("keep")
+		if keep < 0 {
+			return fmt.Errorf("invalid --keep %d", keep)
+		}
+
+		if database.IsPostgres(GetDBPath()) {
+			return fmt.Errorf("db backup only works on SQLite databases, back up PostgreSQL with pg_dump")
+		}
+
+		path := args[0]
+		stamp := time.Now().Format(backupStamp)
+		info, err := os.Stat(path)
+		if err == nil && info.IsDir() || strings.HasSuffix(path, string(os.PathSeparator)) {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return fmt.Errorf("failed to create backup directory: %w", err)
+			}
+			name := filepath.Base(GetDBPath())
+			ext := filepath.Ext(name)
+			path = filepath.Join(path, fmt.Sprintf("%s-%s%s", strings.TrimSuffix(name, ext), stamp, ext))
+		} else if keep > 0 {
+			ext := filepath.Ext(path)
+			path = fmt.Sprintf("%s-%s%s", strings.TrimSuffix(path, ext), stamp, ext)
+		}
+
+		// Initialize database
+		db, err := database.NewDB(GetDBPath())
+		if err != nil {
+			return fmt.Errorf("failed to initialize database: %w", err)
+		}
+		defer db.Close()
+
+		start := time.Now()
+		var shown bool
+		err = db.Backup(path, func(remaining, total int) {
+			fmt.Fprintf(os.Stderr, "\rBacking up: %3d%%", (total-remaining)*100/max(total, 1))
+			shown = true
+		})
+		if shown {
+			fmt.Fprint(os.Stderr, "\r\033[K")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to back up database: %w", err)
+		}
+
+		backup, err := database.NewDB(path)
+		if err != nil {
+			return fmt.Errorf("failed to open backup: %w", err)
+		}
+		result, err := backup.Check(true)
+		backup.Close()
+		if err != nil {
+			return fmt.Errorf("failed to check backup: %w", err)
+		}
+		if !result.OK() {
+			return fmt.Errorf("backup %s failed the integrity check, run db check on the database", path)
+		}
+
+		var size int64
+		if info, err := os.Stat(path); err == nil {
+			size = info.Size()
+		}
+		fmt.Printf("Backed up the database to %s (%s) in %s\n", path, formatBytes(size), time.Since(start).Round(time.Millisecond))
+
+		if keep > 0 {
+			removed, err := rotateBackups(path, keep)
+			if err != nil {
+				return fmt.Errorf("failed to remove old backups: %w", err)
+			}
+			for _, old := range removed {
+				fmt.Printf("Removed old backup %s\n", old)
+			}
+		}
+		return nil
+	},
+}
+
+// rotateBackups deletes all but the newest keep timestamped backups named
+// like path, and returns the deleted paths
+func rotateBackups(path string, keep int) ([]string, error) {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	prefix := base[:len(base)-len(backupStamp)]
+
+	matches, err := filepath.Glob(prefix + "*" + ext)
+	if err != nil {
+		return nil, err
+	}
+	var backups []string
+	for _, m := range matches {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(m, prefix), ext)
+		if _, err := time.Parse(backupStamp, stamp); err == nil {
+			backups = append(backups, m)
+		}
+	}
+	// Timestamps sort by name, newest last
+	slices.Sort(backups)
+
+	var removed []string
+	for len(backups) > keep {
+		if err := os.Remove(backups[0]); err != nil {
+			return removed, err
+		}
+		removed = append(removed, backups[0])
+		backups = backups[1:]
+	}
+	return removed, nil
+}
+
+var dbCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check the database for corruption",
+	Long: `Check the database with SQLite's integrity and foreign key checks and
+report any problems found. Exits with an error if there are any.
+
+Use --quick on large databases to skip checking that indexes match their
+tables.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		quick, _ := cmd.Flags().GetBool("quick")
+
+		// Initialize database
+		db, err := database.NewDB(GetDBPath())
+		if err != nil {
+			return fmt.Errorf("failed to initialize database: %w", err)
+		}
+		defer db.Close()
+
+		result, err := db.Check(quick)
+		if err != nil {
+			return fmt.Errorf("failed to check database: %w", err)
+		}
+
+		if len(result.Integrity) == 0 {
+			fmt.Println("Integrity check: ok")
+		} else {
+			fmt.Printf("Integrity check: %d problems\n", len(result.Integrity))
+			for _, line := range result.Integrity {
+				fmt.Printf("  %s\n", line)
+			}
+		}
+
+		if len(result.ForeignKeys) == 0 {
+			fmt.Println("Foreign key check: ok")
+		} else {
+			fmt.Println("Foreign key check: rows refer to missing rows")
+			for _, p := range result.ForeignKeys {
+				ids := make([]string, len(p.RowIDs))
+				for i, id := range p.RowIDs {
+					ids[i] = fmt.Sprint(id)
+				}
+				more := ""
+				if p.Rows > len(p.RowIDs) {
+					more = ", ..."
+				}
+				fmt.Printf("  %d %s rows refer to missing %s (rows %s%s)\n", p.Rows, p.Table, p.Parent,
+					strings.Join(ids, ", "), more)
+			}
+		}
+
+		if len(result.Integrity) > 0 {
+			fmt.Println()
+			fmt.Println("The database file is damaged. Restore a backup made with db backup, or export what is still readable.")
+		} else if len(result.ForeignKeys) > 0 {
+			fmt.Println()
+			fmt.Println("Rows referring to missing rows are left over from interrupted or manual deletes and don't affect logging.")
+		}
+		if !result.OK() {
+			return fmt.Errorf("database check failed")
+		}
+		return nil
+	},
+}
+
+func init() {
+	dbBackupCmd.Flags().Int("keep", 0, "Timestamp the backup and keep only the newest N backups")
+	dbCheckCmd.Flags().Bool("quick", false, "Run the faster quick_check instead of integrity_check")
+
+	dbCmd.AddCommand(dbBackupCmd)
+	dbCmd.AddCommand(dbCheckCmd)
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"tiktok-live-logger/pkg/database"
+	"tiktok-live-logger/pkg/subtitle"
+
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export [username]",
+	Short: "Export logged events",
+	Long: `Export logged events to a file.
+
+The json, txt and db formats export every event of a streamer.
+
+The srt and vtt formats export the chat and gift events of a single session
+(--session) as subtitle cues timed relative to the session start, so the
+transcript can be overlaid on a recording of the stream. Use --offset to shift
+the cues when the recording didn't start with the session.
+
+--language keeps only chat messages detected in the given languages, as ISO
+639-1 codes. Other events are exported as usual.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+		sessionArg, _ := cmd.Flags().GetString("session")
+		languages, _ := cmd.Flags().GetStringSlice("language")
+
+		// Initialize database
+		db, err := database.NewDB(GetDBPath())
+		if err != nil {
+			return fmt.Errorf("failed to initialize database: %w", err)
+		}
+		defer db.Close()
+
+		switch format {
+		case "json", "txt", "db":
+			if len(args) == 0 {
+				return fmt.Errorf("a username is required for the %s format", format)
+			}
+			username := args[0]
+			if output == "" {
+				output = fmt.Sprintf("%s.%s", username, format)
+			}
+
+			switch format {
+			case "json":
+				err = db.ExportToJSON(username, output, languages...)
+			case "txt":
+				err = db.ExportToTXT(username, output, languages...)
+			case "db":
+				err = db.ExportToDB(username, output, languages...)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to export events: %w", err)
+			}
+
+		case "srt", "vtt":
+			if sessionArg == "" {
+				return fmt.Errorf("--session is required for the %s format", format)
+			}
+			session, err := loadSession(db, sessionArg)
+			if err != nil {
+				return err
+			}
+			if output == "" {
+				output = fmt.Sprintf("%s-session-%d.%s", session.Username, session.ID, format)
+			}
+			if err := exportSubtitles(cmd, db, session, format, output); err != nil {
+				return err
+			}
+
+		default:
+			return fmt.Errorf("unknown format %q: must be json, txt, db, srt or vtt", format)
+		}
+
+		fmt.Printf("Exported to %s\n", output)
+		return nil
+	},
+}
+
+func exportSubtitles(cmd *cobra.Command, db *database.DB, session *database.Session, format, output string) error {
+	opts := subtitle.DefaultOptions()
+	opts.Offset, _ = cmd.Flags().GetDuration("offset")
+	opts.CueDuration, _ = cmd.Flags().GetDuration("cue-duration")
+	opts.MaxLines, _ = cmd.Flags().GetInt("max-lines")
+	types, _ := cmd.Flags().GetStringSlice("types")
+	languages, _ := cmd.Flags().GetStringSlice("language")
+
+	include := make(map[string]bool)
+	for _, t := range types {
+		include[strings.TrimSpace(t)] = true
+	}
+
+	events, err := db.GetEventsBySession(session.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get events: %w", err)
+	}
+
+	var lines []subtitle.Line
+	for _, event := range events {
+		if event.Type == "chat" && len(languages) > 0 && !slices.Contains(languages, event.Language) {
+			continue
+		}
+		if include[event.Type] {
+			lines = append(lines, subtitle.Line{At: event.Timestamp, Text: event.Content})
+		}
+	}
+	cues := subtitle.Build(lines, session.StartedAt, opts)
+
+	file, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	if format == "srt" {
+		err = subtitle.WriteSRT(file, cues)
+	} else {
+		err = subtitle.WriteVTT(file, cues)
+	}
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write subtitles: %w", err)
+	}
+	return file.Close()
+}
+
+func init() {
+	defaults := subtitle.DefaultOptions()
+	exportCmd.Flags().StringP("format", "f", "json", "Export format: json, txt, db, srt or vtt")
+	exportCmd.Flags().StringP("output", "o", "", "Output file path")
+	exportCmd.Flags().StringP("session", "s", "", "Session ID to export (srt and vtt)")
+	exportCmd.Flags().Duration("offset", 0, "Shift all cues by this duration, e.g. 12s or -1m30s (srt and vtt)")
+	exportCmd.Flags().Duration("cue-duration", defaults.CueDuration, "How long each message stays on screen (srt and vtt)")
+	exportCmd.Flags().Int("max-lines", defaults.MaxLines, "Maximum number of messages on screen at once (srt and vtt)")
+	exportCmd.Flags().StringSlice("language", nil, "Only export chat messages in these languages, e.g. es,pt")
+	exportCmd.Flags().StringSlice("types", []string{"chat", "gift"}, "Event types to include (srt and vtt)")
+}
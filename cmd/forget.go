@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"tiktok-live-logger/pkg/database"
+	"tiktok-live-logger/pkg/privacy"
+
+	"github.com/spf13/cobra"
+)
+
+var forgetCmd = &cobra.Command{
+	Use:   "forget <user>",
+	Short: "Delete or anonymize all data of a viewer",
+	Long: `Delete all events of a viewer across every session and streamer, for
+requests to erase their data.
+
+The viewer is given by nickname, or by TikTok user ID with --id. Events stored
+in privacy mode are found by the viewer's pseudonym, which is derived from the
+salt in the config; a pseudonym can also be given directly.
+
+With --anonymize the events are kept for statistics, such as gift and like
+totals, but their nickname and user ID are removed and chat messages are
+replaced. Use --dry-run to see how many events would be affected.
+
+Archived sessions and event files or brokers written to by sinks other than
+the database are not changed.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		byID, _ := cmd.Flags().GetBool("id")
+		anonymize, _ := cmd.Flags().GetBool("anonymize")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		config, err := LoadConfig()
+		if err != nil {
+			return err
+		}
+
+		// Match the viewer as logged and as pseudonymized
+		var pseudonyms *privacy.Scrubber
+		if config.Privacy.Salt != "" {
+			pseudonyms, err = privacy.New(privacy.Config{Enabled: true, Salt: config.Privacy.Salt})
+			if err != nil {
+				return err
+			}
+		}
+		var viewer database.Viewer
+		if byID {
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil || id <= 0 {
+				return fmt.Errorf("invalid user ID: %s", args[0])
+			}
+			viewer.UserIDs = []int64{id}
+			if pseudonyms != nil {
+				viewer.UserIDs = append(viewer.UserIDs, pseudonyms.UserID(id))
+			}
+		} else {
+			viewer.Nicknames = []string{args[0]}
+			if pseudonyms != nil {
+				viewer.Nicknames = append(viewer.Nicknames, pseudonyms.Nickname(args[0]))
+			}
+		}
+
+		// Initialize database
+		db, err := database.NewDB(GetDBPath())
+		if err != nil {
+			return fmt.Errorf("failed to initialize database: %w", err)
+		}
+		defer db.Close()
+
+		events, sessions, err := db.CountViewerEvents(viewer)
+		if err != nil {
+			return fmt.Errorf("failed to find viewer events: %w", err)
+		}
+		if err := warnArchived(db); err != nil {
+			return err
+		}
+		if events == 0 {
+			fmt.Printf("No events of %s found\n", args[0])
+			return nil
+		}
+
+		action, done := "delete", "Deleted"
+		if anonymize {
+			action, done = "anonymize", "Anonymized"
+		}
+		if dryRun {
+			fmt.Printf("Would %s %d events of %s in %d sessions\n", action, events, args[0], sessions)
+			return nil
+		}
+
+		var n int64
+		if anonymize {
+			n, err = db.AnonymizeViewer(viewer)
+		} else {
+			n, err = db.DeleteViewer(viewer)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to %s viewer events: %w", action, err)
+		}
+		fmt.Printf("%s %d events of %s in %d sessions\n", done, n, args[0], sessions)
+		return nil
+	},
+}
+
+// warnArchived points out archived sessions, whose events forget can't reach
+func warnArchived(db *database.DB) error {
+	sessions, err := db.GetSessions("")
+	if err != nil {
+		return fmt.Errorf("failed to get sessions: %w", err)
+	}
+	var archived int
+	for _, s := range sessions {
+		if s.Archive != "" {
+			archived++
+		}
+	}
+	if archived > 0 {
+		fmt.Printf("%d archived sessions were not searched; restore them and run forget again to include them\n", archived)
+	}
+	return nil
+}
+
+func init() {
+	forgetCmd.Flags().Bool("id", false, "Treat the user as a TikTok user ID instead of a nickname")
+	forgetCmd.Flags().Bool("anonymize", false, "Keep the events without the viewer's identity instead of deleting them")
+	forgetCmd.Flags().Bool("dry-run", false, "Show how many events would be affected without changing them")
+}
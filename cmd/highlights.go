@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"tiktok-live-logger/pkg/database"
+	"tiktok-live-logger/pkg/highlights"
+
+	"github.com/spf13/cobra"
+)
+
+var highlightsCmd = &cobra.Command{
+	Use:   "highlights <session>",
+	Short: "Find the highlights of a session",
+	Long: `Find the moments of a session where chat rate, gift value, likes or the
+viewer count spiked compared to the minutes before, for clipping and recaps.
+
+The session is split into --bucket windows. Each window is scored against the
+median of the preceding --baseline, in robust standard deviations, and windows
+scoring at least --threshold on any metric become highlights. Neighbouring
+windows are merged. Highlights are ranked by score and shown with their time
+of day and their offset into the session.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+		bucket, _ := cmd.Flags().GetDuration("bucket")
+		baseline, _ := cmd.Flags().GetDuration("baseline")
+		threshold, _ := cmd.Flags().GetFloat64("threshold")
+		n, _ := cmd.Flags().GetInt("top")
+
+		if format != "text" && format != "json" && format != "md" {
+			return fmt.Errorf("unknown format %q: must be text, json or md", format)
+		}
+		if bucket <= 0 || baseline < bucket {
+			return fmt.Errorf("--bucket must be positive and no longer than --baseline")
+		}
+
+		// Initialize database
+		db, err := database.NewDB(GetDBPath())
+		if err != nil {
+			return fmt.Errorf("failed to initialize database: %w", err)
+		}
+		defer db.Close()
+
+		session, err := loadSession(db, args[0])
+		if err != nil {
+			return err
+		}
+		events, err := db.GetEventsBySession(session.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get events: %w", err)
+		}
+
+		found, err := highlights.Detect(*session, events, highlights.Options{
+			Bucket:    bucket,
+			Baseline:  baseline,
+			Threshold: threshold,
+			Limit:     n,
+		})
+		if err != nil {
+			return err
+		}
+
+		var w io.Writer = os.Stdout
+		if output != "" {
+			file, err := os.Create(output)
+			if err != nil {
+				return fmt.Errorf("failed to create output file: %w", err)
+			}
+			defer file.Close()
+			w = file
+		}
+
+		switch format {
+		case "json":
+			if found == nil {
+				found = []highlights.Highlight{}
+			}
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			return enc.Encode(struct {
+				Session    int64                  `json:"session"`
+				Username   string                 `json:"username"`
+				StartedAt  time.Time              `json:"started_at"`
+				Highlights []highlights.Highlight `json:"highlights"`
+			}{session.ID, session.Username, session.StartedAt, found})
+		case "md":
+			writeHighlightsMarkdown(w, session, found)
+		default:
+			writeHighlightsText(w, session, found)
+		}
+		return nil
+	},
+}
+
+func writeHighlightsText(w io.Writer, session *database.Session, found []highlights.Highlight) {
+	fmt.Fprintf(w, "Session %d, @%s, %s\n", session.ID, session.Username, session.StartedAt.Format("2006-01-02 15:04:05"))
+	if len(found) == 0 {
+		fmt.Fprintln(w, "No highlights found")
+		return
+	}
+	for _, h := range found {
+		fmt.Fprintf(w, "\n#%d  %s  (+%s, %s)  score %.1f\n", h.Rank, h.Start.Format("2006-01-02 15:04:05"),
+			clock(h.Offset), h.Duration, h.Score)
+		for _, s := range h.Spikes {
+			fmt.Fprintf(w, "    %-8s %s vs %s\n", s.Metric, formatAmount(s.Peak), formatAmount(s.Baseline))
+		}
+		fmt.Fprintf(w, "    %d chat messages, %d diamonds\n", h.Chats, h.Diamonds)
+		for _, msg := range h.Messages {
+			fmt.Fprintf(w, "    > %s\n", msg)
+		}
+	}
+}
+
+func writeHighlightsMarkdown(w io.Writer, session *database.Session, found []highlights.Highlight) {
+	fmt.Fprintf(w, "# Highlights of @%s, %s\n\n", session.Username, session.StartedAt.Format("2006-01-02 15:04"))
+	if len(found) == 0 {
+		fmt.Fprintln(w, "No highlights found.")
+		return
+	}
+	fmt.Fprintln(w, "| # | Time | Offset | Length | Score | Spikes | Chat | Diamonds |")
+	fmt.Fprintln(w, "|---|------|--------|--------|-------|--------|------|----------|")
+	for _, h := range found {
+		spikes := make([]string, len(h.Spikes))
+		for i, s := range h.Spikes {
+			spikes[i] = fmt.Sprintf("%s %s vs %s", s.Metric, formatAmount(s.Peak), formatAmount(s.Baseline))
+		}
+		fmt.Fprintf(w, "| %d | %s | %s | %s | %.1f | %s | %d | %d |\n", h.Rank, h.Start.Format("15:04:05"),
+			clock(h.Offset), h.Duration, h.Score, strings.Join(spikes, ", "), h.Chats, h.Diamonds)
+	}
+
+	// Sample chat for context below the table
+	for _, h := range found {
+		if len(h.Messages) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n**#%d at %s**\n\n", h.Rank, clock(h.Offset))
+		for _, msg := range h.Messages {
+			fmt.Fprintf(w, "> %s\n", strings.ReplaceAll(msg, "\n", " "))
+		}
+	}
+}
+
+// clock formats an offset into the session as H:MM:SS
+func clock(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
+
+// formatAmount prints whole numbers without decimals
+func formatAmount(f float64) string {
+	if f == float64(int64(f)) {
+		return fmt.Sprintf("%d", int64(f))
+	}
+	return fmt.Sprintf("%.1f", f)
+}
+
+func init() {
+	d := highlights.DefaultOptions()
+	highlightsCmd.Flags().StringP("format", "f", "text", "Output format: text, json or md")
+	highlightsCmd.Flags().StringP("output", "o", "", "Output file path (default: standard output)")
+	highlightsCmd.Flags().Duration("bucket", d.Bucket, "Length of the windows activity is counted in")
+	highlightsCmd.Flags().Duration("baseline", d.Baseline, "How far back the rolling baseline looks")
+	highlightsCmd.Flags().Float64("threshold", d.Threshold, "Score a window needs to become a highlight")
+	highlightsCmd.Flags().IntP("top", "n", d.Limit, "Number of highlights to show")
+}
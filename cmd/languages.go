@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"tiktok-live-logger/pkg/database"
+	"tiktok-live-logger/pkg/language"
+
+	"github.com/spf13/cobra"
+)
+
+var languagesCmd = &cobra.Command{
+	Use:   "languages [session]",
+	Short: "Show which languages chat was written in",
+	Long: `Show the languages of chat messages, per session or for a single session.
+
+Languages are detected offline while logging. Chat logged before languages
+were detected is detected on first use and stored. Messages whose language
+can't be told, such as emoji-only messages, count as undetermined (und).
+
+Use the language codes with export --language to export the chat of a single
+language, or press L in the live view to cycle through them.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		streamer, _ := cmd.Flags().GetString("streamer")
+		sinceArg, _ := cmd.Flags().GetString("since")
+		untilArg, _ := cmd.Flags().GetString("until")
+		n, _ := cmd.Flags().GetInt("top")
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+
+		if format != "text" && format != "csv" {
+			return fmt.Errorf("unknown format %q: must be text or csv", format)
+		}
+		if len(args) > 0 && (streamer != "" || sinceArg != "" || untilArg != "") {
+			return fmt.Errorf("give either a session ID or a range with --streamer, --since and --until")
+		}
+		since, until, err := parseRange(sinceArg, untilArg)
+		if err != nil {
+			return err
+		}
+
+		// Initialize database
+		db, err := database.NewDB(GetDBPath())
+		if err != nil {
+			return fmt.Errorf("failed to initialize database: %w", err)
+		}
+		defer db.Close()
+
+		var sessions []database.Session
+		if len(args) > 0 {
+			session, err := loadSession(db, args[0])
+			if err != nil {
+				return err
+			}
+			sessions = append(sessions, *session)
+		} else {
+			all, err := db.GetSessions(strings.TrimPrefix(streamer, "@"))
+			if err != nil {
+				return fmt.Errorf("failed to get sessions: %w", err)
+			}
+			sessions = sessionsInRange(all, since, until)
+		}
+		if len(sessions) == 0 {
+			fmt.Println("No sessions found")
+			return nil
+		}
+
+		share := func(count, total int64) string {
+			f := float64(count) / float64(max(total, 1))
+			if format == "csv" {
+				return strconv.FormatFloat(f, 'f', 4, 64)
+			}
+			return fmt.Sprintf("%.0f%%", f*100)
+		}
+
+		var t table
+		for _, session := range sessions {
+			if err := detectLanguages(db, session.ID); err != nil {
+				return fmt.Errorf("session %d: %w", session.ID, err)
+			}
+			counts, err := db.GetLanguageCounts(session.ID)
+			if err != nil {
+				return fmt.Errorf("failed to count languages: %w", err)
+			}
+			var total int64
+			for _, c := range counts {
+				total += c.Messages
+			}
+
+			if len(args) > 0 {
+				t.header = []string{"language", "name", "messages", "share"}
+				for _, c := range counts {
+					t.add(c.Language, language.Name(c.Language), c.Messages, share(c.Messages, total))
+				}
+				continue
+			}
+
+			t.header = []string{"session", "streamer", "started", "messages", "languages"}
+			var top []string
+			for _, c := range counts[:min(n, len(counts))] {
+				top = append(top, c.Language+" "+share(c.Messages, total))
+			}
+			t.add(session.ID, "@"+session.Username, session.StartedAt.Format("2006-01-02 15:04"), total,
+				strings.Join(top, ", "))
+		}
+
+		var w io.Writer = os.Stdout
+		if output != "" {
+			file, err := os.Create(output)
+			if err != nil {
+				return fmt.Errorf("failed to create output file: %w", err)
+			}
+			defer file.Close()
+			w = file
+		}
+		if format == "csv" {
+			return t.writeCSV(w)
+		}
+		if len(t.rows) == 0 {
+			fmt.Fprintln(w, "No chat messages found")
+			return nil
+		}
+		return t.writeText(w)
+	},
+}
+
+// detectLanguages detects and stores the language of a session's chat
+// messages logged before languages were detected
+func detectLanguages(db *database.DB, sessionID int64) error {
+	events, err := db.GetChatEvents(sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get chat events: %w", err)
+	}
+	var detected []database.EventLanguage
+	for _, e := range events {
+		if e.Language == "" {
+			detected = append(detected, database.EventLanguage{EventID: e.ID, Language: language.Detect(e.Message())})
+		}
+	}
+	if len(detected) == 0 {
+		return nil
+	}
+	if err := db.SaveLanguages(detected); err != nil {
+		return fmt.Errorf("failed to save languages: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	languagesCmd.Flags().String("streamer", "", "Only sessions of this streamer")
+	languagesCmd.Flags().String("since", "", "Only sessions started on or after this date (YYYY-MM-DD or RFC 3339)")
+	languagesCmd.Flags().String("until", "", "Only sessions started before the end of this date (YYYY-MM-DD or RFC 3339)")
+	languagesCmd.Flags().IntP("top", "n", 3, "Number of languages listed per session")
+	languagesCmd.Flags().StringP("format", "f", "text", "Output format: text or csv")
+	languagesCmd.Flags().StringP("output", "o", "", "Output file path (default: standard output)")
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"tiktok-live-logger/pkg/database"
+	"tiktok-live-logger/pkg/tiktok"
+	"tiktok-live-logger/pkg/ui"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+)
+
+// runLauncher asks for a streamer, then tracks them live or opens their
+// logged sessions
+func runLauncher(cmd *cobra.Command) error {
+	choice, err := launch()
+	if err != nil {
+		return err
+	}
+
+	switch choice.Action {
+	case ui.LaunchTrack:
+		return runLog(cmd, choice.Username)
+	case ui.LaunchHistory:
+		return runBrowser(choice.Username)
+	}
+	return nil
+}
+
+// launch runs the launcher and returns the user's choice. Everything it
+// opens is closed again before the chosen command starts.
+func launch() (ui.LaunchChoice, error) {
+	// Initialize database
+	dbPath := GetDBPath()
+	if !database.IsPostgres(dbPath) {
+		if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+			return ui.LaunchChoice{}, fmt.Errorf("failed to create database directory: %w", err)
+		}
+	}
+
+	db, err := database.NewDB(dbPath)
+	if err != nil {
+		return ui.LaunchChoice{}, fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer db.Close()
+
+	// Initialize logger
+	log, err := NewLogger("")
+	if err != nil {
+		return ui.LaunchChoice{}, fmt.Errorf("failed to initialize logger: %w", err)
+	}
+	defer log.Close()
+	log.SetConsole(false)
+
+	// Initialize TikTok client
+	client, err := tiktok.NewClient(log)
+	if err != nil {
+		return ui.LaunchChoice{}, fmt.Errorf("failed to initialize TikTok client: %w", err)
+	}
+	defer client.Close()
+
+	// Start the program
+	p := tea.NewProgram(ui.NewLauncher(launcherSource{db, client}), tea.WithAltScreen())
+	final, err := p.Run()
+	if err != nil {
+		return ui.LaunchChoice{}, fmt.Errorf("failed to run UI: %w", err)
+	}
+
+	if m, ok := final.(interface{ Choice() ui.LaunchChoice }); ok {
+		return m.Choice(), nil
+	}
+	return ui.LaunchChoice{}, nil
+}
+
+// launcherSource adapts the database and TikTok client to the launcher
+type launcherSource struct {
+	db     *database.DB
+	client *tiktok.Client
+}
+
+func (s launcherSource) Usernames() ([]string, error) {
+	summaries, err := s.db.GetStreamerSummaries()
+	if err != nil {
+		return nil, err
+	}
+	usernames := make([]string, len(summaries))
+	for i, summary := range summaries {
+		usernames[i] = summary.Username
+	}
+	return usernames, nil
+}
+
+func (s launcherSource) CheckLive(username string) (ui.LiveStatus, error) {
+	status, err := s.client.CheckLive(username)
+	if err != nil {
+		return ui.LiveStatus{}, err
+	}
+	return ui.LiveStatus{
+		Live:    status.Live,
+		Title:   status.Title,
+		Viewers: status.Viewers,
+	}, nil
+}
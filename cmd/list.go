@@ -2,88 +2,132 @@ package cmd
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
-	"time"
 
+	"tiktok-live-logger/pkg/archive"
 	"tiktok-live-logger/pkg/database"
 	"tiktok-live-logger/pkg/ui"
 
-	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
 )
 
 var listCmd = &cobra.Command{
-	Use:   "list",
-	Short: "List all logged live streams",
-	Long: `View a list of all logged live streams and their events.
-Select a stream to view its detailed logs.`,
+	Use:   "list [username]",
+	Short: "Browse logged live streams",
+	Long: `Browse logged live streams: pick a streamer, then one of their sessions
+to view its event log, or play the session back in the live dashboard at
+1x to 60x speed. Given a username, the browser opens that streamer's sessions.`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// Initialize database
-		dbPath := filepath.Join(os.Getenv("HOME"), ".tiktok-live-logger", "events.db")
-		db, err := database.NewDB(dbPath)
-		if err != nil {
-			return fmt.Errorf("failed to initialize database: %w", err)
+		username := ""
+		if len(args) > 0 {
+			username = args[0]
 		}
-		defer db.Close()
+		return runBrowser(username)
+	},
+}
 
-		// Get all usernames
-		usernames, err := db.GetAllUsernames()
-		if err != nil {
-			return fmt.Errorf("failed to get usernames: %w", err)
-		}
+// runBrowser opens the history browser, at a streamer's sessions if one is given
+func runBrowser(username string) error {
+	config, err := LoadConfig()
+	if err != nil {
+		return err
+	}
 
-		// Create list items
-		var items []list.Item
-		for _, username := range usernames {
-			events, err := db.GetEventsByUsername(username)
-			if err != nil {
-				continue
-			}
+	// Initialize database
+	db, err := database.NewDB(GetDBPath())
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer db.Close()
 
-			if len(events) > 0 {
-				// Group events by date
-				dates := make(map[string]time.Time)
-				for _, event := range events {
-					date := event.Timestamp.Format("2006-01-02")
-					if _, exists := dates[date]; !exists {
-						dates[date] = event.Timestamp
-					}
-				}
+	// Start the program
+	p := tea.NewProgram(ui.NewBrowser(browserSource{db}, config.Layout, username), tea.WithAltScreen())
+	if _, err := p.Run(); err != nil {
+		return fmt.Errorf("failed to run UI: %w", err)
+	}
 
-				// Add username and dates to list
-				items = append(items, listItem{
-					title:    username,
-					desc:     fmt.Sprintf("%d sessions", len(dates)),
-					username: username,
-					dates:    dates,
-				})
-			}
-		}
+	return nil
+}
 
-		// Initialize UI
-		model := ui.NewModel("")
-		model.SetListItems(items)
-		model.SetViewMode(true)
+// browserSource adapts the database to the history browser
+type browserSource struct {
+	db *database.DB
+}
 
-		// Start the program
-		p := tea.NewProgram(model, tea.WithAltScreen())
-		if _, err := p.Run(); err != nil {
-			return fmt.Errorf("failed to run UI: %w", err)
+func (s browserSource) Streamers() ([]ui.StreamerItem, error) {
+	summaries, err := s.db.GetStreamerSummaries()
+	if err != nil {
+		return nil, err
+	}
+	items := make([]ui.StreamerItem, len(summaries))
+	for i, summary := range summaries {
+		items[i] = ui.StreamerItem{
+			Username: summary.Username,
+			Sessions: summary.Sessions,
+			Events:   summary.Events,
+			Diamonds: summary.Diamonds,
+			LastSeen: summary.LastSeen,
 		}
+	}
+	return items, nil
+}
 
-		return nil
-	},
+func (s browserSource) Sessions(username string) ([]ui.SessionItem, error) {
+	summaries, err := s.db.GetSessionSummaries(username)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]ui.SessionItem, len(summaries))
+	for i, summary := range summaries {
+		items[i] = ui.SessionItem{
+			ID:          summary.ID,
+			Username:    summary.Username,
+			StartedAt:   summary.StartedAt,
+			Duration:    summary.Length(),
+			Events:      summary.Events,
+			PeakViewers: summary.PeakViewers,
+			Diamonds:    summary.Diamonds,
+			Archived:    summary.Archive != "",
+		}
+	}
+	return items, nil
 }
 
-type listItem struct {
-	title    string
-	desc     string
-	username string
-	dates    map[string]time.Time
+func (s browserSource) Events(sessionID int64) ([]ui.Event, error) {
+	session, err := s.db.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	// Archived sessions are read from their archive, without restoring them
+	if session.Archive != "" {
+		_, events, err := archive.Read(session.Archive)
+		if err != nil {
+			return nil, err
+		}
+		return uiEvents(events), nil
+	}
+	events, err := s.db.GetEventsBySession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return uiEvents(events), nil
 }
 
-func (i listItem) Title() string       { return i.title }
-func (i listItem) Description() string { return i.desc }
-func (i listItem) FilterValue() string { return i.title } 
\ No newline at end of file
+// uiEvents converts stored events for display
+func uiEvents(events []database.Event) []ui.Event {
+	converted := make([]ui.Event, len(events))
+	for i, e := range events {
+		converted[i] = ui.Event{
+			ID:        e.ID,
+			Type:      e.Type,
+			Content:   e.Content,
+			Timestamp: e.Timestamp,
+			Nickname:  e.Nickname,
+			Value:     e.Value,
+			Flags:     e.Flags,
+			Language:  e.Language,
+		}
+	}
+	return converted
+}
@@ -1,11 +1,19 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"tiktok-live-logger/pkg/database"
+	"tiktok-live-logger/pkg/language"
+	"tiktok-live-logger/pkg/metrics"
+	"tiktok-live-logger/pkg/moderation"
+	"tiktok-live-logger/pkg/privacy"
+	"tiktok-live-logger/pkg/sentiment"
+	"tiktok-live-logger/pkg/sink"
 	"tiktok-live-logger/pkg/tiktok"
 	"tiktok-live-logger/pkg/ui"
 
@@ -17,55 +25,214 @@ var logCmd = &cobra.Command{
 	Use:   "log [username]",
 	Short: "Log a TikTok live stream",
 	Long: `Connect to a TikTok live stream and log all events (chat, gifts, etc.)
-to a SQLite database while displaying them in a beautiful TUI interface.`,
+to a SQLite database, or the sinks from the config, while displaying them in a beautiful TUI interface.
+Sessions are recorded in the database while the database sink is enabled.`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		username := args[0]
+		return runLog(cmd, args[0])
+	},
+}
+
+// startSession records a session starting now, and returns a func that ends
+// it at the time it's called
+func startSession(db *database.DB, username string) (int64, func() error, error) {
+	id, err := db.StartSession(username, time.Now())
+	if err != nil {
+		return 0, nil, err
+	}
+	return id, func() error { return db.EndSession(id, time.Now()) }, nil
+}
 
-		// Initialize database
+// runLog tracks a streamer in the live dashboard until the user quits
+func runLog(cmd *cobra.Command, username string) error {
+	config, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	// Expose Prometheus metrics if requested
+	metricsAddr, _ := cmd.Flags().GetString("metrics-addr")
+	if metricsAddr != "" {
+		srv, err := metrics.Serve(metricsAddr)
+		if err != nil {
+			return fmt.Errorf("failed to start metrics server: %w", err)
+		}
+		defer srv.Shutdown(context.Background())
+	}
+
+	// Record the session in the database, unless events only go to files
+	// or a broker
+	var db *database.DB
+	var sessionID int64
+	if config.Sinks.Database {
 		dbPath := GetDBPath()
-		if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
-			return fmt.Errorf("failed to create database directory: %w", err)
+		if !database.IsPostgres(dbPath) {
+			if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+				return fmt.Errorf("failed to create database directory: %w", err)
+			}
 		}
 
-		db, err := database.NewDB(dbPath)
+		db, err = database.NewDB(dbPath)
 		if err != nil {
 			return fmt.Errorf("failed to initialize database: %w", err)
 		}
 		defer db.Close()
 
-		// Initialize TikTok client
-		client, err := tiktok.NewClient(IsDebug())
+		var endSession func() error
+		sessionID, endSession, err = startSession(db, username)
 		if err != nil {
-			return fmt.Errorf("failed to initialize TikTok client: %w", err)
+			return fmt.Errorf("failed to start session: %w", err)
 		}
-		defer client.Close()
-
-		// Initialize UI with username
-		model := ui.NewModel(username)
-
-		// Set up event handler
-		onEvent := func(event tiktok.Event) {
-			// Update UI with new event
-			model.AddEvent(event.Content)
-			
-			// Save event to database
-			if err := db.SaveEvent(event.Type, event.Content, event.Timestamp, username); err != nil {
-				model.SetError(fmt.Errorf("failed to save event: %w", err))
+		defer endSession()
+	}
+
+	// Initialize logger
+	log, err := NewLogger(username)
+	if err != nil {
+		return fmt.Errorf("failed to initialize logger: %w", err)
+	}
+	defer log.Close()
+	if db != nil {
+		log = log.With("session", sessionID)
+	}
+
+	// Initialize TikTok client
+	client, err := tiktok.NewClient(log)
+	if err != nil {
+		return fmt.Errorf("failed to initialize TikTok client: %w", err)
+	}
+	defer client.Close()
+
+	// Initialize UI with username
+	model := ui.NewModel(username)
+	model.SetLayout(config.Layout)
+	model.SetBufferSize(config.BufferSize)
+	if db != nil {
+		model.SetHistory(func(beforeID int64, limit int) ([]ui.Event, error) {
+			events, err := db.GetEventsBefore(sessionID, beforeID, limit)
+			if err != nil {
+				return nil, err
 			}
+			return uiEvents(events), nil
+		})
+	}
+	p := tea.NewProgram(model, tea.WithAltScreen())
+
+	// Pseudonymize viewers with a salt kept in the config
+	if config.Privacy.Enabled && config.Privacy.Salt == "" {
+		config.Privacy.Salt = privacy.NewSalt()
+		if err := config.Save(); err != nil {
+			return err
 		}
+	}
+	scrubber, err := privacy.New(config.Privacy)
+	if err != nil {
+		return err
+	}
+	client.SetScrub(scrubber.Scrub)
 
-		// Start tracking user
-		if err := client.TrackUser(username, onEvent); err != nil {
-			return fmt.Errorf("failed to track user: %w", err)
+	// Open where events are stored
+	sinks, err := sink.New(config.Sinks, db, func(err error) {
+		log.Warn("Failed to publish events, retrying", "error", err)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open sinks: %w", err)
+	}
+	defer func() {
+		if err := sinks.Close(); err != nil {
+			log.Warn("Failed to close sinks", "error", err)
 		}
+	}()
 
-		// Start the program
-		p := tea.NewProgram(model, tea.WithAltScreen())
-		if _, err := p.Run(); err != nil {
-			return fmt.Errorf("failed to run UI: %w", err)
+	// Set up event handler
+	onEvent := func(event tiktok.Event) {
+		// Save event to the database and other sinks, without the viewer's
+		// identity in privacy mode. The dashboard still shows it live.
+		scrubbed := scrubber.Scrub(event)
+		saved := database.Event{
+			SessionID: sessionID,
+			Username:  username,
+			Type:      scrubbed.Type,
+			Content:   scrubbed.Content,
+			Timestamp: scrubbed.Timestamp,
+			UserID:    scrubbed.UserID,
+			Nickname:  scrubbed.Nickname,
+			Value:     scrubbed.Value,
+			Flags:     scrubbed.Flags,
+			Scored:    scrubbed.Scored,
+			Sentiment: scrubbed.Sentiment,
+			Toxicity:  scrubbed.Toxicity,
+			Language:  scrubbed.Language,
+		}
+		if err := sinks.Write(&saved); err != nil {
+			p.Send(ui.ErrorMsg(fmt.Errorf("failed to save event: %w", err)))
 		}
 
-		return nil
-	},
-} 
\ No newline at end of file
+		// Update UI with new event
+		p.Send(ui.EventMsg(ui.Event{
+			ID:        saved.ID,
+			Type:      event.Type,
+			Content:   event.Content,
+			Timestamp: event.Timestamp,
+			Nickname:  event.Nickname,
+			Value:     event.Value,
+			Flags:     event.Flags,
+			Language:  event.Language,
+		}))
+	}
+
+	// Flag and score chat messages before they are stored and displayed
+	processors := []tiktok.Processor{language.Processor()}
+	if config.Moderation.Enabled {
+		filter, err := moderation.New(config.Moderation)
+		if err != nil {
+			return err
+		}
+		processors = append(processors, filter)
+	}
+	if config.Sentiment.Enabled {
+		scorer, err := sentiment.New(config.Sentiment)
+		if err != nil {
+			return err
+		}
+		processors = append(processors, sentiment.Processor(scorer))
+	}
+
+	// The TUI owns the terminal from here on, so keep logs in the file only
+	log.SetConsole(false)
+	defer log.SetConsole(true)
+
+	// Apply retention rules while logging for long
+	if db != nil && config.Retention.Interval > 0 && (len(config.Retention.Rules) > 0 || config.Retention.CompactAfter > 0) {
+		if err := config.Retention.Validate(); err != nil {
+			return err
+		}
+		stop := startRetention(db, config.Retention, log)
+		defer stop()
+	}
+
+	// Start tracking user
+	if err := client.TrackUser(username, tiktok.Pipeline(onEvent, processors...)); err != nil {
+		return fmt.Errorf("failed to track user: %w", err)
+	}
+
+	// Start the program
+	final, err := p.Run()
+	if err != nil {
+		return fmt.Errorf("failed to run UI: %w", err)
+	}
+
+	// Remember layout changes made with the keyboard
+	if m, ok := final.(interface{ Layout() ui.Layout }); ok && m.Layout() != config.Layout {
+		config.Layout = m.Layout()
+		if err := config.Save(); err != nil {
+			log.Warn("Failed to save layout", "error", err)
+		}
+	}
+
+	return nil
+}
+
+func init() {
+	logCmd.Flags().String("metrics-addr", "", "Expose Prometheus metrics at /metrics on this address (e.g. :9090)")
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tiktok-live-logger/pkg/database"
+)
+
+func TestLoggedSessionHasDuration(t *testing.T) {
+	db, err := database.NewDB(filepath.Join(t.TempDir(), "events.db"))
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	defer db.Close()
+
+	id, endSession, err := startSession(db, "streamer1")
+	if err != nil {
+		t.Fatalf("startSession: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if err := endSession(); err != nil {
+		t.Fatalf("ending session: %v", err)
+	}
+
+	s, err := db.GetSession(id)
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if s.EndedAt.IsZero() {
+		t.Fatal("session was not ended")
+	}
+	if s.Duration() < 20*time.Millisecond {
+		t.Errorf("session lasted %v, want at least 20ms", s.Duration())
+	}
+}
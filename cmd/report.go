@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"tiktok-live-logger/pkg/database"
+	"tiktok-live-logger/pkg/report"
+
+	"github.com/spf13/cobra"
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report <session>",
+	Short: "Generate a recap report for a session",
+	Long: `Render a self-contained HTML and/or Markdown report for a logged session,
+with session metadata, a viewer chart, top gifters, a gift timeline, the most
+active chatters, word cloud data and the full transcript.
+
+Templates can be overridden by placing report.html.tmpl or report.md.tmpl in
+the directory given by --templates or the report_template_dir config key.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+		templateDir, _ := cmd.Flags().GetString("templates")
+
+		if templateDir == "" {
+			config, err := LoadConfig()
+			if err != nil {
+				return err
+			}
+			templateDir = config.ReportTemplateDir
+		}
+
+		var templates []string
+		switch format {
+		case "html":
+			templates = []string{report.HTMLTemplate}
+		case "md", "markdown":
+			templates = []string{report.MarkdownTemplate}
+		case "all":
+			templates = []string{report.HTMLTemplate, report.MarkdownTemplate}
+		default:
+			return fmt.Errorf("unknown format %q: must be html, md or all", format)
+		}
+
+		// Initialize database
+		db, err := database.NewDB(GetDBPath())
+		if err != nil {
+			return fmt.Errorf("failed to initialize database: %w", err)
+		}
+		defer db.Close()
+
+		session, err := loadSession(db, args[0])
+		if err != nil {
+			return err
+		}
+
+		events, err := db.GetEventsBySession(session.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get events: %w", err)
+		}
+
+		data := report.Build(*session, events)
+
+		for _, name := range templates {
+			ext := ".html"
+			if name == report.MarkdownTemplate {
+				ext = ".md"
+			}
+
+			path := output
+			if path == "" {
+				path = fmt.Sprintf("%s-session-%d", session.Username, session.ID)
+			}
+			path = strings.TrimSuffix(strings.TrimSuffix(path, ".html"), ".md") + ext
+
+			file, err := os.Create(path)
+			if err != nil {
+				return fmt.Errorf("failed to create report file: %w", err)
+			}
+			if err := report.Render(file, name, templateDir, data); err != nil {
+				file.Close()
+				return fmt.Errorf("failed to render report: %w", err)
+			}
+			if err := file.Close(); err != nil {
+				return fmt.Errorf("failed to write report file: %w", err)
+			}
+			fmt.Printf("Report written to %s\n", path)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	reportCmd.Flags().StringP("format", "f", "html", "Report format: html, md or all")
+	reportCmd.Flags().StringP("output", "o", "", "Output file path without extension (default <username>-session-<id>)")
+	reportCmd.Flags().String("templates", "", "Directory with template overrides")
+}
@@ -5,6 +5,10 @@ import (
 	"os"
 	"path/filepath"
 
+	"tiktok-live-logger/pkg/logger"
+	"tiktok-live-logger/pkg/theme"
+	"tiktok-live-logger/pkg/ui"
+
 	"github.com/spf13/cobra"
 )
 
@@ -19,19 +23,45 @@ var rootCmd = &cobra.Command{
 	Short: "A TikTok live stream logger with beautiful TUI",
 	Long: `A command line tool to log and view TikTok live streams.
 It connects to live streams, logs all events (chat, gifts, etc.) and provides
-a beautiful interface to view the logs.`,
+a beautiful interface to view the logs.
+
+Run without a command to open the launcher: type a username, see whether
+they're live, then start tracking them or browse their logged sessions.`,
+	Args:    cobra.NoArgs,
 	Version: fmt.Sprintf("%s (commit: %s, date: %s)", version, commit, date),
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		applyTheme()
+	},
 }
 
 func init() {
+	// Open the launcher when no command is given. Set here since the launcher
+	// refers back to rootCmd for its flags.
+	rootCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return runLauncher(cmd)
+	}
+
 	// Add commands
 	rootCmd.AddCommand(logCmd)
-	// rootCmd.AddCommand(listCmd)
-	// rootCmd.AddCommand(cleanCmd)
-	// rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(cleanCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(sessionsCmd)
+	rootCmd.AddCommand(reportCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(topicsCmd)
+	rootCmd.AddCommand(audienceCmd)
+	rootCmd.AddCommand(compareCmd)
+	rootCmd.AddCommand(highlightsCmd)
+	rootCmd.AddCommand(scoreCmd)
+	rootCmd.AddCommand(languagesCmd)
+	rootCmd.AddCommand(archiveCmd)
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(dbCmd)
+	rootCmd.AddCommand(forgetCmd)
 
 	// Add flags
-	rootCmd.PersistentFlags().StringP("db", "d", "", "Path to database file")
+	rootCmd.PersistentFlags().StringP("db", "d", "", "Path to database file or postgres:// URL")
 	rootCmd.PersistentFlags().BoolP("debug", "v", false, "Enable debug mode")
 }
 
@@ -39,12 +69,21 @@ func Execute() error {
 	return rootCmd.Execute()
 }
 
-// GetDBPath returns the path to the database file
+// GetDBPath returns the database given with --db, else the PostgreSQL URL or
+// database file from the config, else the default database file
 func GetDBPath() string {
-	dbPath, err := rootCmd.Flags().GetString("db")
+	dbPath, err := rootCmd.PersistentFlags().GetString("db")
 	if err == nil && dbPath != "" {
 		return dbPath
 	}
+	if config, err := LoadConfig(); err == nil {
+		if config.DatabaseDSN != "" {
+			return config.DatabaseDSN
+		}
+		if config.DatabasePath != "" {
+			return config.DatabasePath
+		}
+	}
 	return filepath.Join(os.Getenv("HOME"), ".tiktok-live-logger", "events.db")
 }
 
@@ -52,4 +91,51 @@ func GetDBPath() string {
 func IsDebug() bool {
 	debug, _ := rootCmd.Flags().GetBool("debug")
 	return debug
-} 
\ No newline at end of file
+}
+
+// applyTheme styles the TUI and console logs with the configured theme. A
+// broken theme keeps the default one so the config can still be fixed.
+func applyTheme() {
+	config, err := LoadConfig()
+	if err != nil {
+		return
+	}
+	t, err := theme.Resolve(config.Theme, config.Themes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v, using the default theme\n", err)
+		if t, err = theme.Resolve(theme.Auto, nil); err != nil {
+			return
+		}
+	}
+	ui.SetTheme(t)
+	logger.SetTheme(t)
+}
+
+// NewLogger builds the application logger from the config file, writing to
+// a file of its own for a streamer name. Debug mode from the command line or
+// config lowers the level to debug.
+func NewLogger(name string) (*logger.Logger, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	level, err := logger.ParseLevel(config.LogLevel)
+	if err != nil {
+		return nil, err
+	}
+	if IsDebug() || config.DebugMode {
+		level = logger.DEBUG
+	}
+
+	return logger.New(logger.Options{
+		Dir:        config.LogDir,
+		Name:       name,
+		Format:     config.LogFormat,
+		Level:      level,
+		Console:    true,
+		MaxSizeMB:  config.LogMaxSizeMB,
+		MaxAgeDays: config.LogMaxAgeDays,
+		MaxBackups: config.LogMaxBackups,
+	})
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestGetDBPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Cleanup(func() { rootCmd.PersistentFlags().Set("db", "") })
+
+	if got, want := GetDBPath(), filepath.Join(home, ".tiktok-live-logger", "events.db"); got != want {
+		t.Errorf("without a config, GetDBPath = %q, want %q", got, want)
+	}
+
+	config := DefaultConfig()
+	config.DatabasePath = "/data/events.db"
+	if err := config.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if got := GetDBPath(); got != "/data/events.db" {
+		t.Errorf("with database_path, GetDBPath = %q", got)
+	}
+
+	config.DatabaseDSN = "postgres://localhost/tiktok"
+	if err := config.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if got := GetDBPath(); got != config.DatabaseDSN {
+		t.Errorf("with database_dsn, GetDBPath = %q", got)
+	}
+
+	if err := rootCmd.PersistentFlags().Set("db", "/tmp/other.db"); err != nil {
+		t.Fatal(err)
+	}
+	if got := GetDBPath(); got != "/tmp/other.db" {
+		t.Errorf("with --db, GetDBPath = %q", got)
+	}
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"tiktok-live-logger/pkg/database"
+	"tiktok-live-logger/pkg/sentiment"
+
+	"github.com/spf13/cobra"
+)
+
+var scoreCmd = &cobra.Command{
+	Use:   "score [session]",
+	Short: "Score chat sentiment and toxicity",
+	Long: `Score the sentiment and toxicity of logged chat messages that were not
+scored while logging, and chart how positive the chat of a session was.
+
+Without a session, every unscored chat message in the database (or of the
+streamer given with --streamer) is scored. With a session, its chat is scored
+and the session's sentiment is charted per --bucket.
+
+Scoring runs offline with the scorer set in the sentiment config. Set
+sentiment.enabled to score chat while logging instead.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		streamer, _ := cmd.Flags().GetString("streamer")
+		rescore, _ := cmd.Flags().GetBool("rescore")
+		bucket, _ := cmd.Flags().GetDuration("bucket")
+		format, _ := cmd.Flags().GetString("format")
+
+		if format != "text" && format != "json" {
+			return fmt.Errorf("unknown format %q: must be text or json", format)
+		}
+		if bucket <= 0 {
+			return fmt.Errorf("--bucket must be positive")
+		}
+		if len(args) > 0 && streamer != "" {
+			return fmt.Errorf("give either a session ID or --streamer")
+		}
+
+		config, err := LoadConfig()
+		if err != nil {
+			return err
+		}
+		scorer, err := sentiment.New(config.Sentiment)
+		if err != nil {
+			return err
+		}
+
+		// Initialize database
+		db, err := database.NewDB(GetDBPath())
+		if err != nil {
+			return fmt.Errorf("failed to initialize database: %w", err)
+		}
+		defer db.Close()
+
+		if len(args) == 0 {
+			sessions, err := db.GetSessions(streamer)
+			if err != nil {
+				return fmt.Errorf("failed to get sessions: %w", err)
+			}
+			total, scoredSessions := 0, 0
+			for _, s := range sessions {
+				n, _, err := scoreSession(db, scorer, s.ID, rescore)
+				if err != nil {
+					return fmt.Errorf("session %d: %w", s.ID, err)
+				}
+				if n > 0 {
+					total += n
+					scoredSessions++
+				}
+			}
+			fmt.Printf("Scored %d chat messages in %d sessions\n", total, scoredSessions)
+			return nil
+		}
+
+		session, err := loadSession(db, args[0])
+		if err != nil {
+			return err
+		}
+		n, events, err := scoreSession(db, scorer, session.ID, rescore)
+		if err != nil {
+			return err
+		}
+		summary := sentiment.Summarize(events)
+		timeline := sentiment.Timeline(events, session.StartedAt, bucket)
+
+		if format == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(struct {
+				Session  int64             `json:"session"`
+				Username string            `json:"username"`
+				Scored   int               `json:"scored"`
+				Summary  sentiment.Summary `json:"summary"`
+				Timeline []sentiment.Point `json:"timeline"`
+			}{session.ID, session.Username, n, summary, timeline})
+		}
+
+		fmt.Printf("Session %d, @%s, %s: scored %d new chat messages\n", session.ID, session.Username,
+			session.StartedAt.Format("2006-01-02 15:04"), n)
+		if summary.Messages == 0 {
+			fmt.Println("No chat messages to chart")
+			return nil
+		}
+		fmt.Printf("Average sentiment %+.2f: %d positive, %d neutral, %d negative, %d toxic\n\n",
+			summary.Average, summary.Positive, summary.Messages-summary.Positive-summary.Negative,
+			summary.Negative, summary.Toxic)
+		for _, p := range timeline {
+			fmt.Printf("%8s  %s  %+.2f  %3d msgs", clock(p.Time.Sub(session.StartedAt)),
+				sentimentBar(p.Average, 12), p.Average, p.Messages)
+			if p.Toxic > 0 {
+				fmt.Printf("  %d toxic", p.Toxic)
+			}
+			fmt.Println()
+		}
+		return nil
+	},
+}
+
+// scoreSession scores the chat events of a session that have no scores yet,
+// or all of them with rescore, and returns how many were scored along with
+// the session's chat events
+func scoreSession(db *database.DB, scorer sentiment.Scorer, sessionID int64, rescore bool) (int, []database.Event, error) {
+	events, err := db.GetChatEvents(sessionID)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to get chat events: %w", err)
+	}
+
+	var scores []database.EventScore
+	for i, e := range events {
+		if e.Scored && !rescore {
+			continue
+		}
+		s := scorer.Score(e.Message())
+		events[i].Scored = true
+		events[i].Sentiment, events[i].Toxicity = s.Sentiment, s.Toxicity
+		scores = append(scores, database.EventScore{EventID: e.ID, Sentiment: s.Sentiment, Toxicity: s.Toxicity})
+	}
+	if len(scores) > 0 {
+		if err := db.SaveScores(scores); err != nil {
+			return 0, nil, fmt.Errorf("failed to save scores: %w", err)
+		}
+	}
+	return len(scores), events, nil
+}
+
+// sentimentBar draws a value from -1 to 1 as a bar growing left or right
+// from a center line, width cells to each side
+func sentimentBar(v float64, width int) string {
+	n := int(math.Round(math.Abs(v) * float64(width)))
+	n = min(n, width)
+	left, right := strings.Repeat(" ", width), strings.Repeat(" ", width)
+	if v < 0 {
+		left = strings.Repeat(" ", width-n) + strings.Repeat("█", n)
+	} else {
+		right = strings.Repeat("█", n) + strings.Repeat(" ", width-n)
+	}
+	return left + "│" + right
+}
+
+func init() {
+	scoreCmd.Flags().String("streamer", "", "Only score sessions of this streamer")
+	scoreCmd.Flags().Bool("rescore", false, "Score messages again, e.g. after changing the lexicon")
+	scoreCmd.Flags().Duration("bucket", 5*time.Minute, "Time per row of the session chart")
+	scoreCmd.Flags().StringP("format", "f", "text", "Output format for a session: text or json")
+}
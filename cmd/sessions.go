@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"tiktok-live-logger/pkg/database"
+
+	"github.com/spf13/cobra"
+)
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions [username]",
+	Short: "List logged sessions",
+	Long: `List logging sessions with their IDs, newest first.
+Session IDs are used by commands such as report.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		username := ""
+		if len(args) > 0 {
+			username = args[0]
+		}
+
+		// Initialize database
+		db, err := database.NewDB(GetDBPath())
+		if err != nil {
+			return fmt.Errorf("failed to initialize database: %w", err)
+		}
+		defer db.Close()
+
+		sessions, err := db.GetSessions(username)
+		if err != nil {
+			return fmt.Errorf("failed to get sessions: %w", err)
+		}
+		if len(sessions) == 0 {
+			fmt.Println("No sessions found")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tSTREAMER\tSTARTED\tDURATION")
+		for _, s := range sessions {
+			duration := s.Duration().Round(time.Second).String()
+			if s.EndedAt.IsZero() {
+				duration += " (active)"
+			}
+			if s.Archive != "" {
+				duration += " (archived)"
+			}
+			fmt.Fprintf(w, "%d\t@%s\t%s\t%s\n", s.ID, s.Username, s.StartedAt.Format("2006-01-02 15:04"), duration)
+		}
+		return w.Flush()
+	},
+}
+
+// loadSession parses a session ID argument and fetches the session
+func loadSession(db *database.DB, arg string) (*database.Session, error) {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid session ID %q: %w", arg, err)
+	}
+	session, err := db.GetSession(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("session %d not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if session.Archive != "" {
+		return nil, fmt.Errorf("session %d is archived, restore it first with: tiktok-live-logger restore %d", id, id)
+	}
+	return session, nil
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// table is a report written as aligned text, CSV or Markdown
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+func (t *table) add(values ...any) {
+	row := make([]string, len(values))
+	for i, v := range values {
+		row[i] = fmt.Sprint(v)
+	}
+	t.rows = append(t.rows, row)
+}
+
+func (t *table) writeText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(t.header, "\t")))
+	for _, row := range t.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+func (t *table) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.header); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(t.rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// writeMarkdown writes a Markdown table, escaping pipes in cells
+func (t *table) writeMarkdown(w io.Writer) error {
+	escape := func(cells []string) string {
+		escaped := make([]string, len(cells))
+		for i, cell := range cells {
+			escaped[i] = strings.ReplaceAll(cell, "|", "\\|")
+		}
+		return "| " + strings.Join(escaped, " | ") + " |"
+	}
+	separator := make([]string, len(t.header))
+	for i := range separator {
+		separator[i] = "---"
+	}
+
+	lines := []string{escape(t.header), escape(separator)}
+	for _, row := range t.rows {
+		lines = append(lines, escape(row))
+	}
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
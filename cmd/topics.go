@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"tiktok-live-logger/pkg/database"
+	"tiktok-live-logger/pkg/topics"
+
+	"github.com/spf13/cobra"
+)
+
+var topicsCmd = &cobra.Command{
+	Use:   "topics [session]",
+	Short: "Show what chat talked about",
+	Long: `Count the most frequent words, word pairs, emojis, mentions and hashtags in
+the chat of a session, or of all sessions started in a date range.
+
+Common words of English, Spanish, Portuguese, French, German, Indonesian and
+Turkish are skipped. Chinese, Japanese, Thai and other text written without
+spaces is counted as character pairs.
+
+Counts are cached per session and recomputed when the session's chat changes.
+Archived sessions in a range are skipped.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		streamer, _ := cmd.Flags().GetString("streamer")
+		sinceArg, _ := cmd.Flags().GetString("since")
+		untilArg, _ := cmd.Flags().GetString("until")
+		n, _ := cmd.Flags().GetInt("top")
+		format, _ := cmd.Flags().GetString("format")
+		refresh, _ := cmd.Flags().GetBool("refresh")
+
+		if format != "text" && format != "json" {
+			return fmt.Errorf("unknown format %q: must be text or json", format)
+		}
+		if len(args) > 0 && (streamer != "" || sinceArg != "" || untilArg != "") {
+			return fmt.Errorf("give either a session ID or a range with --streamer, --since and --until")
+		}
+		since, until, err := parseRange(sinceArg, untilArg)
+		if err != nil {
+			return err
+		}
+
+		// Initialize database
+		db, err := database.NewDB(GetDBPath())
+		if err != nil {
+			return fmt.Errorf("failed to initialize database: %w", err)
+		}
+		defer db.Close()
+
+		var sessions []database.Session
+		if len(args) > 0 {
+			session, err := loadSession(db, args[0])
+			if err != nil {
+				return err
+			}
+			sessions = append(sessions, *session)
+		} else {
+			all, err := db.GetSessions(streamer)
+			if err != nil {
+				return fmt.Errorf("failed to get sessions: %w", err)
+			}
+			// The chat of archived sessions is only in their archive files
+			var archived []string
+			for _, s := range sessionsInRange(all, since, until) {
+				if s.Archive != "" {
+					archived = append(archived, strconv.FormatInt(s.ID, 10))
+					continue
+				}
+				sessions = append(sessions, s)
+			}
+			if len(archived) > 0 {
+				fmt.Fprintf(os.Stderr, "Warning: skipping archived sessions %s, restore them to include their chat\n", strings.Join(archived, ", "))
+			}
+		}
+		if len(sessions) == 0 {
+			fmt.Println("No sessions found")
+			return nil
+		}
+
+		counts := topics.NewCounts()
+		for _, session := range sessions {
+			c, err := topics.ForSession(db, session.ID, refresh)
+			if err != nil {
+				return fmt.Errorf("session %d: %w", session.ID, err)
+			}
+			counts.Merge(c)
+		}
+		summary := counts.Top(n)
+
+		if format == "json" {
+			ids := make([]int64, len(sessions))
+			for i, s := range sessions {
+				ids[i] = s.ID
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(struct {
+				Sessions []int64 `json:"sessions"`
+				topics.Summary
+			}{ids, summary})
+		}
+
+		if len(sessions) == 1 {
+			s := sessions[0]
+			fmt.Printf("Session %d, @%s, %s: %d chat messages\n", s.ID, s.Username,
+				s.StartedAt.Format("2006-01-02 15:04"), summary.Messages)
+		} else {
+			fmt.Printf("%d sessions: %d chat messages\n", len(sessions), summary.Messages)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, section := range []struct {
+			title  string
+			counts []topics.Count
+		}{
+			{"WORDS", summary.Words},
+			{"WORD PAIRS", summary.Bigrams},
+			{"EMOJIS", summary.Emojis},
+			{"MENTIONS", summary.Mentions},
+			{"HASHTAGS", summary.Hashtags},
+		} {
+			if len(section.counts) == 0 {
+				fmt.Fprintf(w, "\n%s\tnone\n", section.title)
+				continue
+			}
+			fmt.Fprintf(w, "\n%s\tCOUNT\n", section.title)
+			for _, c := range section.counts {
+				fmt.Fprintf(w, "%s\t%d\n", c.Label, c.Count)
+			}
+		}
+		return w.Flush()
+	},
+}
+
+// parseRange parses --since and --until as dates or RFC 3339 times. A date
+// given for until includes that whole day. Empty values leave the range open.
+func parseRange(sinceArg, untilArg string) (since, until time.Time, err error) {
+	parse := func(flag, value string) (time.Time, bool, error) {
+		if value == "" {
+			return time.Time{}, false, nil
+		}
+		if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+			return t, true, nil
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid --%s %q: use YYYY-MM-DD or RFC 3339", flag, value)
+		}
+		return t, false, nil
+	}
+
+	if since, _, err = parse("since", sinceArg); err != nil {
+		return
+	}
+	var date bool
+	if until, date, err = parse("until", untilArg); err != nil {
+		return
+	}
+	if date {
+		until = until.AddDate(0, 0, 1)
+	}
+	if !since.IsZero() && !until.IsZero() && !until.After(since) {
+		err = fmt.Errorf("--until must be after --since")
+	}
+	return
+}
+
+// sessionsInRange keeps the sessions started within [since, until). Zero
+// bounds are open.
+func sessionsInRange(sessions []database.Session, since, until time.Time) []database.Session {
+	var result []database.Session
+	for _, s := range sessions {
+		if inRange(s.StartedAt, since, until) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// inRange reports whether t is within [since, until), zero bounds are open
+func inRange(t, since, until time.Time) bool {
+	return (since.IsZero() || !t.Before(since)) && (until.IsZero() || t.Before(until))
+}
+
+func init() {
+	topicsCmd.Flags().String("streamer", "", "Only sessions of this streamer")
+	topicsCmd.Flags().String("since", "", "Only sessions started on or after this date (YYYY-MM-DD or RFC 3339)")
+	topicsCmd.Flags().String("until", "", "Only sessions started before the end of this date (YYYY-MM-DD or RFC 3339)")
+	topicsCmd.Flags().IntP("top", "n", 20, "Number of entries per list")
+	topicsCmd.Flags().StringP("format", "f", "text", "Output format: text or json")
+	topicsCmd.Flags().Bool("refresh", false, "Recompute counts instead of using the cache")
+}
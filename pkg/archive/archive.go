@@ -0,0 +1,394 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"tiktok-live-logger/pkg/database"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression formats
+const (
+	Zstd = "zstd"
+	Gzip = "gzip"
+)
+
+// Version is the archive format version written to manifests. Version 2
+// added the rollups of compacted sessions.
+const Version = 2
+
+// Session identifies the archived session
+type Session struct {
+	ID        int64     `json:"id"`
+	Username  string    `json:"username"`
+	StartedAt time.Time `json:"started_at"`
+	EndedAt   time.Time `json:"ended_at"`
+}
+
+// Manifest describes an archive file. It is stored next to the file, which
+// holds one JSON Record per line.
+type Manifest struct {
+	Version     int     `json:"version"`
+	Session     Session `json:"session"`
+	File        string  `json:"file"`
+	Compression string  `json:"compression"`
+	Events      int64   `json:"events"`
+	// Size and SHA256 are of the compressed file
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	CreatedAt time.Time `json:"created_at"`
+	// Compaction is set for compacted sessions, whose archived events lack
+	// the likes and viewer counts kept in rollups
+	Compaction *Compaction `json:"compaction,omitempty"`
+}
+
+// Compaction holds the rollups of a compacted session, which replaced its
+// like and stats events logged before Before
+type Compaction struct {
+	Before  time.Time `json:"before"`
+	Minutes []Rollup  `json:"minutes"`
+	Hours   []Rollup  `json:"hours"`
+}
+
+// Rollup is an archived rollup bucket
+type Rollup struct {
+	Start         time.Time `json:"start"`
+	Events        int64     `json:"events"`
+	Chats         int64     `json:"chats,omitempty"`
+	Gifts         int64     `json:"gifts,omitempty"`
+	Diamonds      int64     `json:"diamonds,omitempty"`
+	Likes         int64     `json:"likes,omitempty"`
+	Follows       int64     `json:"follows,omitempty"`
+	Shares        int64     `json:"shares,omitempty"`
+	ViewerSamples int64     `json:"viewer_samples,omitempty"`
+	ViewerSum     int64     `json:"viewer_sum,omitempty"`
+	ViewerMax     int64     `json:"viewer_max,omitempty"`
+	LastEventAt   time.Time `json:"last_event_at"`
+}
+
+// Record is an archived event
+type Record struct {
+	ID        int64     `json:"id"`
+	Type      string    `json:"type"`
+	Content   string    `json:"content"`
+	Timestamp time.Time `json:"timestamp"`
+	UserID    int64     `json:"user_id,omitempty"`
+	Nickname  string    `json:"nickname,omitempty"`
+	Value     int64     `json:"value,omitempty"`
+	Flags     []string  `json:"flags,omitempty"`
+	Sentiment *float64  `json:"sentiment,omitempty"`
+	Toxicity  *float64  `json:"toxicity,omitempty"`
+	Language  string    `json:"language,omitempty"`
+}
+
+func newRecord(e database.Event) Record {
+	r := Record{
+		ID:        e.ID,
+		Type:      e.Type,
+		Content:   e.Content,
+		Timestamp: e.Timestamp,
+		UserID:    e.UserID,
+		Nickname:  e.Nickname,
+		Value:     e.Value,
+		Flags:     e.Flags,
+		Language:  e.Language,
+	}
+	if e.Scored {
+		r.Sentiment, r.Toxicity = &e.Sentiment, &e.Toxicity
+	}
+	return r
+}
+
+func (r Record) event(session Session) database.Event {
+	e := database.Event{
+		ID:        r.ID,
+		SessionID: session.ID,
+		Username:  session.Username,
+		Type:      r.Type,
+		Content:   r.Content,
+		Timestamp: r.Timestamp,
+		UserID:    r.UserID,
+		Nickname:  r.Nickname,
+		Value:     r.Value,
+		Flags:     r.Flags,
+		Language:  r.Language,
+	}
+	if r.Sentiment != nil && r.Toxicity != nil {
+		e.Scored, e.Sentiment, e.Toxicity = true, *r.Sentiment, *r.Toxicity
+	}
+	return e
+}
+
+func newRollup(r database.Rollup) Rollup {
+	return Rollup{
+		Start:         r.Start,
+		Events:        r.Events,
+		Chats:         r.Chats,
+		Gifts:         r.Gifts,
+		Diamonds:      r.Diamonds,
+		Likes:         r.Likes,
+		Follows:       r.Follows,
+		Shares:        r.Shares,
+		ViewerSamples: r.ViewerSamples,
+		ViewerSum:     r.ViewerSum,
+		ViewerMax:     r.ViewerMax,
+		LastEventAt:   r.LastEventAt,
+	}
+}
+
+func (r Rollup) rollup(session Session) database.Rollup {
+	return database.Rollup{
+		SessionID:     session.ID,
+		Start:         r.Start,
+		Events:        r.Events,
+		Chats:         r.Chats,
+		Gifts:         r.Gifts,
+		Diamonds:      r.Diamonds,
+		Likes:         r.Likes,
+		Follows:       r.Follows,
+		Shares:        r.Shares,
+		ViewerSamples: r.ViewerSamples,
+		ViewerSum:     r.ViewerSum,
+		ViewerMax:     r.ViewerMax,
+		LastEventAt:   r.LastEventAt,
+	}
+}
+
+func newCompaction(c *database.Compaction) *Compaction {
+	if c == nil {
+		return nil
+	}
+	archived := &Compaction{Before: c.Before}
+	for _, r := range c.Minutes {
+		archived.Minutes = append(archived.Minutes, newRollup(r))
+	}
+	for _, r := range c.Hours {
+		archived.Hours = append(archived.Hours, newRollup(r))
+	}
+	return archived
+}
+
+// DatabaseCompaction returns the compaction to restore along with the
+// archived events, or nil if the session wasn't compacted
+func (m *Manifest) DatabaseCompaction() *database.Compaction {
+	if m.Compaction == nil {
+		return nil
+	}
+	c := &database.Compaction{Before: m.Compaction.Before}
+	for _, r := range m.Compaction.Minutes {
+		c.Minutes = append(c.Minutes, r.rollup(m.Session))
+	}
+	for _, r := range m.Compaction.Hours {
+		c.Hours = append(c.Hours, r.rollup(m.Session))
+	}
+	return c
+}
+
+// ManifestPath returns where the manifest of a session's archive is stored
+func ManifestPath(dir string, session database.Session) string {
+	return filepath.Join(dir, session.Username, fmt.Sprintf("session-%d.json", session.ID))
+}
+
+// Write archives the events of a session to dir, with the compaction of a
+// compacted session, and returns the path of its manifest. The archive is
+// read back and checked before Write returns, so the events can be deleted
+// from the database afterwards.
+func Write(dir, compression string, session database.Session, events []database.Event, compaction *database.Compaction) (string, error) {
+	ext := ".ndjson.zst"
+	switch compression {
+	case Zstd:
+	case Gzip:
+		ext = ".ndjson.gz"
+	default:
+		return "", fmt.Errorf("unknown compression %q: must be zstd or gzip", compression)
+	}
+
+	manifestPath := ManifestPath(dir, session)
+	if err := os.MkdirAll(filepath.Dir(manifestPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	manifest := Manifest{
+		Version: Version,
+		Session: Session{
+			ID:        session.ID,
+			Username:  session.Username,
+			StartedAt: session.StartedAt,
+			EndedAt:   session.EndedAt,
+		},
+		File:        fmt.Sprintf("session-%d%s", session.ID, ext),
+		Compression: compression,
+		Events:      int64(len(events)),
+		CreatedAt:   time.Now(),
+		Compaction:  newCompaction(compaction),
+	}
+	path := filepath.Join(filepath.Dir(manifestPath), manifest.File)
+
+	// Write to temporary files first, so an interrupted archive never
+	// replaces a good one
+	size, sum, err := writeEvents(path+".tmp", compression, events)
+	if err != nil {
+		os.Remove(path + ".tmp")
+		return "", err
+	}
+	manifest.Size, manifest.SHA256 = size, sum
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		os.Remove(path + ".tmp")
+		return "", fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := os.WriteFile(manifestPath+".tmp", data, 0644); err != nil {
+		os.Remove(path + ".tmp")
+		return "", fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return "", fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := os.Rename(manifestPath+".tmp", manifestPath); err != nil {
+		return "", fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	if _, _, err := Read(manifestPath); err != nil {
+		return "", fmt.Errorf("archive failed verification: %w", err)
+	}
+	return manifestPath, nil
+}
+
+// writeEvents writes the compressed records to path and returns the size and
+// SHA-256 checksum of the file
+func writeEvents(path, compression string, events []database.Event) (int64, string, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	counter := &countingWriter{w: io.MultiWriter(file, hash)}
+	var compressor io.WriteCloser
+	if compression == Gzip {
+		compressor = gzip.NewWriter(counter)
+	} else if compressor, err = zstd.NewWriter(counter); err != nil {
+		return 0, "", err
+	}
+
+	buf := bufio.NewWriter(compressor)
+	encoder := json.NewEncoder(buf)
+	for _, e := range events {
+		if err := encoder.Encode(newRecord(e)); err != nil {
+			return 0, "", fmt.Errorf("failed to write archive: %w", err)
+		}
+	}
+	if err := buf.Flush(); err != nil {
+		return 0, "", fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := compressor.Close(); err != nil {
+		return 0, "", fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return 0, "", fmt.Errorf("failed to write archive: %w", err)
+	}
+	return counter.n, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// ReadManifest reads a manifest without opening its archive file
+func ReadManifest(manifestPath string) (*Manifest, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", manifestPath, err)
+	}
+	if manifest.Version > Version {
+		return nil, fmt.Errorf("manifest %s has unsupported version %d", manifestPath, manifest.Version)
+	}
+	return &manifest, nil
+}
+
+// Read reads an archive, checking it against the checksum and event count in
+// its manifest, and returns its events in their original order
+func Read(manifestPath string) (*Manifest, []database.Event, error) {
+	manifest, err := ReadManifest(manifestPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	path := filepath.Join(filepath.Dir(manifestPath), manifest.File)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != manifest.SHA256 || int64(len(data)) != manifest.Size {
+		return nil, nil, fmt.Errorf("archive %s is corrupted: checksum mismatch", path)
+	}
+
+	var r io.Reader
+	switch manifest.Compression {
+	case Gzip:
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decompress %s: %w", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	case Zstd:
+		zr, err := zstd.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decompress %s: %w", path, err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return nil, nil, fmt.Errorf("archive %s has unknown compression %q", path, manifest.Compression)
+	}
+
+	var events []database.Event
+	decoder := json.NewDecoder(r)
+	for decoder.More() {
+		var record Record
+		if err := decoder.Decode(&record); err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		events = append(events, record.event(manifest.Session))
+	}
+	if int64(len(events)) != manifest.Events {
+		return nil, nil, fmt.Errorf("archive %s holds %d events, its manifest %d", path, len(events), manifest.Events)
+	}
+	return manifest, events, nil
+}
+
+// Remove deletes an archive file and its manifest
+func Remove(manifestPath string) error {
+	manifest, err := ReadManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(filepath.Dir(manifestPath), manifest.File)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Remove(manifestPath)
+}
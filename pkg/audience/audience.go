@@ -0,0 +1,307 @@
+package audience
+
+import (
+	"sort"
+	"time"
+
+	"tiktok-live-logger/pkg/database"
+)
+
+// SessionCohort splits a session's participants, the viewers who chatted or
+// sent gifts, into first-timers and viewers seen in an earlier session
+type SessionCohort struct {
+	SessionID        int64
+	StartedAt        time.Time
+	Participants     int
+	New              int
+	Returning        int
+	Gifters          int
+	ReturningGifters int
+}
+
+// ReturningRate returns the share of participants seen before
+func (c SessionCohort) ReturningRate() float64 {
+	if c.Participants == 0 {
+		return 0
+	}
+	return float64(c.Returning) / float64(c.Participants)
+}
+
+// BySession returns a cohort for every session in chronological order.
+// Participations must be ordered by session start, as returned by
+// DB.GetParticipations.
+func BySession(sessions []database.Session, parts []database.Participation) []SessionCohort {
+	cohorts := make(map[int64]*SessionCohort)
+	for _, s := range sessions {
+		cohorts[s.ID] = &SessionCohort{SessionID: s.ID, StartedAt: s.StartedAt}
+	}
+
+	seen := make(map[int64]bool)
+	for _, p := range parts {
+		returning := seen[p.UserID]
+		seen[p.UserID] = true
+		c, ok := cohorts[p.SessionID]
+		if !ok {
+			continue
+		}
+
+		c.Participants++
+		if returning {
+			c.Returning++
+		} else {
+			c.New++
+		}
+		if p.Gifts > 0 {
+			c.Gifters++
+			if returning {
+				c.ReturningGifters++
+			}
+		}
+	}
+
+	result := make([]SessionCohort, 0, len(cohorts))
+	for _, c := range cohorts {
+		result = append(result, *c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].StartedAt.Equal(result[j].StartedAt) {
+			return result[i].StartedAt.Before(result[j].StartedAt)
+		}
+		return result[i].SessionID < result[j].SessionID
+	})
+	return result
+}
+
+// Cohort is the viewers who first participated in a week and how many of
+// them participated again in the following weeks
+type Cohort struct {
+	// Week is the Monday the cohort's first week starts on
+	Week time.Time
+	Size int
+	// Active[k] is the number of cohort members seen k weeks after their
+	// first week; Active[0] equals Size
+	Active []int
+}
+
+// Rate returns the share of the cohort active k weeks after its first week
+func (c Cohort) Rate(k int) float64 {
+	if c.Size == 0 || k >= len(c.Active) {
+		return 0
+	}
+	return float64(c.Active[k]) / float64(c.Size)
+}
+
+// Retention groups viewers by the week they first participated and follows
+// each cohort for the given number of weeks after that. Weeks that haven't
+// happened yet are left out of a cohort's Active counts.
+func Retention(parts []database.Participation, weeks int, now time.Time) []Cohort {
+	first := make(map[int64]time.Time)
+	active := make(map[int64]map[int]bool)
+	for _, p := range parts {
+		week := weekStart(p.StartedAt)
+		if f, ok := first[p.UserID]; !ok || week.Before(f) {
+			first[p.UserID] = week
+		}
+	}
+	for _, p := range parts {
+		k := weeksBetween(first[p.UserID], weekStart(p.StartedAt))
+		if k > weeks {
+			continue
+		}
+		if active[p.UserID] == nil {
+			active[p.UserID] = make(map[int]bool)
+		}
+		active[p.UserID][k] = true
+	}
+
+	cohorts := make(map[time.Time]*Cohort)
+	current := weekStart(now)
+	for userID, week := range first {
+		c, ok := cohorts[week]
+		if !ok {
+			n := min(weeks, weeksBetween(week, current)) + 1
+			c = &Cohort{Week: week, Active: make([]int, max(n, 1))}
+			cohorts[week] = c
+		}
+		c.Size++
+		for k := range active[userID] {
+			if k < len(c.Active) {
+				c.Active[k]++
+			}
+		}
+	}
+
+	result := make([]Cohort, 0, len(cohorts))
+	for _, c := range cohorts {
+		result = append(result, *c)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Week.Before(result[j].Week) })
+	return result
+}
+
+// weekStart returns local midnight of the Monday starting t's week
+func weekStart(t time.Time) time.Time {
+	t = t.Local()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// weeksBetween counts whole weeks between two week starts, rounding so
+// daylight saving changes don't matter
+func weeksBetween(from, to time.Time) int {
+	return int((to.Sub(from).Hours() + 12) / (24 * 7))
+}
+
+// Supporter totals a viewer's participation across sessions
+type Supporter struct {
+	// Rank is the viewer's position among all supporters by diamonds, then
+	// chat messages
+	Rank     int
+	UserID   int64
+	Nickname string
+	Sessions int
+	Chats    int
+	Diamonds int64
+	LastSeen time.Time
+}
+
+// Supporters totals every viewer's participation, top supporters first
+func Supporters(parts []database.Participation) []Supporter {
+	byUser := make(map[int64]*Supporter)
+	for _, p := range parts {
+		s, ok := byUser[p.UserID]
+		if !ok {
+			s = &Supporter{UserID: p.UserID}
+			byUser[p.UserID] = s
+		}
+		s.Sessions++
+		s.Chats += p.Chats
+		s.Diamonds += p.Diamonds
+		if !p.StartedAt.Before(s.LastSeen) {
+			s.LastSeen = p.StartedAt
+			// Nicknames can change, show the latest one
+			if p.Nickname != "" {
+				s.Nickname = p.Nickname
+			}
+		}
+	}
+
+	result := make([]Supporter, 0, len(byUser))
+	for _, s := range byUser {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Diamonds != b.Diamonds {
+			return a.Diamonds > b.Diamonds
+		}
+		if a.Chats != b.Chats {
+			return a.Chats > b.Chats
+		}
+		return a.UserID < b.UserID
+	})
+	for i := range result {
+		result[i].Rank = i + 1
+	}
+	return result
+}
+
+// Churned returns the supporters among the top n who didn't participate in
+// any of the streamer's last recent sessions. Sessions must be ordered
+// newest first, as returned by DB.GetSessions.
+func Churned(sessions []database.Session, parts []database.Participation, n, recent int) []Supporter {
+	if len(sessions) <= recent {
+		// Too few sessions to tell churned supporters from occasional ones
+		return nil
+	}
+	recentIDs := make(map[int64]bool)
+	for _, s := range sessions[:recent] {
+		recentIDs[s.ID] = true
+	}
+	active := make(map[int64]bool)
+	for _, p := range parts {
+		if recentIDs[p.SessionID] {
+			active[p.UserID] = true
+		}
+	}
+
+	var churned []Supporter
+	supporters := Supporters(parts)
+	for _, s := range supporters[:min(n, len(supporters))] {
+		if !active[s.UserID] {
+			churned = append(churned, s)
+		}
+	}
+	return churned
+}
+
+// SharedViewer is a viewer who participated with both streamers
+type SharedViewer struct {
+	UserID    int64
+	Nickname  string
+	SessionsA int
+	SessionsB int
+	DiamondsA int64
+	DiamondsB int64
+}
+
+// Overlap compares the participants of two streamers
+type Overlap struct {
+	A, B  int
+	Both  int
+	Union int
+	// Shared lists the viewers of both, most sessions first
+	Shared []SharedViewer
+}
+
+// Jaccard returns the shared viewers as a share of all viewers of either
+func (o Overlap) Jaccard() float64 {
+	if o.Union == 0 {
+		return 0
+	}
+	return float64(o.Both) / float64(o.Union)
+}
+
+// Compare computes the audience overlap of two streamers
+func Compare(a, b []database.Participation) Overlap {
+	supportersA := make(map[int64]Supporter)
+	for _, s := range Supporters(a) {
+		supportersA[s.UserID] = s
+	}
+	supportersB := Supporters(b)
+
+	o := Overlap{A: len(supportersA), B: len(supportersB)}
+	for _, sb := range supportersB {
+		sa, ok := supportersA[sb.UserID]
+		if !ok {
+			continue
+		}
+		nickname := sb.Nickname
+		if sa.LastSeen.After(sb.LastSeen) {
+			nickname = sa.Nickname
+		}
+		o.Shared = append(o.Shared, SharedViewer{
+			UserID:    sb.UserID,
+			Nickname:  nickname,
+			SessionsA: sa.Sessions,
+			SessionsB: sb.Sessions,
+			DiamondsA: sa.Diamonds,
+			DiamondsB: sb.Diamonds,
+		})
+	}
+	o.Both = len(o.Shared)
+	o.Union = o.A + o.B - o.Both
+
+	sort.Slice(o.Shared, func(i, j int) bool {
+		x, y := o.Shared[i], o.Shared[j]
+		if x.SessionsA+x.SessionsB != y.SessionsA+y.SessionsB {
+			return x.SessionsA+x.SessionsB > y.SessionsA+y.SessionsB
+		}
+		if x.DiamondsA+x.DiamondsB != y.DiamondsA+y.DiamondsB {
+			return x.DiamondsA+x.DiamondsB > y.DiamondsA+y.DiamondsB
+		}
+		return x.UserID < y.UserID
+	})
+	return o
+}
@@ -0,0 +1,139 @@
+package audience
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"tiktok-live-logger/pkg/database"
+)
+
+// day returns 20:00 local time on a day of January 2026; the 5th is a Monday
+func day(d int) time.Time {
+	return time.Date(2026, 1, d, 20, 0, 0, 0, time.Local)
+}
+
+func midnight(d int) time.Time {
+	return time.Date(2026, 1, d, 0, 0, 0, 0, time.Local)
+}
+
+// Five sessions over four weeks, newest first as GetSessions returns them
+var sessions = []database.Session{
+	{ID: 5, StartedAt: day(26)},
+	{ID: 4, StartedAt: day(19)},
+	{ID: 3, StartedAt: day(12)},
+	{ID: 2, StartedAt: day(7)},
+	{ID: 1, StartedAt: day(5)},
+}
+
+func part(session int64, userID int64, nickname string, chats, gifts int, diamonds int64) database.Participation {
+	started := day(map[int64]int{1: 5, 2: 7, 3: 12, 4: 19, 5: 26}[session])
+	return database.Participation{SessionID: session, StartedAt: started, UserID: userID, Nickname: nickname,
+		Chats: chats, Gifts: gifts, Diamonds: diamonds}
+}
+
+// participations are ordered by session start, as GetParticipations returns them
+var participations = []database.Participation{
+	part(1, 1, "alice", 5, 0, 0),
+	part(1, 2, "bob", 0, 2, 500),
+	part(2, 2, "bob", 1, 0, 0),
+	part(2, 3, "carol", 3, 0, 0),
+	part(3, 1, "alice", 2, 1, 100),
+	part(3, 4, "dave", 1, 0, 0),
+	part(4, 4, "dave", 1, 1, 10),
+	part(4, 5, "erin", 2, 0, 0),
+	part(5, 1, "alice", 1, 0, 0),
+	part(5, 5, "erin_b", 1, 0, 0),
+}
+
+func TestBySession(t *testing.T) {
+	got := BySession(sessions, participations)
+	want := []SessionCohort{
+		{SessionID: 1, StartedAt: day(5), Participants: 2, New: 2, Gifters: 1},
+		{SessionID: 2, StartedAt: day(7), Participants: 2, New: 1, Returning: 1},
+		{SessionID: 3, StartedAt: day(12), Participants: 2, New: 1, Returning: 1, Gifters: 1, ReturningGifters: 1},
+		{SessionID: 4, StartedAt: day(19), Participants: 2, New: 1, Returning: 1, Gifters: 1, ReturningGifters: 1},
+		{SessionID: 5, StartedAt: day(26), Participants: 2, Returning: 2},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("BySession =\n%+v\nwant\n%+v", got, want)
+	}
+	if rate := got[4].ReturningRate(); rate != 1 {
+		t.Errorf("returning rate of the last session = %v, want 1", rate)
+	}
+	if rate := (SessionCohort{}).ReturningRate(); rate != 0 {
+		t.Errorf("returning rate without participants = %v", rate)
+	}
+}
+
+func TestRetention(t *testing.T) {
+	got := Retention(participations, 2, day(28))
+	want := []Cohort{
+		// alice, bob and carol; alice is back a week later and again after
+		// three weeks, beyond the two followed
+		{Week: midnight(5), Size: 3, Active: []int{3, 1, 0}},
+		{Week: midnight(12), Size: 1, Active: []int{1, 1, 0}},
+		// erin's third week hasn't happened yet
+		{Week: midnight(19), Size: 1, Active: []int{1, 1}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Retention = %+v, want %d cohorts", got, len(want))
+	}
+	for i := range want {
+		if !got[i].Week.Equal(want[i].Week) || got[i].Size != want[i].Size || !slices.Equal(got[i].Active, want[i].Active) {
+			t.Errorf("cohort %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if rate := got[0].Rate(1); rate != 1.0/3 {
+		t.Errorf("rate after a week = %v, want 1/3", rate)
+	}
+	if rate := got[2].Rate(2); rate != 0 {
+		t.Errorf("rate of a week to come = %v, want 0", rate)
+	}
+}
+
+func TestSupportersAndChurn(t *testing.T) {
+	supporters := Supporters(participations)
+	var order []string
+	for _, s := range supporters {
+		order = append(order, s.Nickname)
+	}
+	// By diamonds, then chat messages; erin is listed under the latest nickname
+	if !slices.Equal(order, []string{"bob", "alice", "dave", "carol", "erin_b"}) {
+		t.Errorf("supporters = %v", order)
+	}
+	alice := supporters[1]
+	if alice.Rank != 2 || alice.Sessions != 3 || alice.Chats != 8 || alice.Diamonds != 100 || !alice.LastSeen.Equal(day(26)) {
+		t.Errorf("alice = %+v", alice)
+	}
+
+	// Of the top three, only bob missed the last two sessions
+	churned := Churned(sessions, participations, 3, 2)
+	if len(churned) != 1 || churned[0].UserID != 2 {
+		t.Errorf("churned = %+v, want bob", churned)
+	}
+	if churned := Churned(sessions, participations, 3, 5); churned != nil {
+		t.Errorf("churned with too few sessions = %+v", churned)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	other := []database.Participation{
+		{SessionID: 10, StartedAt: day(27), UserID: 1, Nickname: "alice_new", Chats: 1, Diamonds: 5},
+		{SessionID: 10, StartedAt: day(27), UserID: 6, Nickname: "frank", Chats: 4},
+	}
+	o := Compare(participations, other)
+	if o.A != 5 || o.B != 2 || o.Both != 1 || o.Union != 6 {
+		t.Errorf("overlap = %+v", o)
+	}
+	if j := o.Jaccard(); j != 1.0/6 {
+		t.Errorf("Jaccard = %v, want 1/6", j)
+	}
+	want := SharedViewer{UserID: 1, Nickname: "alice_new", SessionsA: 3, SessionsB: 1, DiamondsA: 100, DiamondsB: 5}
+	if len(o.Shared) != 1 || o.Shared[0] != want {
+		t.Errorf("shared = %+v, want %+v", o.Shared, want)
+	}
+	if j := (Overlap{}).Jaccard(); j != 0 {
+		t.Errorf("Jaccard of nobody = %v", j)
+	}
+}
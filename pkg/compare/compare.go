@@ -0,0 +1,75 @@
+package compare
+
+import (
+	"time"
+
+	"tiktok-live-logger/pkg/database"
+)
+
+// Row holds one streamer's metrics over the compared sessions
+type Row struct {
+	Username    string
+	Sessions    int
+	Streamed    time.Duration
+	AvgViewers  float64
+	PeakViewers int64
+	Chats       int64
+	Gifts       int64
+	Diamonds    int64
+	Likes       int64
+	Follows     int64
+	Shares      int64
+}
+
+// Build totals a streamer's sessions
+func Build(username string, sessions []database.SessionSummary) Row {
+	row := Row{Username: username, Sessions: len(sessions)}
+	var viewerSum, viewerSamples int64
+	for _, s := range sessions {
+		row.Streamed += s.Length()
+		row.PeakViewers = max(row.PeakViewers, s.PeakViewers)
+		row.Chats += s.Chats
+		row.Gifts += s.Gifts
+		row.Diamonds += s.Diamonds
+		row.Likes += s.Likes
+		row.Follows += s.Follows
+		row.Shares += s.Shares
+		viewerSum += s.ViewerSum
+		viewerSamples += s.ViewerSamples
+	}
+	if viewerSamples > 0 {
+		row.AvgViewers = float64(viewerSum) / float64(viewerSamples)
+	}
+	return row
+}
+
+// Hours returns the time streamed in hours
+func (r Row) Hours() float64 {
+	return r.Streamed.Hours()
+}
+
+// ChatRate returns chat messages per minute streamed
+func (r Row) ChatRate() float64 {
+	if r.Streamed <= 0 {
+		return 0
+	}
+	return float64(r.Chats) / r.Streamed.Minutes()
+}
+
+// DiamondsPerHour returns gift revenue per hour streamed
+func (r Row) DiamondsPerHour() float64 {
+	if r.Streamed <= 0 {
+		return 0
+	}
+	return float64(r.Diamonds) / r.Streamed.Hours()
+}
+
+// Engagement returns chat messages, gifts, follows and shares per average
+// viewer, so streams of different sizes can be compared. Likes are left out
+// since viewers send them in bursts of hundreds.
+func (r Row) Engagement() float64 {
+	if r.AvgViewers == 0 {
+		return 0
+	}
+	return float64(r.Chats+r.Gifts+r.Follows+r.Shares) / r.AvgViewers
+}
@@ -0,0 +1,75 @@
+package compare
+
+import (
+	"testing"
+	"time"
+
+	"tiktok-live-logger/pkg/database"
+)
+
+var start = time.Date(2026, 1, 31, 20, 0, 0, 0, time.UTC)
+
+func summary(offset, length time.Duration, ended bool) database.SessionSummary {
+	s := database.SessionSummary{Session: database.Session{StartedAt: start.Add(offset)}}
+	if ended {
+		s.EndedAt = s.StartedAt.Add(length)
+	} else {
+		// The logger was killed; the last event marks the end
+		s.LastEventAt = s.StartedAt.Add(length)
+	}
+	return s
+}
+
+func TestBuild(t *testing.T) {
+	a := summary(0, 90*time.Minute, true)
+	a.PeakViewers, a.Chats, a.Gifts, a.Diamonds, a.Likes, a.Follows, a.Shares = 300, 120, 10, 1000, 5000, 6, 4
+	a.ViewerSum, a.ViewerSamples = 200*90, 90
+	b := summary(24*time.Hour, 30*time.Minute, false)
+	b.PeakViewers, b.Chats, b.Gifts, b.Diamonds, b.Likes, b.Follows, b.Shares = 150, 60, 5, 500, 1000, 3, 2
+	b.ViewerSum, b.ViewerSamples = 100*30, 30
+
+	row := Build("streamer", []database.SessionSummary{a, b})
+	want := Row{Username: "streamer", Sessions: 2, Streamed: 2 * time.Hour, AvgViewers: 175, PeakViewers: 300,
+		Chats: 180, Gifts: 15, Diamonds: 1500, Likes: 6000, Follows: 9, Shares: 6}
+	if row != want {
+		t.Errorf("Build =\n%+v\nwant\n%+v", row, want)
+	}
+
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"hours", row.Hours(), 2},
+		{"chat rate", row.ChatRate(), 1.5},
+		{"diamonds per hour", row.DiamondsPerHour(), 750},
+		// Chats, gifts, follows and shares per average viewer, without likes
+		{"engagement", row.Engagement(), 210.0 / 175},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestBuildWithoutData(t *testing.T) {
+	// Chat but no viewer counts, in a session that never got going
+	s := summary(0, 0, true)
+	s.Chats = 3
+	row := Build("streamer", []database.SessionSummary{s})
+	for name, got := range map[string]float64{
+		"avg viewers":       row.AvgViewers,
+		"chat rate":         row.ChatRate(),
+		"diamonds per hour": row.DiamondsPerHour(),
+		"engagement":        row.Engagement(),
+	} {
+		if got != 0 {
+			t.Errorf("%s = %v, want 0", name, got)
+		}
+	}
+
+	if row := Build("nobody", nil); row.Sessions != 0 || row.Hours() != 0 || row.Engagement() != 0 {
+		t.Errorf("Build without sessions = %+v", row)
+	}
+}
@@ -0,0 +1,132 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ArchiveSession deletes the stored events of a session whose events were
+// written to the archive with the given manifest, keeping the session and
+// its rollups as a stub. It returns how many events were deleted.
+func (d *DB) ArchiveSession(sessionID int64, manifest string) (int64, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec(`DELETE FROM events WHERE session_id = ?`, sessionID)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if _, err := tx.Exec(`DELETE FROM topic_cache WHERE session_id = ?`, sessionID); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if _, err := tx.Exec(`UPDATE sessions SET archive = ? WHERE id = ?`, manifest, sessionID); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// RestoreSession imports the events of an archived session and returns the
+// ID the session has in this database. Restored into the database it was
+// archived from, the session and its events keep their IDs. Otherwise the
+// session is added, with a new ID if its own is taken, along with rollups
+// of its events, or the archived rollups of a compacted session.
+func (d *DB) RestoreSession(session Session, events []Event, compaction *Compaction) (int64, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	id, stub, err := restoreTarget(tx, session)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	// Likes and viewer counts a stub was compacted to are in its rollups
+	// already
+	var compactedBefore sql.NullTime
+	if stub {
+		err := tx.QueryRow(`SELECT compacted_before FROM sessions WHERE id = ?`, id).Scan(&compactedBefore)
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	} else if compaction != nil {
+		if err := restoreCompaction(tx, id, compaction); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	for _, event := range events {
+		if compactedBefore.Valid && (event.Type == "like" || event.Type == "stats") && event.Timestamp.Before(compactedBefore.Time) {
+			continue
+		}
+		event.SessionID, event.Username = id, session.Username
+		// Event IDs are never reused, so only a stub's events are sure to
+		// find theirs free
+		var eventID int64
+		if stub {
+			eventID = event.ID
+		}
+		if _, err := insertEvent(tx, eventID, event); err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("failed to restore event: %w", err)
+		}
+		// A stub still has the rollups of its events, as does a compaction
+		if !stub && compaction == nil {
+			if err := addToRollups(tx, event); err != nil {
+				tx.Rollback()
+				return 0, err
+			}
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE sessions SET archive = NULL WHERE id = ?`, id); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// restoreTarget finds the stub of an archived session, or adds the session.
+// A session with the same ID that was logged at another time, e.g. in the
+// database the archive came from, leaves the restored one a new ID.
+func restoreTarget(tx *tx, session Session) (id int64, stub bool, err error) {
+	var username string
+	var startedAt time.Time
+	var archive sql.NullString
+	err = tx.QueryRow(`SELECT username, started_at, archive FROM sessions WHERE id = ?`, session.ID).Scan(&username, &startedAt, &archive)
+	// PostgreSQL keeps timestamps to the microsecond
+	same := err == nil && username == session.Username &&
+		startedAt.Truncate(time.Second).Equal(session.StartedAt.Truncate(time.Second))
+	switch {
+	case same && archive.Valid:
+		return session.ID, true, nil
+	case same:
+		return 0, false, fmt.Errorf("session %d is already in the database", session.ID)
+	case err == nil:
+		// The ID belongs to another session here
+		var id int64
+		err := tx.QueryRow(`INSERT INTO sessions (username, started_at, ended_at) VALUES (?, ?, ?) RETURNING id`,
+			session.Username, session.StartedAt, sql.NullTime{Time: session.EndedAt, Valid: !session.EndedAt.IsZero()}).Scan(&id)
+		return id, false, err
+	case errors.Is(err, sql.ErrNoRows):
+		_, err := tx.Exec(`INSERT INTO sessions (id, username, started_at, ended_at) VALUES (?, ?, ?, ?)`,
+			session.ID, session.Username, session.StartedAt, sql.NullTime{Time: session.EndedAt, Valid: !session.EndedAt.IsZero()})
+		if err != nil {
+			return 0, false, err
+		}
+		return session.ID, false, tx.backend.syncIDs(tx, "sessions")
+	default:
+		return 0, false, err
+	}
+}
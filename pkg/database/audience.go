@@ -0,0 +1,47 @@
+package database
+
+import "time"
+
+// Participation sums up what one viewer did in one session
+type Participation struct {
+	SessionID int64
+	StartedAt time.Time
+	UserID    int64
+	Nickname  string
+	Chats     int
+	Gifts     int
+	Diamonds  int64
+}
+
+// GetParticipations returns one row per session and viewer who chatted or
+// sent gifts in a streamer's sessions, oldest session first. Events logged
+// without viewer IDs are left out.
+func (d *DB) GetParticipations(username string) ([]Participation, error) {
+	query := `
+	SELECT s.id, s.started_at, e.user_id, MAX(e.nickname),
+		SUM(CASE WHEN e.type = 'chat' THEN 1 ELSE 0 END),
+		SUM(CASE WHEN e.type = 'gift' THEN 1 ELSE 0 END),
+		SUM(CASE WHEN e.type = 'gift' THEN e.value ELSE 0 END)
+	FROM events e
+	JOIN sessions s ON s.id = e.session_id
+	WHERE s.username = ? AND e.user_id != 0 AND e.type IN ('chat', 'gift')
+	GROUP BY s.id, e.user_id
+	ORDER BY s.started_at, s.id
+	`
+	rows, err := d.db.Query(query, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var parts []Participation
+	for rows.Next() {
+		var p Participation
+		err := rows.Scan(&p.SessionID, &p.StartedAt, &p.UserID, &p.Nickname, &p.Chats, &p.Gifts, &p.Diamonds)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, p)
+	}
+	return parts, rows.Err()
+}
@@ -0,0 +1,280 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	_ "github.com/lib/pq"
+)
+
+// ErrSQLiteOnly is returned by maintenance features that only exist for
+// SQLite databases
+var ErrSQLiteOnly = errors.New("only supported on SQLite databases")
+
+// IsPostgres reports whether a database path is a PostgreSQL URL rather
+// than a SQLite file
+func IsPostgres(dsn string) bool {
+	return strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://")
+}
+
+// backend is the SQL database events are stored in. Queries are written
+// once, in the SQL both backends understand with ? placeholders, and the
+// backend adapts what differs.
+type backend interface {
+	// open connects to the database
+	open(dsn string) (*sql.DB, error)
+	// rebind rewrites the ? placeholders of a query for the driver
+	rebind(query string) string
+	// schema adapts the SQLite DDL of the base schema (version 0) or a
+	// migration
+	schema(version int, ddl string) string
+	// version returns the number of applied migrations
+	version(db *sql.DB) (int, error)
+	setVersion(tx *sql.Tx, version int) error
+	// syncIDs lets a table hand out IDs after rows inserted with their own
+	syncIDs(db execer, table string) error
+	size(db *sql.DB) (size, free int64, err error)
+	vacuum(db *sql.DB, incremental bool) (bool, error)
+}
+
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// conn is a database handle that rebinds queries for its backend
+type conn struct {
+	*sql.DB
+	backend backend
+}
+
+func (c *conn) Exec(query string, args ...any) (sql.Result, error) {
+	return c.DB.Exec(c.backend.rebind(query), args...)
+}
+
+func (c *conn) Query(query string, args ...any) (*sql.Rows, error) {
+	return c.DB.Query(c.backend.rebind(query), args...)
+}
+
+func (c *conn) QueryRow(query string, args ...any) *sql.Row {
+	return c.DB.QueryRow(c.backend.rebind(query), args...)
+}
+
+func (c *conn) Begin() (*tx, error) {
+	t, err := c.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &tx{Tx: t, backend: c.backend}, nil
+}
+
+// tx is a transaction that rebinds queries for its backend
+type tx struct {
+	*sql.Tx
+	backend backend
+}
+
+func (t *tx) Exec(query string, args ...any) (sql.Result, error) {
+	return t.Tx.Exec(t.backend.rebind(query), args...)
+}
+
+func (t *tx) Query(query string, args ...any) (*sql.Rows, error) {
+	return t.Tx.Query(t.backend.rebind(query), args...)
+}
+
+func (t *tx) QueryRow(query string, args ...any) *sql.Row {
+	return t.Tx.QueryRow(t.backend.rebind(query), args...)
+}
+
+func (t *tx) Prepare(query string) (*sql.Stmt, error) {
+	return t.Tx.Prepare(t.backend.rebind(query))
+}
+
+type sqlite struct{}
+
+func (sqlite) open(dsn string) (*sql.DB, error) {
+	// Wait for other writers, such as the retention job while logging,
+	// instead of failing with "database is locked"
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return sql.Open("sqlite3", dsn+sep+"_busy_timeout=5000")
+}
+
+func (sqlite) rebind(query string) string { return query }
+
+func (sqlite) schema(version int, ddl string) string { return ddl }
+
+func (sqlite) version(db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRow(`PRAGMA user_version`).Scan(&version)
+	return version, err
+}
+
+func (sqlite) setVersion(tx *sql.Tx, version int) error {
+	// PRAGMA does not accept bound parameters
+	_, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, version))
+	return err
+}
+
+// SQLite AUTOINCREMENT keys already continue after the highest ID
+func (sqlite) syncIDs(db execer, table string) error { return nil }
+
+func (sqlite) size(db *sql.DB) (size, free int64, err error) {
+	var pages, freePages, pageSize int64
+	if err := db.QueryRow(`PRAGMA page_count`).Scan(&pages); err != nil {
+		return 0, 0, err
+	}
+	if err := db.QueryRow(`PRAGMA freelist_count`).Scan(&freePages); err != nil {
+		return 0, 0, err
+	}
+	if err := db.QueryRow(`PRAGMA page_size`).Scan(&pageSize); err != nil {
+		return 0, 0, err
+	}
+	return pages * pageSize, freePages * pageSize, nil
+}
+
+func (sqlite) vacuum(db *sql.DB, incremental bool) (bool, error) {
+	if !incremental {
+		// Switch to incremental auto-vacuum for the next time
+		if _, err := db.Exec(`PRAGMA auto_vacuum = INCREMENTAL`); err != nil {
+			return false, err
+		}
+		_, err := db.Exec(`VACUUM`)
+		return err == nil, err
+	}
+
+	var mode int
+	if err := db.QueryRow(`PRAGMA auto_vacuum`).Scan(&mode); err != nil {
+		return false, err
+	}
+	// 2 is INCREMENTAL
+	if mode != 2 {
+		return false, nil
+	}
+	// The pragma frees a page per step, so read it to the end
+	rows, err := db.Query(`PRAGMA incremental_vacuum`)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+	}
+	return true, rows.Err()
+}
+
+type postgres struct{}
+
+func (postgres) open(dsn string) (*sql.DB, error) {
+	return sql.Open("postgres", dsn)
+}
+
+// rebind numbers the placeholders, skipping quoted strings
+func (postgres) rebind(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+	var b strings.Builder
+	n, quoted := 0, false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == '?' && !quoted:
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// postgresTypes maps SQLite column types to PostgreSQL ones, longest first
+var postgresTypes = []struct {
+	pattern *regexp.Regexp
+	typ     string
+}{
+	{regexp.MustCompile(`\bINTEGER PRIMARY KEY AUTOINCREMENT\b`), "BIGSERIAL PRIMARY KEY"},
+	{regexp.MustCompile(`\bINTEGER\b`), "BIGINT"},
+	{regexp.MustCompile(`\bDATETIME\b`), "TIMESTAMPTZ"},
+	{regexp.MustCompile(`\bREAL\b`), "DOUBLE PRECISION"},
+	{regexp.MustCompile(`\bBLOB\b`), "BYTEA"},
+}
+
+// postgresMigrations replaces migrations using SQLite functions. They only
+// backfill rows logged before the migration, and PostgreSQL databases are
+// always created with every migration at once.
+var postgresMigrations = map[int]string{
+	3: ``,
+	8: `
+	CREATE TABLE IF NOT EXISTS rollups (
+		session_id INTEGER NOT NULL REFERENCES sessions(id),
+		resolution INTEGER NOT NULL,
+		bucket INTEGER NOT NULL,
+		events INTEGER NOT NULL,
+		chats INTEGER NOT NULL,
+		gifts INTEGER NOT NULL,
+		diamonds INTEGER NOT NULL,
+		likes INTEGER NOT NULL,
+		follows INTEGER NOT NULL,
+		shares INTEGER NOT NULL,
+		viewer_samples INTEGER NOT NULL,
+		viewer_sum INTEGER NOT NULL,
+		viewer_max INTEGER NOT NULL,
+		last_event INTEGER NOT NULL,
+		PRIMARY KEY (session_id, resolution, bucket)
+	);
+	ALTER TABLE sessions ADD COLUMN compacted_before DATETIME;
+	`,
+}
+
+func (postgres) schema(version int, ddl string) string {
+	if m, ok := postgresMigrations[version]; ok {
+		ddl = m
+	}
+	for _, t := range postgresTypes {
+		ddl = t.pattern.ReplaceAllString(ddl, t.typ)
+	}
+	return ddl
+}
+
+func (postgres) version(db *sql.DB) (int, error) {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)`); err != nil {
+		return 0, err
+	}
+	var version int
+	err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version)
+	return version, err
+}
+
+func (postgres) setVersion(tx *sql.Tx, version int) error {
+	_, err := tx.Exec(`INSERT INTO schema_version (version) VALUES ($1)`, version)
+	return err
+}
+
+func (postgres) syncIDs(db execer, table string) error {
+	_, err := db.Exec(fmt.Sprintf(`SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), MAX(id)) FROM %[1]s HAVING MAX(id) IS NOT NULL`, table))
+	return err
+}
+
+// PostgreSQL reuses the space of deleted rows itself, so free is always zero
+func (postgres) size(db *sql.DB) (size, free int64, err error) {
+	err = db.QueryRow(`SELECT pg_database_size(current_database())`).Scan(&size)
+	return size, 0, err
+}
+
+// A plain VACUUM runs alongside writers like SQLite's incremental vacuum,
+// VACUUM FULL rewrites the tables like a full one
+func (postgres) vacuum(db *sql.DB, incremental bool) (bool, error) {
+	query := `VACUUM FULL`
+	if incremental {
+		query = `VACUUM`
+	}
+	_, err := db.Exec(query)
+	return err == nil, err
+}
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPostgresRebind(t *testing.T) {
+	tests := []struct {
+		query, want string
+	}{
+		{`SELECT 1`, `SELECT 1`},
+		{`SELECT * FROM events WHERE id = ? AND type = ?`, `SELECT * FROM events WHERE id = $1 AND type = $2`},
+		{`SELECT '?' FROM events WHERE id = ?`, `SELECT '?' FROM events WHERE id = $1`},
+		{`UPDATE events SET content = 'what? why?' WHERE id IN (?, ?)`, `UPDATE events SET content = 'what? why?' WHERE id IN ($1, $2)`},
+		{`SELECT 'it''s ?', ? FROM events`, `SELECT 'it''s ?', $1 FROM events`},
+		{`SELECT ? || '?' || ?`, `SELECT $1 || '?' || $2`},
+	}
+	for _, tt := range tests {
+		if got := (postgres{}).rebind(tt.query); got != tt.want {
+			t.Errorf("rebind(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSQLiteRebind(t *testing.T) {
+	query := `SELECT '?' FROM events WHERE id = ?`
+	if got := (sqlite{}).rebind(query); got != query {
+		t.Errorf("rebind(%q) = %q, want it unchanged", query, got)
+	}
+}
+
+func TestPostgresSchema(t *testing.T) {
+	base := (postgres{}).schema(0, `CREATE TABLE t (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		n INTEGER NOT NULL,
+		at DATETIME NOT NULL,
+		score REAL,
+		data BLOB
+	)`)
+	for _, want := range []string{"id BIGSERIAL PRIMARY KEY", "n BIGINT NOT NULL", "at TIMESTAMPTZ NOT NULL", "score DOUBLE PRECISION", "data BYTEA"} {
+		if !strings.Contains(base, want) {
+			t.Errorf("base schema is missing %q:\n%s", want, base)
+		}
+	}
+
+	// Migrations using SQLite functions are replaced
+	if got := (postgres{}).schema(3, migrations[2]); strings.TrimSpace(got) != "" {
+		t.Errorf("migration 3 = %q, want it empty", got)
+	}
+	rollups := (postgres{}).schema(8, migrations[7])
+	if !strings.Contains(rollups, "CREATE TABLE IF NOT EXISTS rollups") || strings.Contains(rollups, "INSERT") {
+		t.Errorf("migration 8 should create the rollups table without a backfill:\n%s", rollups)
+	}
+	if strings.Contains(rollups, "INTEGER") || strings.Contains(rollups, "DATETIME") {
+		t.Errorf("migration 8 still has SQLite types:\n%s", rollups)
+	}
+
+	// Other migrations only have their types mapped
+	if got, want := (postgres{}).schema(1, migrations[0]), migrations[0]; got != want {
+		t.Errorf("migration 1 = %q, want %q", got, want)
+	}
+	if got := (sqlite{}).schema(3, migrations[2]); got != migrations[2] {
+		t.Errorf("SQLite migration 3 was changed")
+	}
+}
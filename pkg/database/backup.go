@@ -0,0 +1,173 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// backupStep is the number of pages copied at a time, so a logger writing to
+// the database only waits for one step
+const backupStep = 1024
+
+// maxBackupRestarts is how often a backup may start over because another
+// process wrote to the database, before the rest is copied in one step
+const maxBackupRestarts = 3
+
+// Backup copies the database to path with SQLite's online backup API, which
+// is safe while other processes read and write the database. The copy is
+// written next to path first and only replaces it once complete. progress,
+// if not nil, is called with the number of pages left and the total.
+func (d *DB) Backup(path string, progress func(remaining, total int)) error {
+	if _, ok := d.db.backend.(sqlite); !ok {
+		return ErrSQLiteOnly
+	}
+	tmp := path + ".tmp"
+	os.Remove(tmp)
+	if err := d.backup(tmp, progress); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (d *DB) backup(path string, progress func(remaining, total int)) error {
+	dest, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer dest.Close()
+
+	ctx := context.Background()
+	destConn, err := dest.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destConn.Close()
+	srcConn, err := d.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	return destConn.Raw(func(destRaw any) error {
+		return srcConn.Raw(func(srcRaw any) error {
+			backup, err := destRaw.(*sqlite3.SQLiteConn).Backup("main", srcRaw.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+
+			step, restarts, last := backupStep, 0, -1
+			for {
+				done, err := backup.Step(step)
+				if err != nil {
+					backup.Close()
+					return err
+				}
+				if done {
+					break
+				}
+				remaining := backup.Remaining()
+				if progress != nil {
+					progress(remaining, backup.PageCount())
+				}
+				// Writes by another process make the backup start over, so
+				// it stops making progress
+				if last >= 0 && remaining >= last {
+					if restarts++; restarts >= maxBackupRestarts {
+						step = -1
+					}
+				}
+				last = remaining
+				// Let writers in between steps
+				time.Sleep(10 * time.Millisecond)
+			}
+			return backup.Finish()
+		})
+	})
+}
+
+// ForeignKeyProblem is a group of rows referring to missing parent rows
+type ForeignKeyProblem struct {
+	Table  string
+	Parent string
+	Rows   int
+	// RowIDs holds the first few offending row IDs
+	RowIDs []int64
+}
+
+// CheckResult is the outcome of a database check
+type CheckResult struct {
+	// Integrity holds the problems found by the integrity check
+	Integrity   []string
+	ForeignKeys []ForeignKeyProblem
+}
+
+// OK reports whether the check found no problems
+func (r *CheckResult) OK() bool {
+	return len(r.Integrity) == 0 && len(r.ForeignKeys) == 0
+}
+
+// Check runs SQLite's integrity and foreign key checks. quick runs the
+// faster quick_check, which skips verifying that indexes match their tables.
+func (d *DB) Check(quick bool) (*CheckResult, error) {
+	if _, ok := d.db.backend.(sqlite); !ok {
+		return nil, ErrSQLiteOnly
+	}
+	pragma := "integrity_check"
+	if quick {
+		pragma = "quick_check"
+	}
+
+	result := &CheckResult{}
+	rows, err := d.db.Query(`PRAGMA ` + pragma)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run %s: %w", pragma, err)
+	}
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if line != "ok" {
+			result.Integrity = append(result.Integrity, line)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = d.db.Query(`PRAGMA foreign_key_check`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run foreign_key_check: %w", err)
+	}
+	defer rows.Close()
+	index := make(map[[2]string]int)
+	for rows.Next() {
+		var table, parent string
+		var rowID sql.NullInt64
+		var fk int
+		if err := rows.Scan(&table, &rowID, &parent, &fk); err != nil {
+			return nil, err
+		}
+		key := [2]string{table, parent}
+		i, ok := index[key]
+		if !ok {
+			i = len(result.ForeignKeys)
+			index[key] = i
+			result.ForeignKeys = append(result.ForeignKeys, ForeignKeyProblem{Table: table, Parent: parent})
+		}
+		p := &result.ForeignKeys[i]
+		p.Rows++
+		if rowID.Valid && len(p.RowIDs) < 5 {
+			p.RowIDs = append(p.RowIDs, rowID.Int64)
+		}
+	}
+	return result, rows.Err()
+}
@@ -5,28 +5,99 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
+	"tiktok-live-logger/pkg/metrics"
+
 	_ "github.com/mattn/go-sqlite3"
 )
 
 type Event struct {
 	ID        int64
+	SessionID int64
 	Username  string
 	Type      string
 	Content   string
 	Timestamp time.Time
+	// UserID is the TikTok ID of the viewer who triggered the event. Unlike
+	// nicknames it never changes, but it is zero for events logged before IDs
+	// were stored.
+	UserID int64
+	// Nickname is the viewer who triggered the event, if any
+	Nickname string
+	// Value is the diamond value for gifts, the like count for likes
+	// and the viewer count for stats events
+	Value int64
+	// Flags holds moderation reasons for flagged chat messages
+	Flags []string
+	// Scored is set for chat messages with a Sentiment from -1 (negative)
+	// to 1 (positive) and a Toxicity from 0 to 1
+	Scored    bool
+	Sentiment float64
+	Toxicity  float64
+	// Language is the ISO 639-1 code detected for chat messages, or empty
+	// if it wasn't detected yet
+	Language string
+}
+
+// Message returns the comment text of a chat event without the nickname prefix
+func (e Event) Message() string {
+	if e.Type != "chat" {
+		return e.Content
+	}
+	if e.Nickname != "" {
+		return strings.TrimPrefix(e.Content, e.Nickname+": ")
+	}
+	if _, msg, ok := strings.Cut(e.Content, ": "); ok {
+		return msg
+	}
+	return e.Content
+}
+
+// Session is one continuous run of the logger against a streamer
+type Session struct {
+	ID        int64
+	Username  string
+	StartedAt time.Time
+	// EndedAt is zero while the session is still being logged
+	EndedAt time.Time
+	// Archive is the manifest of the archive file holding the events of an
+	// archived session, which are no longer in the database
+	Archive string
 }
 
+// Duration returns how long the session lasted, or has lasted so far
+func (s Session) Duration() time.Duration {
+	if s.EndedAt.IsZero() {
+		return time.Since(s.StartedAt)
+	}
+	return s.EndedAt.Sub(s.StartedAt)
+}
+
+// eventColumns is the column list read by scanEvents
+const eventColumns = `id, session_id, username, type, content, timestamp, user_id, nickname, value, flags, sentiment, toxicity, language`
+
+// sessionColumns is the column list read by scanSessions
+const sessionColumns = `id, username, started_at, ended_at, archive`
+
 type DB struct {
-	db *sql.DB
+	db *conn
 }
 
+// NewDB opens the database at dbPath, a SQLite file or a postgres:// URL,
+// and brings its schema up to date
 func NewDB(dbPath string) (*DB, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+	var b backend = sqlite{}
+	if IsPostgres(dbPath) {
+		b = postgres{}
+	}
+	sqlDB, err := b.open(dbPath)
 	if err != nil {
 		return nil, err
 	}
+	db := &conn{DB: sqlDB, backend: b}
 
 	if err := db.Ping(); err != nil {
 		return nil, err
@@ -38,6 +109,11 @@ func NewDB(dbPath string) (*DB, error) {
 		return nil, err
 	}
 
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	return &DB{db: db}, nil
 }
 
@@ -45,7 +121,7 @@ func (d *DB) Close() error {
 	return d.db.Close()
 }
 
-func createTables(db *sql.DB) error {
+func createTables(db *conn) error {
 	query := `
 	CREATE TABLE IF NOT EXISTS events (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -58,44 +134,232 @@ func createTables(db *sql.DB) error {
 	CREATE INDEX IF NOT EXISTS idx_timestamp ON events(timestamp);
 	`
 
-	_, err := db.Exec(query)
+	_, err := db.Exec(db.backend.schema(0, query))
 	return err
 }
 
-func (d *DB) SaveEvent(eventType, content string, timestamp time.Time, username string) error {
-	query := `
-	INSERT INTO events (type, content, timestamp, username)
-	VALUES (?, ?, ?, ?)
-	`
-	_, err := d.db.Exec(query, eventType, content, timestamp, username)
-	return err
+// SaveEvent stores an event and returns its ID
+func (d *DB) SaveEvent(event Event) (int64, error) {
+	start := time.Now()
+	id, err := d.saveEvent(event)
+	metrics.ObserveDBWrite("save_event", start, err)
+	if err == nil {
+		metrics.EventsPersisted.WithLabelValues(event.Username, event.Type).Inc()
+	}
+	return id, err
 }
 
-func (d *DB) GetEventsByUsername(username string) ([]Event, error) {
-	query := `
-	SELECT id, username, type, content, timestamp
-	FROM events
-	WHERE username = ?
-	ORDER BY timestamp DESC
-	`
-	rows, err := d.db.Query(query, username)
+// saveEvent inserts an event and counts it in the rollups of its session
+func (d *DB) saveEvent(event Event) (int64, error) {
+	tx, err := d.db.Begin()
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	defer rows.Close()
+	id, err := insertEvent(tx, 0, event)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if event.SessionID != 0 {
+		if err := addToRollups(tx, event); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+	return id, tx.Commit()
+}
+
+// insertEvent inserts an event with the given ID, or a new one for zero,
+// and returns its ID
+func insertEvent(tx *tx, id int64, event Event) (int64, error) {
+	args := []any{nullID(event.SessionID), event.Type, event.Content, event.Timestamp,
+		event.Username, event.UserID, event.Nickname, event.Value, strings.Join(event.Flags, ","),
+		nullScore(event.Scored, event.Sentiment), nullScore(event.Scored, event.Toxicity),
+		sql.NullString{String: event.Language, Valid: event.Language != ""}}
+	columns := `session_id, type, content, timestamp, username, user_id, nickname, value, flags, sentiment, toxicity, language`
+	// PostgreSQL only generates IDs for columns left out, not for NULL
+	if id != 0 {
+		columns, args = "id, "+columns, append([]any{id}, args...)
+	}
+	query := `INSERT INTO events (` + columns + `) VALUES (` +
+		strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ") + `) RETURNING id`
+	err := tx.QueryRow(query, args...).Scan(&id)
+	return id, err
+}
 
+func scanEvents(rows *sql.Rows) ([]Event, error) {
 	var events []Event
 	for rows.Next() {
 		var event Event
-		err := rows.Scan(&event.ID, &event.Username, &event.Type, &event.Content, &event.Timestamp)
+		var sessionID sql.NullInt64
+		var flags string
+		var sentiment, toxicity sql.NullFloat64
+		var language sql.NullString
+		err := rows.Scan(&event.ID, &sessionID, &event.Username, &event.Type, &event.Content,
+			&event.Timestamp, &event.UserID, &event.Nickname, &event.Value, &flags, &sentiment, &toxicity, &language)
 		if err != nil {
 			return nil, err
 		}
+		event.SessionID = sessionID.Int64
+		event.Scored = sentiment.Valid
+		event.Sentiment, event.Toxicity = sentiment.Float64, toxicity.Float64
+		event.Language = language.String
+		if flags != "" {
+			event.Flags = strings.Split(flags, ",")
+		}
 		events = append(events, event)
 	}
 	return events, rows.Err()
 }
 
+// nullID stores zero IDs as NULL so unset references stay unset
+func nullID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
+
+// nullScore stores scores of unscored events as NULL
+func nullScore(scored bool, score float64) sql.NullFloat64 {
+	return sql.NullFloat64{Float64: score, Valid: scored}
+}
+
+// StartSession records the start of a logging session and returns its ID
+func (d *DB) StartSession(username string, startedAt time.Time) (int64, error) {
+	var id int64
+	err := d.db.QueryRow(`INSERT INTO sessions (username, started_at) VALUES (?, ?) RETURNING id`,
+		username, startedAt).Scan(&id)
+	return id, err
+}
+
+// EndSession marks a session as finished
+func (d *DB) EndSession(id int64, endedAt time.Time) error {
+	_, err := d.db.Exec(`UPDATE sessions SET ended_at = ? WHERE id = ?`, endedAt, id)
+	return err
+}
+
+func scanSessions(rows *sql.Rows) ([]Session, error) {
+	var sessions []Session
+	for rows.Next() {
+		var session Session
+		var endedAt sql.NullTime
+		var archive sql.NullString
+		if err := rows.Scan(&session.ID, &session.Username, &session.StartedAt, &endedAt, &archive); err != nil {
+			return nil, err
+		}
+		session.EndedAt, session.Archive = endedAt.Time, archive.String
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// GetSession returns a single session, or sql.ErrNoRows if it doesn't exist
+func (d *DB) GetSession(id int64) (*Session, error) {
+	rows, err := d.db.Query(`SELECT `+sessionColumns+` FROM sessions WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions, err := scanSessions(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &sessions[0], nil
+}
+
+// GetSessions returns the sessions of a streamer, or of all streamers if
+// username is empty, newest first
+func (d *DB) GetSessions(username string) ([]Session, error) {
+	query := `
+	SELECT ` + sessionColumns + `
+	FROM sessions
+	WHERE ? = '' OR username = ?
+	ORDER BY started_at DESC
+	`
+	rows, err := d.db.Query(query, username, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanSessions(rows)
+}
+
+// GetEventsBySession returns all events of a session in chronological order.
+// Likes and viewer counts of compacted sessions come from their minute
+// rollups, as one event per minute.
+func (d *DB) GetEventsBySession(sessionID int64) ([]Event, error) {
+	events, err := d.GetStoredEvents(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return d.withRollups(sessionID, events)
+}
+
+// GetStoredEvents returns the events stored for a session in chronological
+// order, without those replaced by rollups
+func (d *DB) GetStoredEvents(sessionID int64) ([]Event, error) {
+	query := `
+	SELECT ` + eventColumns + `
+	FROM events
+	WHERE session_id = ?
+	ORDER BY timestamp ASC, id ASC
+	`
+	rows, err := d.db.Query(query, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanEvents(rows)
+}
+
+// GetEventsBefore returns up to limit events of a session stored before the
+// event with the given ID, in the order they were stored. That is the order
+// they arrived in while logging, which timestamps don't always follow.
+func (d *DB) GetEventsBefore(sessionID, beforeID int64, limit int) ([]Event, error) {
+	query := `
+	SELECT ` + eventColumns + `
+	FROM events
+	WHERE session_id = ? AND id < ?
+	ORDER BY id DESC
+	LIMIT ?
+	`
+	rows, err := d.db.Query(query, sessionID, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events, err := scanEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(events)
+	return events, nil
+}
+
+// GetEventsByUsername returns the events of a streamer, newest first. Given
+// languages, chat messages in other languages are left out.
+func (d *DB) GetEventsByUsername(username string, languages ...string) ([]Event, error) {
+	filter, args := languageFilter(languages)
+	query := `
+	SELECT ` + eventColumns + `
+	FROM events
+	WHERE username = ?` + filter + `
+	ORDER BY timestamp DESC
+	`
+	rows, err := d.db.Query(query, append([]any{username}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanEvents(rows)
+}
+
 func (d *DB) GetAllUsernames() ([]string, error) {
 	query := `
 	SELECT DISTINCT username
@@ -122,7 +386,7 @@ func (d *DB) GetAllUsernames() ([]string, error) {
 
 func (d *DB) GetEventsByTimeRange(username string, start, end time.Time) ([]Event, error) {
 	query := `
-	SELECT id, username, type, content, timestamp
+	SELECT ` + eventColumns + `
 	FROM events
 	WHERE username = ? AND timestamp BETWEEN ? AND ?
 	ORDER BY timestamp DESC
@@ -133,31 +397,18 @@ func (d *DB) GetEventsByTimeRange(username string, start, end time.Time) ([]Even
 	}
 	defer rows.Close()
 
-	var events []Event
-	for rows.Next() {
-		var event Event
-		err := rows.Scan(&event.ID, &event.Username, &event.Type, &event.Content, &event.Timestamp)
-		if err != nil {
-			return nil, err
-		}
-		events = append(events, event)
-	}
-	return events, rows.Err()
+	return scanEvents(rows)
 }
 
 func (d *DB) DeleteOldEvents(days int) (int64, error) {
 	cutoff := time.Now().AddDate(0, 0, -days)
-	query := `DELETE FROM events WHERE timestamp < ?`
-	result, err := d.db.Exec(query, cutoff)
-	if err != nil {
-		return 0, err
-	}
-	return result.RowsAffected()
+	return d.deleteEvents(`timestamp < ?`, []any{cutoff}, 0)
 }
 
-// Export functions
-func (d *DB) ExportToJSON(username string, outputPath string) error {
-	events, err := d.GetEventsByUsername(username)
+// Export functions. Given languages, chat messages in other languages are
+// left out.
+func (d *DB) ExportToJSON(username string, outputPath string, languages ...string) error {
+	events, err := d.GetEventsByUsername(username, languages...)
 	if err != nil {
 		return err
 	}
@@ -170,8 +421,8 @@ func (d *DB) ExportToJSON(username string, outputPath string) error {
 	return os.WriteFile(outputPath, data, 0644)
 }
 
-func (d *DB) ExportToTXT(username string, outputPath string) error {
-	events, err := d.GetEventsByUsername(username)
+func (d *DB) ExportToTXT(username string, outputPath string, languages ...string) error {
+	events, err := d.GetEventsByUsername(username, languages...)
 	if err != nil {
 		return err
 	}
@@ -187,7 +438,7 @@ func (d *DB) ExportToTXT(username string, outputPath string) error {
 	return os.WriteFile(outputPath, []byte(content), 0644)
 }
 
-func (d *DB) ExportToDB(username string, outputPath string) error {
+func (d *DB) ExportToDB(username string, outputPath string, languages ...string) error {
 	// Create a new database for export
 	exportDB, err := NewDB(outputPath)
 	if err != nil {
@@ -196,17 +447,34 @@ func (d *DB) ExportToDB(username string, outputPath string) error {
 	defer exportDB.Close()
 
 	// Get events for the username
-	events, err := d.GetEventsByUsername(username)
+	events, err := d.GetEventsByUsername(username, languages...)
 	if err != nil {
 		return err
 	}
 
+	// Copy the sessions the events belong to, keeping their IDs
+	sessions, err := d.GetSessions(username)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		_, err := exportDB.db.Exec(
+			`INSERT INTO sessions (id, username, started_at, ended_at) VALUES (?, ?, ?, ?) ON CONFLICT (id) DO NOTHING`,
+			session.ID, session.Username, session.StartedAt, sql.NullTime{Time: session.EndedAt, Valid: !session.EndedAt.IsZero()})
+		if err != nil {
+			return err
+		}
+	}
+	if err := exportDB.db.backend.syncIDs(exportDB.db, "sessions"); err != nil {
+		return err
+	}
+
 	// Insert events into the export database
 	for _, event := range events {
-		if err := exportDB.SaveEvent(event.Type, event.Content, event.Timestamp, event.Username); err != nil {
+		if _, err := exportDB.SaveEvent(event); err != nil {
 			return err
 		}
 	}
 
 	return nil
-} 
\ No newline at end of file
+}
@@ -0,0 +1,466 @@
+package database
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// forEachBackend runs test against a new SQLite database and, with
+// TIKTOK_TEST_POSTGRES_DSN set to a postgres:// URL, a new schema in that
+// PostgreSQL database
+func forEachBackend(t *testing.T, test func(t *testing.T, dsn string, db *DB)) {
+	t.Run("sqlite", func(t *testing.T) {
+		dsn := filepath.Join(t.TempDir(), "events.db")
+		test(t, dsn, openDB(t, dsn))
+	})
+	t.Run("postgres", func(t *testing.T) {
+		dsn := postgresDSN(t)
+		test(t, dsn, openDB(t, dsn))
+	})
+}
+
+// postgresDSN creates a schema for the test, dropped afterwards, and returns
+// a URL using it
+func postgresDSN(t *testing.T) string {
+	t.Helper()
+	dsn := os.Getenv("TIKTOK_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TIKTOK_TEST_POSTGRES_DSN is not set")
+	}
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	b := make([]byte, 6)
+	rand.Read(b)
+	schema := "tll_test_" + hex.EncodeToString(b)
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() { admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`) })
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	// lib/pq passes unknown parameters on to the server
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func openDB(t *testing.T, dsn string) *DB {
+	t.Helper()
+	db, err := NewDB(dsn)
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+var testStart = time.Date(2026, 1, 31, 20, 0, 0, 0, time.UTC)
+
+// logSession stores a finished session with a chat, gift, like, follow and
+// viewer count event per minute
+func logSession(t *testing.T, db *DB, username string, minutes int) Session {
+	t.Helper()
+	id, err := db.StartSession(username, testStart)
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	var last int64
+	for i := range minutes {
+		at := testStart.Add(time.Duration(i) * time.Minute)
+		for _, e := range []Event{
+			{Type: "chat", Content: "alice: hi", Nickname: "alice", UserID: 1},
+			{Type: "gift", Content: "bob sent Rose (x2)", Nickname: "bob", UserID: 2, Value: 2},
+			{Type: "like", Content: "carol sent 5 likes", Nickname: "carol", UserID: 3, Value: 5},
+			{Type: "follow", Content: "alice followed the streamer", Nickname: "alice", UserID: 1},
+			{Type: "stats", Content: "Viewer count: 100", Value: 100 + int64(i)},
+		} {
+			e.SessionID, e.Username, e.Timestamp = id, username, at
+			eventID, err := db.SaveEvent(e)
+			if err != nil {
+				t.Fatalf("SaveEvent: %v", err)
+			}
+			if eventID <= last {
+				t.Fatalf("SaveEvent returned ID %d after %d", eventID, last)
+			}
+			last = eventID
+		}
+	}
+	end := testStart.Add(time.Duration(minutes) * time.Minute)
+	if err := db.EndSession(id, end); err != nil {
+		t.Fatalf("EndSession: %v", err)
+	}
+	return Session{ID: id, Username: username, StartedAt: testStart, EndedAt: end}
+}
+
+// rollupTotals sums the hourly rollups of a session
+func rollupTotals(t *testing.T, db *DB, sessionID int64) Rollup {
+	t.Helper()
+	rollups, err := db.GetRollups(sessionID, HourRollup, time.Time{})
+	if err != nil {
+		t.Fatalf("GetRollups: %v", err)
+	}
+	var total Rollup
+	for _, r := range rollups {
+		total.Events += r.Events
+		total.Chats += r.Chats
+		total.Gifts += r.Gifts
+		total.Diamonds += r.Diamonds
+		total.Likes += r.Likes
+		total.Follows += r.Follows
+		total.ViewerSamples += r.ViewerSamples
+		total.ViewerSum += r.ViewerSum
+		total.ViewerMax = max(total.ViewerMax, r.ViewerMax)
+	}
+	return total
+}
+
+func TestSessionsAndEvents(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, dsn string, db *DB) {
+		s := logSession(t, db, "streamer1", 3)
+
+		got, err := db.GetSession(s.ID)
+		if err != nil {
+			t.Fatalf("GetSession: %v", err)
+		}
+		if got.Username != "streamer1" || !got.StartedAt.Equal(s.StartedAt) || !got.EndedAt.Equal(s.EndedAt) {
+			t.Errorf("GetSession = %+v, want %+v", got, s)
+		}
+
+		events, err := db.GetEventsBySession(s.ID)
+		if err != nil {
+			t.Fatalf("GetEventsBySession: %v", err)
+		}
+		if len(events) != 15 {
+			t.Fatalf("got %d events, want 15", len(events))
+		}
+		if e := events[1]; e.Type != "gift" || e.Nickname != "bob" || e.UserID != 2 || e.Value != 2 || !e.Timestamp.Equal(testStart) {
+			t.Errorf("second event = %+v", e)
+		}
+
+		// Paging back from the last event returns the ones stored before it
+		older, err := db.GetEventsBefore(s.ID, events[14].ID, 4)
+		if err != nil {
+			t.Fatalf("GetEventsBefore: %v", err)
+		}
+		if len(older) != 4 || older[0].ID != events[10].ID || older[3].ID != events[13].ID {
+			t.Errorf("GetEventsBefore returned %d events from %d", len(older), older[0].ID)
+		}
+
+		total := rollupTotals(t, db, s.ID)
+		want := Rollup{Events: 15, Chats: 3, Gifts: 3, Diamonds: 6, Likes: 15, Follows: 3,
+			ViewerSamples: 3, ViewerSum: 303, ViewerMax: 102}
+		if total != want {
+			t.Errorf("rollups = %+v, want %+v", total, want)
+		}
+
+		// Migrations are only applied once
+		db.Close()
+		reopened := openDB(t, dsn)
+		if sessions, err := reopened.GetSessions(""); err != nil || len(sessions) != 1 {
+			t.Errorf("GetSessions after reopening = %d sessions, %v", len(sessions), err)
+		}
+	})
+}
+
+func TestDeleteEventsUpdatesRollups(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, dsn string, db *DB) {
+		s := logSession(t, db, "streamer1", 3)
+
+		n, err := db.DeleteEvents(EventSelection{Match: EventSelector{Types: []string{"chat", "stats"}}})
+		if err != nil {
+			t.Fatalf("DeleteEvents: %v", err)
+		}
+		if n != 6 {
+			t.Errorf("DeleteEvents deleted %d events, want 6", n)
+		}
+		total := rollupTotals(t, db, s.ID)
+		want := Rollup{Events: 9, Gifts: 3, Diamonds: 6, Likes: 15, Follows: 3}
+		if total != want {
+			t.Errorf("rollups = %+v, want %+v", total, want)
+		}
+
+		n, err = db.DeleteViewer(Viewer{Nicknames: []string{"bob"}})
+		if err != nil {
+			t.Fatalf("DeleteViewer: %v", err)
+		}
+		if n != 3 {
+			t.Errorf("DeleteViewer deleted %d events, want 3", n)
+		}
+		if total := rollupTotals(t, db, s.ID); total.Events != 6 || total.Gifts != 0 || total.Diamonds != 0 {
+			t.Errorf("rollups after DeleteViewer = %+v", total)
+		}
+	})
+}
+
+func TestTopicCacheUpsert(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, dsn string, db *DB) {
+		s := logSession(t, db, "streamer1", 1)
+		for _, data := range []string{"first", "second"} {
+			err := db.SaveTopicCache(TopicCache{SessionID: s.ID, Version: 1, Messages: 1, Data: []byte(data), CreatedAt: testStart})
+			if err != nil {
+				t.Fatalf("SaveTopicCache: %v", err)
+			}
+		}
+		cache, err := db.GetTopicCache(s.ID)
+		if err != nil {
+			t.Fatalf("GetTopicCache: %v", err)
+		}
+		if string(cache.Data) != "second" {
+			t.Errorf("cache data = %q, want the second save", cache.Data)
+		}
+	})
+}
+
+func TestArchiveAndRestore(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, dsn string, db *DB) {
+		s := logSession(t, db, "streamer1", 2)
+		events, err := db.GetStoredEvents(s.ID)
+		if err != nil {
+			t.Fatalf("GetStoredEvents: %v", err)
+		}
+
+		if _, err := db.ArchiveSession(s.ID, "/archive/1.json"); err != nil {
+			t.Fatalf("ArchiveSession: %v", err)
+		}
+		s.Archive = "/archive/1.json"
+		id, err := db.RestoreSession(s, events, nil)
+		if err != nil {
+			t.Fatalf("RestoreSession: %v", err)
+		}
+		restored, err := db.GetStoredEvents(id)
+		if err != nil {
+			t.Fatalf("GetStoredEvents: %v", err)
+		}
+		if id != s.ID || len(restored) != len(events) || restored[0].ID != events[0].ID {
+			t.Errorf("restored session %d with %d events, want session %d with %d", id, len(restored), s.ID, len(events))
+		}
+
+		// New rows get IDs after the restored ones
+		eventID, err := db.SaveEvent(Event{SessionID: id, Username: "streamer1", Type: "chat", Content: "a: b", Timestamp: testStart})
+		if err != nil {
+			t.Fatalf("SaveEvent after restore: %v", err)
+		}
+		if eventID <= events[len(events)-1].ID {
+			t.Errorf("SaveEvent after restore returned ID %d", eventID)
+		}
+	})
+}
+
+func TestRestoreIntoOtherDatabase(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, dsn string, db *DB) {
+		// The session keeps its ID in a database that doesn't have it yet,
+		// and later sessions are numbered after it
+		s := Session{ID: 7, Username: "streamer1", StartedAt: testStart, EndedAt: testStart.Add(time.Minute)}
+		events := []Event{{Type: "chat", Content: "alice: hi", Nickname: "alice", Timestamp: testStart}}
+		id, err := db.RestoreSession(s, events, nil)
+		if err != nil {
+			t.Fatalf("RestoreSession: %v", err)
+		}
+		if id != 7 {
+			t.Errorf("restored session got ID %d, want 7", id)
+		}
+		next, err := db.StartSession("streamer1", testStart.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("StartSession after restore: %v", err)
+		}
+		if next <= 7 {
+			t.Errorf("StartSession after restore returned ID %d", next)
+		}
+	})
+}
+
+func TestRestoreOverReusedID(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, dsn string, db *DB) {
+		// A session archived elsewhere under the ID of a live one here
+		live := logSession(t, db, "streamer1", 1)
+		s := Session{ID: live.ID, Username: "streamer1", StartedAt: testStart.Add(-24 * time.Hour), EndedAt: testStart.Add(-23 * time.Hour)}
+		events := []Event{{ID: 1, Type: "chat", Content: "alice: hi", Nickname: "alice", Timestamp: s.StartedAt}}
+		id, err := db.RestoreSession(s, events, nil)
+		if err != nil {
+			t.Fatalf("RestoreSession: %v", err)
+		}
+		if id == live.ID {
+			t.Fatalf("restored session took the ID of session %d", live.ID)
+		}
+		restored, err := db.GetStoredEvents(id)
+		if err != nil || len(restored) != 1 || restored[0].ID == 1 {
+			t.Errorf("restored events = %+v, %v", restored, err)
+		}
+		if kept, err := db.GetStoredEvents(live.ID); err != nil || len(kept) != 5 {
+			t.Errorf("session %d has %d events after the restore, want 5 (%v)", live.ID, len(kept), err)
+		}
+
+		// The live session itself can't be restored over
+		if _, err := db.RestoreSession(live, events, nil); err == nil {
+			t.Error("RestoreSession into the same live session succeeded")
+		}
+	})
+}
+
+// likeTotal sums the likes of events
+func likeTotal(events []Event) int64 {
+	var total int64
+	for _, e := range events {
+		if e.Type == "like" {
+			total += e.Value
+		}
+	}
+	return total
+}
+
+func TestCompactSkipsArchivedSessions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, dsn string, db *DB) {
+		s := logSession(t, db, "streamer1", 3)
+		want := rollupTotals(t, db, s.ID)
+		events, err := db.GetStoredEvents(s.ID)
+		if err != nil {
+			t.Fatalf("GetStoredEvents: %v", err)
+		}
+		s.Archive = "/archive/1.json"
+		if _, err := db.ArchiveSession(s.ID, s.Archive); err != nil {
+			t.Fatalf("ArchiveSession: %v", err)
+		}
+
+		n, err := db.Compact(testStart.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("Compact: %v", err)
+		}
+		if before, err := db.compactedBefore(s.ID); n != 0 || err != nil || !before.IsZero() {
+			t.Errorf("Compact deleted %d events and marked the stub compacted before %v (%v)", n, before, err)
+		}
+
+		check := func(id int64) {
+			t.Helper()
+			restored, err := db.GetEventsBySession(id)
+			if err != nil {
+				t.Fatalf("GetEventsBySession: %v", err)
+			}
+			if likes := likeTotal(restored); likes != 15 {
+				t.Errorf("restored session has %d likes, want 15", likes)
+			}
+			if total := rollupTotals(t, db, id); total != want {
+				t.Errorf("rollups = %+v, want %+v", total, want)
+			}
+		}
+		id, err := db.RestoreSession(s, events, nil)
+		if err != nil {
+			t.Fatalf("RestoreSession: %v", err)
+		}
+		check(id)
+
+		// A stub compacted by an earlier version keeps the likes in its
+		// rollups instead of restoring them twice
+		if _, err := db.ArchiveSession(s.ID, s.Archive); err != nil {
+			t.Fatalf("ArchiveSession: %v", err)
+		}
+		if _, err := db.db.Exec(`UPDATE sessions SET compacted_before = ? WHERE id = ?`, testStart.Add(time.Hour), s.ID); err != nil {
+			t.Fatal(err)
+		}
+		if id, err = db.RestoreSession(s, events, nil); err != nil {
+			t.Fatalf("RestoreSession: %v", err)
+		}
+		check(id)
+	})
+}
+
+func TestRestoreCompactedSession(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, dsn string, db *DB) {
+		s := logSession(t, db, "streamer1", 3)
+		want := rollupTotals(t, db, s.ID)
+		if _, err := db.Compact(testStart.Add(time.Hour)); err != nil {
+			t.Fatalf("Compact: %v", err)
+		}
+		events, err := db.GetStoredEvents(s.ID)
+		if err != nil {
+			t.Fatalf("GetStoredEvents: %v", err)
+		}
+		compaction, err := db.GetCompaction(s.ID)
+		if err != nil || compaction == nil {
+			t.Fatalf("GetCompaction = %v, %v", compaction, err)
+		}
+
+		// The likes and viewer counts in the rollups come along into a
+		// database that never had the session
+		other := openDB(t, filepath.Join(t.TempDir(), "other.db"))
+		id, err := other.RestoreSession(s, events, compaction)
+		if err != nil {
+			t.Fatalf("RestoreSession: %v", err)
+		}
+		restored, err := other.GetEventsBySession(id)
+		if err != nil {
+			t.Fatalf("GetEventsBySession: %v", err)
+		}
+		if likes := likeTotal(restored); likes != 15 {
+			t.Errorf("restored session has %d likes, want 15", likes)
+		}
+		if total := rollupTotals(t, other, id); total != want {
+			t.Errorf("rollups = %+v, want %+v", total, want)
+		}
+		if before, err := other.compactedBefore(id); err != nil || !before.Equal(compaction.Before) {
+			t.Errorf("restored session compacted before %v, want %v (%v)", before, compaction.Before, err)
+		}
+	})
+}
+
+func TestExportToDB(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, dsn string, db *DB) {
+		s := logSession(t, db, "streamer1", 2)
+		logSession(t, db, "streamer2", 1)
+
+		path := filepath.Join(t.TempDir(), "export.db")
+		if err := db.ExportToDB("streamer1", path); err != nil {
+			t.Fatalf("ExportToDB: %v", err)
+		}
+		export := openDB(t, path)
+		events, err := export.GetEventsBySession(s.ID)
+		if err != nil {
+			t.Fatalf("GetEventsBySession: %v", err)
+		}
+		if len(events) != 10 {
+			t.Errorf("exported %d events, want 10", len(events))
+		}
+		next, err := export.StartSession("streamer1", testStart)
+		if err != nil {
+			t.Fatalf("StartSession in export: %v", err)
+		}
+		if next <= s.ID {
+			t.Errorf("StartSession in export returned ID %d", next)
+		}
+	})
+}
+
+func TestVacuum(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, dsn string, db *DB) {
+		logSession(t, db, "streamer1", 5)
+		if _, err := db.DeleteEvents(EventSelection{}); err != nil {
+			t.Fatalf("DeleteEvents: %v", err)
+		}
+
+		size, _, err := db.Size()
+		if err != nil || size <= 0 {
+			t.Fatalf("Size = %d, %v", size, err)
+		}
+		if err := db.Vacuum(); err != nil {
+			t.Fatalf("Vacuum: %v", err)
+		}
+		// After a full vacuum, both backends support the incremental one
+		ok, err := db.IncrementalVacuum()
+		if err != nil || !ok {
+			t.Errorf("IncrementalVacuum = %v, %v", ok, err)
+		}
+	})
+}
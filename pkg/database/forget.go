@@ -0,0 +1,157 @@
+package database
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// ForgottenViewer replaces the nickname of anonymized viewers
+const ForgottenViewer = "[forgotten]"
+
+// forgottenMessage replaces the text of anonymized chat messages
+const forgottenMessage = "[removed]"
+
+// Viewer identifies a viewer by any of their nicknames and user IDs, as
+// stored: pseudonyms in privacy mode
+type Viewer struct {
+	Nicknames []string
+	UserIDs   []int64
+}
+
+// where matches the viewer's events. Events logged before nicknames were
+// stored in their own column are matched by the nickname their content
+// starts with.
+func (v Viewer) where() (string, []any) {
+	var conds []string
+	var args []any
+	if len(v.Nicknames) > 0 {
+		conds = append(conds, "nickname IN ("+placeholders(len(v.Nicknames))+")")
+		for _, n := range v.Nicknames {
+			args = append(args, n)
+		}
+		for _, n := range v.Nicknames {
+			conds = append(conds, "(nickname = '' AND type <> 'stats' AND substr(content, 1, ?) IN (?, ?))")
+			args = append(args, utf8.RuneCountInString(n)+1, n+":", n+" ")
+		}
+	}
+	if len(v.UserIDs) > 0 {
+		conds = append(conds, "user_id IN ("+placeholders(len(v.UserIDs))+")")
+		for _, id := range v.UserIDs {
+			args = append(args, id)
+		}
+	}
+	if len(conds) == 0 {
+		return "1 = 0", nil
+	}
+	return "(" + strings.Join(conds, " OR ") + ")", args
+}
+
+// CountViewerEvents returns how many stored events of a viewer there are
+// and in how many sessions
+func (d *DB) CountViewerEvents(v Viewer) (events, sessions int64, err error) {
+	where, args := v.where()
+	query := `SELECT COUNT(*), COUNT(DISTINCT session_id) FROM events WHERE ` + where
+	err = d.db.QueryRow(query, args...).Scan(&events, &sessions)
+	return events, sessions, err
+}
+
+// DeleteViewer deletes all stored events of a viewer, taking them out of
+// the rollups, so totals no longer count them, and returns how many were
+// deleted
+func (d *DB) DeleteViewer(v Viewer) (int64, error) {
+	where, args := v.where()
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	if err := dropTopicCaches(tx, where, args); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	n, err := deleteEventsTx(tx, where, args, 0)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+// AnonymizeViewer keeps the events of a viewer for statistics but removes
+// their nickname, user ID and chat messages, and returns how many events
+// were anonymized
+func (d *DB) AnonymizeViewer(v Viewer) (int64, error) {
+	where, args := v.where()
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	events, err := viewerEvents(tx, where, args)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err := dropTopicCaches(tx, where, args); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	stmt, err := tx.Prepare(`UPDATE events SET nickname = ?, user_id = 0, content = ? WHERE id = ?`)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	defer stmt.Close()
+	for _, e := range events {
+		if _, err := stmt.Exec(ForgottenViewer, anonymizedContent(e, v), e.ID); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int64(len(events)), nil
+}
+
+func viewerEvents(tx *tx, where string, args []any) ([]Event, error) {
+	rows, err := tx.Query(`SELECT id, type, content, nickname FROM events WHERE `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.ID, &e.Type, &e.Content, &e.Nickname); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// anonymizedContent replaces the nickname an event's content starts with
+// and drops the text of chat messages
+func anonymizedContent(e Event, v Viewer) string {
+	if e.Type == "chat" {
+		return ForgottenViewer + ": " + forgottenMessage
+	}
+	names := v.Nicknames
+	if e.Nickname != "" {
+		names = []string{e.Nickname}
+	}
+	for _, name := range names {
+		if rest, ok := strings.CutPrefix(e.Content, name); ok {
+			return ForgottenViewer + rest
+		}
+	}
+	return e.Content
+}
+
+// dropTopicCaches removes the cached analytics of the sessions with matched
+// events, so they are computed again without them
+func dropTopicCaches(tx *tx, where string, args []any) error {
+	_, err := tx.Exec(`DELETE FROM topic_cache WHERE session_id IN (SELECT session_id FROM events WHERE `+where+`)`, args...)
+	return err
+}
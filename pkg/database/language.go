@@ -0,0 +1,79 @@
+package database
+
+import "strings"
+
+// EventLanguage is the detected language of a chat event
+type EventLanguage struct {
+	EventID  int64
+	Language string
+}
+
+// LanguageCount is the number of chat messages of a session in a language
+type LanguageCount struct {
+	Language string
+	Messages int64
+}
+
+// languageFilter returns a WHERE condition keeping non-chat events and chat
+// messages in one of the languages, or nothing without languages
+func languageFilter(languages []string) (string, []any) {
+	if len(languages) == 0 {
+		return "", nil
+	}
+	args := make([]any, len(languages))
+	for i, l := range languages {
+		args[i] = l
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(languages)), ", ")
+	return ` AND (type != 'chat' OR language IN (` + placeholders + `))`, args
+}
+
+// SaveLanguages stores the languages of already saved chat events in one
+// transaction
+func (d *DB) SaveLanguages(languages []EventLanguage) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`UPDATE events SET language = ? WHERE id = ?`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, l := range languages {
+		if _, err := stmt.Exec(l.Language, l.EventID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetLanguageCounts returns the number of chat messages of a session per
+// detected language, most used first
+func (d *DB) GetLanguageCounts(sessionID int64) ([]LanguageCount, error) {
+	query := `
+	SELECT language, COUNT(*) AS messages
+	FROM events
+	WHERE session_id = ? AND type = 'chat' AND language IS NOT NULL
+	GROUP BY language
+	ORDER BY messages DESC, language ASC
+	`
+	rows, err := d.db.Query(query, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []LanguageCount
+	for rows.Next() {
+		var c LanguageCount
+		if err := rows.Scan(&c.Language, &c.Messages); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
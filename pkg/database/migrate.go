@@ -0,0 +1,153 @@
+package database
+
+import (
+	"fmt"
+)
+
+// migrations are applied in order on top of the base schema from createTables.
+// The number of applied migrations is tracked in PRAGMA user_version, or the
+// schema_version table on PostgreSQL, so entries must only ever be appended.
+// They are written for SQLite and adapted by the backend.
+var migrations = []string{
+	// 1: moderation flags
+	`ALTER TABLE events ADD COLUMN flags TEXT NOT NULL DEFAULT ''`,
+
+	// 2: sessions and structured event fields
+	`
+	CREATE TABLE IF NOT EXISTS sessions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT NOT NULL,
+		started_at DATETIME NOT NULL,
+		ended_at DATETIME
+	);
+	CREATE INDEX IF NOT EXISTS idx_sessions_username ON sessions(username);
+	ALTER TABLE events ADD COLUMN session_id INTEGER REFERENCES sessions(id);
+	ALTER TABLE events ADD COLUMN nickname TEXT NOT NULL DEFAULT '';
+	ALTER TABLE events ADD COLUMN value INTEGER NOT NULL DEFAULT 0;
+	CREATE INDEX IF NOT EXISTS idx_events_session ON events(session_id);
+	`,
+
+	// 3: group events logged before sessions existed into one session per
+	// streamer and day, and recover viewer counts from their content
+	`
+	INSERT INTO sessions (username, started_at, ended_at)
+	SELECT username, MIN(timestamp), MAX(timestamp)
+	FROM events
+	WHERE session_id IS NULL
+	GROUP BY username, date(timestamp);
+	UPDATE events SET session_id = (
+		SELECT s.id FROM sessions s
+		WHERE s.username = events.username AND date(s.started_at) = date(events.timestamp)
+	)
+	WHERE session_id IS NULL;
+	UPDATE events SET value = CAST(substr(content, length('Viewer count: ') + 1) AS INTEGER)
+	WHERE type = 'stats' AND content LIKE 'Viewer count: %';
+	`,
+
+	// 4: cached text analytics per session
+	`
+	CREATE TABLE IF NOT EXISTS topic_cache (
+		session_id INTEGER PRIMARY KEY REFERENCES sessions(id),
+		version INTEGER NOT NULL,
+		messages INTEGER NOT NULL,
+		last_event_id INTEGER NOT NULL,
+		data BLOB NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+	`,
+
+	// 5: stable viewer IDs for audience analytics
+	`
+	ALTER TABLE events ADD COLUMN user_id INTEGER NOT NULL DEFAULT 0;
+	CREATE INDEX IF NOT EXISTS idx_events_user ON events(user_id);
+	`,
+
+	// 6: chat sentiment and toxicity scores, NULL until scored
+	`
+	ALTER TABLE events ADD COLUMN sentiment REAL;
+	ALTER TABLE events ADD COLUMN toxicity REAL;
+	`,
+
+	// 7: detected chat language, NULL until detected
+	`ALTER TABLE events ADD COLUMN language TEXT`,
+
+	// 8: per-minute and per-hour rollups of each session, with buckets and
+	// last_event in Unix seconds, and how far raw events were compacted
+	`
+	CREATE TABLE IF NOT EXISTS rollups (
+		session_id INTEGER NOT NULL REFERENCES sessions(id),
+		resolution INTEGER NOT NULL,
+		bucket INTEGER NOT NULL,
+		events INTEGER NOT NULL,
+		chats INTEGER NOT NULL,
+		gifts INTEGER NOT NULL,
+		diamonds INTEGER NOT NULL,
+		likes INTEGER NOT NULL,
+		follows INTEGER NOT NULL,
+		shares INTEGER NOT NULL,
+		viewer_samples INTEGER NOT NULL,
+		viewer_sum INTEGER NOT NULL,
+		viewer_max INTEGER NOT NULL,
+		last_event INTEGER NOT NULL,
+		PRIMARY KEY (session_id, resolution, bucket)
+	);
+	ALTER TABLE sessions ADD COLUMN compacted_before DATETIME;
+	INSERT INTO rollups (session_id, resolution, bucket, events, chats, gifts, diamonds, likes,
+		follows, shares, viewer_samples, viewer_sum, viewer_max, last_event)
+	SELECT session_id, 60, CAST(strftime('%s', timestamp) AS INTEGER) / 60 * 60,
+		COUNT(*), SUM(type = 'chat'), SUM(type = 'gift'),
+		SUM(CASE WHEN type = 'gift' THEN value ELSE 0 END),
+		SUM(CASE WHEN type = 'like' THEN value ELSE 0 END),
+		SUM(type = 'follow'), SUM(type = 'share'), SUM(type = 'stats'),
+		SUM(CASE WHEN type = 'stats' THEN value ELSE 0 END),
+		MAX(CASE WHEN type = 'stats' THEN value ELSE 0 END),
+		MAX(CAST(strftime('%s', timestamp) AS INTEGER))
+	FROM events
+	WHERE session_id IS NOT NULL
+	GROUP BY 1, 3;
+	INSERT INTO rollups (session_id, resolution, bucket, events, chats, gifts, diamonds, likes,
+		follows, shares, viewer_samples, viewer_sum, viewer_max, last_event)
+	SELECT session_id, 3600, CAST(strftime('%s', timestamp) AS INTEGER) / 3600 * 3600,
+		COUNT(*), SUM(type = 'chat'), SUM(type = 'gift'),
+		SUM(CASE WHEN type = 'gift' THEN value ELSE 0 END),
+		SUM(CASE WHEN type = 'like' THEN value ELSE 0 END),
+		SUM(type = 'follow'), SUM(type = 'share'), SUM(type = 'stats'),
+		SUM(CASE WHEN type = 'stats' THEN value ELSE 0 END),
+		MAX(CASE WHEN type = 'stats' THEN value ELSE 0 END),
+		MAX(CAST(strftime('%s', timestamp) AS INTEGER))
+	FROM events
+	WHERE session_id IS NOT NULL
+	GROUP BY 1, 3;
+	`,
+
+	// 9: manifest path of sessions archived out of the database
+	`ALTER TABLE sessions ADD COLUMN archive TEXT`,
+}
+
+func migrate(db *conn) error {
+	version, err := db.backend.version(db.DB)
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.DB.Begin()
+		if err != nil {
+			return err
+		}
+		if ddl := db.backend.schema(i+1, migrations[i]); ddl != "" {
+			if _, err := tx.Exec(ddl); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+			}
+		}
+		if err := db.backend.setVersion(tx, i+1); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
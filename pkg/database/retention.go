@@ -0,0 +1,417 @@
+package database
+
+import (
+	"database/sql"
+	"slices"
+	"strings"
+	"time"
+)
+
+// EventSelector matches events by streamer and type. Empty fields match any.
+type EventSelector struct {
+	Username string
+	Types    []string
+}
+
+func (s EventSelector) where() (string, []any) {
+	conds := []string{"1 = 1"}
+	var args []any
+	if s.Username != "" {
+		conds = append(conds, "username = ?")
+		args = append(args, s.Username)
+	}
+	if len(s.Types) > 0 {
+		conds = append(conds, "type IN ("+strings.TrimSuffix(strings.Repeat("?, ", len(s.Types)), ", ")+")")
+		for _, t := range s.Types {
+			args = append(args, t)
+		}
+	}
+	return "(" + strings.Join(conds, " AND ") + ")", args
+}
+
+// matches reports whether the selector matches events of a streamer and type
+func (s EventSelector) matches(username, eventType string) bool {
+	return (s.Username == "" || s.Username == username) && (len(s.Types) == 0 || slices.Contains(s.Types, eventType))
+}
+
+// EventSelection is the events matched by Match but none of Except, logged
+// in [After, Before). Zero times leave the range open.
+type EventSelection struct {
+	Match  EventSelector
+	Except []EventSelector
+	After  time.Time
+	Before time.Time
+}
+
+func (s EventSelection) where() (string, []any) {
+	where, args := s.Match.where()
+	for _, e := range s.Except {
+		cond, a := e.where()
+		where += " AND NOT " + cond
+		args = append(args, a...)
+	}
+	if !s.After.IsZero() {
+		where += " AND timestamp >= ?"
+		args = append(args, s.After)
+	}
+	if !s.Before.IsZero() {
+		where += " AND timestamp < ?"
+		args = append(args, s.Before)
+	}
+	return where, args
+}
+
+// EventCount is the number of selected events of a streamer and type
+type EventCount struct {
+	Username string
+	Type     string
+	Events   int64
+}
+
+// CountEvents counts the selected events per streamer and type
+func (d *DB) CountEvents(sel EventSelection) ([]EventCount, error) {
+	where, args := sel.where()
+	query := `
+	SELECT username, type, COUNT(*)
+	FROM events
+	WHERE ` + where + `
+	GROUP BY username, type
+	ORDER BY username, type
+	`
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []EventCount
+	for rows.Next() {
+		var c EventCount
+		if err := rows.Scan(&c.Username, &c.Type, &c.Events); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// GetSelectedEvents returns the selected events ordered by session and time
+func (d *DB) GetSelectedEvents(sel EventSelection) ([]Event, error) {
+	where, args := sel.where()
+	query := `
+	SELECT ` + eventColumns + `
+	FROM events
+	WHERE ` + where + `
+	ORDER BY session_id, timestamp, id
+	`
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanEvents(rows)
+}
+
+// matches reports whether the selection matches events of a streamer and
+// type, regardless of their time
+func (s EventSelection) matches(username, eventType string) bool {
+	if !s.Match.matches(username, eventType) {
+		return false
+	}
+	for _, e := range s.Except {
+		if e.matches(username, eventType) {
+			return false
+		}
+	}
+	return true
+}
+
+// compactedDeltas returns what the minute rollups of compacted sessions hold
+// of the selected like and stats events, whose rows Compact deleted, as
+// deltas for their minute and hour rollups. Only whole minutes before
+// sel.Before count. It also returns how many events that is per streamer
+// and type.
+func compactedDeltas(tx *tx, sel EventSelection) ([]rollupKey, map[rollupKey]*Rollup, []EventCount, error) {
+	type compacted struct {
+		id       int64
+		username string
+		before   time.Time
+	}
+	rows, err := tx.Query(`SELECT id, username, compacted_before FROM sessions WHERE compacted_before IS NOT NULL AND archive IS NULL ORDER BY id`)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var sessions []compacted
+	for rows.Next() {
+		var c compacted
+		if err := rows.Scan(&c.id, &c.username, &c.before); err != nil {
+			rows.Close()
+			return nil, nil, nil, err
+		}
+		sessions = append(sessions, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	deltas := make(map[rollupKey]*Rollup)
+	var keys []rollupKey
+	delta := func(k rollupKey) *Rollup {
+		r := deltas[k]
+		if r == nil {
+			r = &Rollup{}
+			deltas[k] = r
+			keys = append(keys, k)
+		}
+		return r
+	}
+	counted := make(map[EventCount]int64)
+	var counts []EventCount
+	for _, c := range sessions {
+		likes, stats := sel.matches(c.username, "like"), sel.matches(c.username, "stats")
+		if !likes && !stats {
+			continue
+		}
+		end := c.before
+		if !sel.Before.IsZero() && sel.Before.Truncate(MinuteRollup).Before(end) {
+			end = sel.Before.Truncate(MinuteRollup)
+		}
+		var start int64
+		if !sel.After.IsZero() {
+			start = sel.After.Unix()
+		}
+		minute, hour := int64(MinuteRollup/time.Second), int64(HourRollup/time.Second)
+		rows, err := tx.Query(`SELECT `+rollupColumns+` FROM rollups WHERE session_id = ? AND resolution = ? AND bucket >= ? AND bucket < ? ORDER BY bucket`,
+			c.id, minute, start, end.Unix())
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		rollups, err := scanRollups(rows)
+		rows.Close()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		for _, r := range rollups {
+			var d Rollup
+			if likes {
+				// Every other event type has a count of its own
+				d.Events = r.Events - r.Chats - r.Gifts - r.Follows - r.Shares - r.ViewerSamples
+				d.Likes = r.Likes
+				counted[EventCount{Username: c.username, Type: "like"}] += d.Events
+			}
+			if stats {
+				d.Events += r.ViewerSamples
+				d.ViewerSamples, d.ViewerSum = r.ViewerSamples, r.ViewerSum
+				counted[EventCount{Username: c.username, Type: "stats"}] += r.ViewerSamples
+			}
+			if d.Events == 0 {
+				continue
+			}
+			bucket := r.Start.Unix()
+			for _, k := range []rollupKey{{c.id, minute, bucket}, {c.id, hour, bucket / hour * hour}} {
+				total := delta(k)
+				total.Events += d.Events
+				total.Likes += d.Likes
+				total.ViewerSamples += d.ViewerSamples
+				total.ViewerSum += d.ViewerSum
+			}
+		}
+	}
+
+	for c, n := range counted {
+		if n > 0 {
+			c.Events = n
+			counts = append(counts, c)
+		}
+	}
+	slices.SortFunc(counts, func(a, b EventCount) int {
+		return strings.Compare(a.Username+"\x00"+a.Type, b.Username+"\x00"+b.Type)
+	})
+	return keys, deltas, counts, nil
+}
+
+// CountCompacted counts the selected like and stats events per streamer and
+// type that compacted sessions only keep in their rollups
+func (d *DB) CountCompacted(sel EventSelection) ([]EventCount, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	_, _, counts, err := compactedDeltas(tx, sel)
+	return counts, err
+}
+
+// DeleteCompacted takes the selected like and stats events that compacted
+// sessions only keep in their rollups out of the rollups, as DeleteEvents
+// does for stored events, and returns how many events that was
+func (d *DB) DeleteCompacted(sel EventSelection) (int64, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	keys, deltas, counts, err := compactedDeltas(tx, sel)
+	if err == nil {
+		err = subtractRollups(tx, keys, deltas)
+	}
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	var total int64
+	for _, c := range counts {
+		total += c.Events
+	}
+	return total, tx.Commit()
+}
+
+// deleteBatch bounds the rows deleted per statement, so a logger writing to
+// the same database is never locked out for long
+const deleteBatch = 5000
+
+// DeleteEvents deletes the selected events and returns how many were deleted
+func (d *DB) DeleteEvents(sel EventSelection) (int64, error) {
+	where, args := sel.where()
+	var total int64
+	for {
+		n, err := d.deleteEvents(where, args, deleteBatch)
+		total += n
+		if err != nil || n < deleteBatch {
+			return total, err
+		}
+	}
+}
+
+// DeleteEventsByID deletes events by ID and returns how many were deleted
+func (d *DB) DeleteEventsByID(ids []int64) (int64, error) {
+	var total int64
+	for len(ids) > 0 {
+		batch := ids[:min(len(ids), 500)]
+		ids = ids[len(batch):]
+
+		args := make([]any, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+		n, err := d.deleteEvents(`id IN (`+placeholders(len(batch))+`)`, args, 0)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// placeholders returns n comma separated query placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// deleteEvents deletes up to limit events matching where in a transaction of
+// its own, see deleteEventsTx
+func (d *DB) deleteEvents(where string, args []any, limit int) (int64, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	n, err := deleteEventsTx(tx, where, args, limit)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+// deleteEventsTx deletes up to limit events matching where, or all of them
+// for zero, and takes them out of the rollups of their sessions. It returns
+// how many were deleted.
+func deleteEventsTx(tx *tx, where string, args []any, limit int) (int64, error) {
+	query := `SELECT id, session_id, type, value, timestamp FROM events WHERE ` + where
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args[:len(args):len(args)], limit)
+	}
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	var events []Event
+	for rows.Next() {
+		var e Event
+		var sessionID sql.NullInt64
+		if err := rows.Scan(&e.ID, &sessionID, &e.Type, &e.Value, &e.Timestamp); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		e.SessionID = sessionID.Int64
+		events = append(events, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for batch := events; len(batch) > 0; {
+		n := min(len(batch), 500)
+		ids := make([]any, n)
+		for i, e := range batch[:n] {
+			ids[i] = e.ID
+		}
+		if _, err := tx.Exec(`DELETE FROM events WHERE id IN (`+placeholders(n)+`)`, ids...); err != nil {
+			return 0, err
+		}
+		batch = batch[n:]
+	}
+	if err := removeFromRollups(tx, events); err != nil {
+		return 0, err
+	}
+	return int64(len(events)), nil
+}
+
+// DeleteEmptySessions deletes finished sessions without any events left,
+// along with their cached analytics and rollups, and returns how many were
+// deleted. Compacted and archived sessions are kept.
+func (d *DB) DeleteEmptySessions() (int64, error) {
+	empty := `
+	SELECT id FROM sessions
+	WHERE ended_at IS NOT NULL AND compacted_before IS NULL AND archive IS NULL
+		AND NOT EXISTS (SELECT 1 FROM events WHERE events.session_id = sessions.id)
+	`
+	// Rows referring to the sessions go first, PostgreSQL enforces foreign
+	// keys. Orphans left by older versions are removed along with them.
+	for _, table := range []string{"topic_cache", "rollups"} {
+		_, err := d.db.Exec(`DELETE FROM ` + table + ` WHERE session_id IN (` + empty + `)
+			OR session_id NOT IN (SELECT id FROM sessions)`)
+		if err != nil {
+			return 0, err
+		}
+	}
+	result, err := d.db.Exec(`DELETE FROM sessions WHERE id IN (` + empty + `)`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Size returns the size of the database in bytes and how many of them are
+// free pages a vacuum would give back
+func (d *DB) Size() (size, free int64, err error) {
+	return d.db.backend.size(d.db.DB)
+}
+
+// Vacuum rebuilds the database file without free pages. It also switches the
+// database to incremental auto-vacuum, so IncrementalVacuum works afterwards.
+func (d *DB) Vacuum() error {
+	_, err := d.db.backend.vacuum(d.db.DB, false)
+	return err
+}
+
+// IncrementalVacuum gives free pages back to the file system without
+// rebuilding the database. It reports false if the database isn't in
+// incremental auto-vacuum mode yet, which takes one Vacuum.
+func (d *DB) IncrementalVacuum() (bool, error) {
+	return d.db.backend.vacuum(d.db.DB, true)
+}
@@ -0,0 +1,383 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// Rollup resolutions. Summaries read the hourly rollups, charts of compacted
+// sessions the minute ones.
+const (
+	MinuteRollup = time.Minute
+	HourRollup   = time.Hour
+)
+
+// Rollup aggregates the events of a session in one bucket
+type Rollup struct {
+	SessionID int64
+	Start     time.Time
+	Events    int64
+	Chats     int64
+	Gifts     int64
+	Diamonds  int64
+	Likes     int64
+	Follows   int64
+	Shares    int64
+	// ViewerSamples counts the viewer count updates, with their sum and
+	// the highest count
+	ViewerSamples int64
+	ViewerSum     int64
+	ViewerMax     int64
+	LastEventAt   time.Time
+}
+
+// rollupColumns is the column list read by scanRollups
+const rollupColumns = `session_id, bucket, events, chats, gifts, diamonds, likes, follows, shares,
+	viewer_samples, viewer_sum, viewer_max, last_event`
+
+// addToRollups counts a newly saved event in its minute and hour rollups
+func addToRollups(tx *tx, event Event) error {
+	r := Rollup{Events: 1}
+	switch event.Type {
+	case "chat":
+		r.Chats = 1
+	case "gift":
+		r.Gifts, r.Diamonds = 1, event.Value
+	case "like":
+		r.Likes = event.Value
+	case "follow":
+		r.Follows = 1
+	case "share":
+		r.Shares = 1
+	case "stats":
+		r.ViewerSamples, r.ViewerSum, r.ViewerMax = 1, event.Value, event.Value
+	}
+
+	query := `
+	INSERT INTO rollups (session_id, resolution, bucket, events, chats, gifts, diamonds, likes,
+		follows, shares, viewer_samples, viewer_sum, viewer_max, last_event)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT (session_id, resolution, bucket) DO UPDATE SET
+		events = rollups.events + excluded.events,
+		chats = rollups.chats + excluded.chats,
+		gifts = rollups.gifts + excluded.gifts,
+		diamonds = rollups.diamonds + excluded.diamonds,
+		likes = rollups.likes + excluded.likes,
+		follows = rollups.follows + excluded.follows,
+		shares = rollups.shares + excluded.shares,
+		viewer_samples = rollups.viewer_samples + excluded.viewer_samples,
+		viewer_sum = rollups.viewer_sum + excluded.viewer_sum,
+		viewer_max = CASE WHEN excluded.viewer_max > rollups.viewer_max THEN excluded.viewer_max ELSE rollups.viewer_max END,
+		last_event = CASE WHEN excluded.last_event > rollups.last_event THEN excluded.last_event ELSE rollups.last_event END
+	`
+	at := event.Timestamp.Unix()
+	for _, resolution := range []time.Duration{MinuteRollup, HourRollup} {
+		seconds := int64(resolution / time.Second)
+		_, err := tx.Exec(query, event.SessionID, seconds, at/seconds*seconds,
+			r.Events, r.Chats, r.Gifts, r.Diamonds, r.Likes, r.Follows, r.Shares,
+			r.ViewerSamples, r.ViewerSum, r.ViewerMax, at)
+		if err != nil {
+			return fmt.Errorf("failed to update rollups: %w", err)
+		}
+	}
+	return nil
+}
+
+// rollupKey identifies a rollup bucket
+type rollupKey struct {
+	session    int64
+	resolution int64
+	bucket     int64
+}
+
+// removeFromRollups takes deleted events out of the rollups of their
+// sessions, so totals match the events left
+func removeFromRollups(tx *tx, events []Event) error {
+	deltas := make(map[rollupKey]*Rollup)
+	var keys []rollupKey
+	for _, event := range events {
+		if event.SessionID == 0 {
+			continue
+		}
+		at := event.Timestamp.Unix()
+		for _, resolution := range []time.Duration{MinuteRollup, HourRollup} {
+			seconds := int64(resolution / time.Second)
+			k := rollupKey{event.SessionID, seconds, at / seconds * seconds}
+			r := deltas[k]
+			if r == nil {
+				r = &Rollup{}
+				deltas[k] = r
+				keys = append(keys, k)
+			}
+			r.Events++
+			switch event.Type {
+			case "chat":
+				r.Chats++
+			case "gift":
+				r.Gifts++
+				r.Diamonds += event.Value
+			case "like":
+				r.Likes += event.Value
+			case "follow":
+				r.Follows++
+			case "share":
+				r.Shares++
+			case "stats":
+				r.ViewerSamples++
+				r.ViewerSum += event.Value
+			}
+		}
+	}
+	return subtractRollups(tx, keys, deltas)
+}
+
+// subtractRollups subtracts deltas from their rollups. The highest viewer
+// count can't be taken back and is only cleared with the last viewer count
+// of a bucket; buckets left without events are removed.
+func subtractRollups(tx *tx, keys []rollupKey, deltas map[rollupKey]*Rollup) error {
+	update := `
+	UPDATE rollups SET
+		events = events - ?,
+		chats = chats - ?,
+		gifts = gifts - ?,
+		diamonds = diamonds - ?,
+		likes = likes - ?,
+		follows = follows - ?,
+		shares = shares - ?,
+		viewer_max = CASE WHEN viewer_samples - ? > 0 THEN viewer_max ELSE 0 END,
+		viewer_samples = viewer_samples - ?,
+		viewer_sum = viewer_sum - ?
+	WHERE session_id = ? AND resolution = ? AND bucket = ?
+	`
+	for _, k := range keys {
+		r := deltas[k]
+		_, err := tx.Exec(update, r.Events, r.Chats, r.Gifts, r.Diamonds, r.Likes, r.Follows, r.Shares,
+			r.ViewerSamples, r.ViewerSamples, r.ViewerSum, k.session, k.resolution, k.bucket)
+		if err != nil {
+			return fmt.Errorf("failed to update rollups: %w", err)
+		}
+		_, err = tx.Exec(`DELETE FROM rollups WHERE session_id = ? AND resolution = ? AND bucket = ? AND events <= 0`,
+			k.session, k.resolution, k.bucket)
+		if err != nil {
+			return fmt.Errorf("failed to update rollups: %w", err)
+		}
+	}
+	return nil
+}
+
+func scanRollups(rows *sql.Rows) ([]Rollup, error) {
+	var rollups []Rollup
+	for rows.Next() {
+		var r Rollup
+		var start, last int64
+		err := rows.Scan(&r.SessionID, &start, &r.Events, &r.Chats, &r.Gifts, &r.Diamonds, &r.Likes,
+			&r.Follows, &r.Shares, &r.ViewerSamples, &r.ViewerSum, &r.ViewerMax, &last)
+		if err != nil {
+			return nil, err
+		}
+		r.Start, r.LastEventAt = time.Unix(start, 0), time.Unix(last, 0)
+		rollups = append(rollups, r)
+	}
+	return rollups, rows.Err()
+}
+
+// GetRollups returns the rollups of a session at a resolution, oldest first.
+// Given a non-zero before, only buckets starting before it are returned.
+func (d *DB) GetRollups(sessionID int64, resolution time.Duration, before time.Time) ([]Rollup, error) {
+	end := int64(math.MaxInt64)
+	if !before.IsZero() {
+		end = before.Unix()
+	}
+	query := `
+	SELECT ` + rollupColumns + `
+	FROM rollups
+	WHERE session_id = ? AND resolution = ? AND bucket < ?
+	ORDER BY bucket
+	`
+	rows, err := d.db.Query(query, sessionID, int64(resolution/time.Second), end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRollups(rows)
+}
+
+// compactedBefore returns the time before which a session's like and stats
+// events were replaced by rollups, or zero if it wasn't compacted
+func (d *DB) compactedBefore(sessionID int64) (time.Time, error) {
+	var before sql.NullTime
+	err := d.db.QueryRow(`SELECT compacted_before FROM sessions WHERE id = ?`, sessionID).Scan(&before)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	return before.Time, err
+}
+
+// Compaction is what a compacted session keeps in its rollups in place of
+// the like and stats events logged before a time
+type Compaction struct {
+	Before  time.Time
+	Minutes []Rollup
+	Hours   []Rollup
+}
+
+// GetCompaction returns the compaction of a session with all its rollups, or
+// nil if the session wasn't compacted
+func (d *DB) GetCompaction(sessionID int64) (*Compaction, error) {
+	before, err := d.compactedBefore(sessionID)
+	if err != nil || before.IsZero() {
+		return nil, err
+	}
+	c := &Compaction{Before: before}
+	if c.Minutes, err = d.GetRollups(sessionID, MinuteRollup, time.Time{}); err != nil {
+		return nil, err
+	}
+	if c.Hours, err = d.GetRollups(sessionID, HourRollup, time.Time{}); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// restoreCompaction stores the rollups of a compacted session, which already
+// count its events, and marks it compacted
+func restoreCompaction(tx *tx, sessionID int64, c *Compaction) error {
+	query := `
+	INSERT INTO rollups (session_id, resolution, bucket, events, chats, gifts, diamonds, likes,
+		follows, shares, viewer_samples, viewer_sum, viewer_max, last_event)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	for i, rollups := range [][]Rollup{c.Minutes, c.Hours} {
+		resolution := []time.Duration{MinuteRollup, HourRollup}[i]
+		for _, r := range rollups {
+			_, err := tx.Exec(query, sessionID, int64(resolution/time.Second), r.Start.Unix(),
+				r.Events, r.Chats, r.Gifts, r.Diamonds, r.Likes, r.Follows, r.Shares,
+				r.ViewerSamples, r.ViewerSum, r.ViewerMax, r.LastEventAt.Unix())
+			if err != nil {
+				return fmt.Errorf("failed to restore rollups: %w", err)
+			}
+		}
+	}
+	_, err := tx.Exec(`UPDATE sessions SET compacted_before = ? WHERE id = ?`, c.Before, sessionID)
+	return err
+}
+
+// withRollups adds one like and one stats event per minute for the part of
+// a session that was compacted, so its charts and totals stay the same. The
+// stats events carry the highest viewer count of their minute.
+func (d *DB) withRollups(sessionID int64, events []Event) ([]Event, error) {
+	before, err := d.compactedBefore(sessionID)
+	if err != nil || before.IsZero() {
+		return events, err
+	}
+	rollups, err := d.GetRollups(sessionID, MinuteRollup, before)
+	if err != nil {
+		return nil, err
+	}
+
+	var username string
+	if len(events) > 0 {
+		username = events[0].Username
+	} else if session, err := d.GetSession(sessionID); err == nil {
+		username = session.Username
+	}
+	for _, r := range rollups {
+		if r.Likes > 0 {
+			events = append(events, Event{SessionID: sessionID, Username: username, Type: "like",
+				Content: fmt.Sprintf("%d likes", r.Likes), Timestamp: r.Start, Value: r.Likes})
+		}
+		if r.ViewerSamples > 0 {
+			events = append(events, Event{SessionID: sessionID, Username: username, Type: "stats",
+				Content: fmt.Sprintf("Viewer count: %d", r.ViewerMax), Timestamp: r.Start, Value: r.ViewerMax})
+		}
+	}
+	slices.SortStableFunc(events, func(a, b Event) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+	return events, nil
+}
+
+// CountCompactable counts the events per streamer and type that Compact
+// would replace with rollups
+func (d *DB) CountCompactable(before time.Time) ([]EventCount, error) {
+	query := `
+	SELECT e.username, e.type, COUNT(*)
+	FROM events e
+	JOIN sessions s ON s.id = e.session_id
+	WHERE s.ended_at IS NOT NULL AND e.type IN ('like', 'stats') AND e.timestamp < ?
+	GROUP BY e.username, e.type
+	ORDER BY e.username, e.type
+	`
+	rows, err := d.db.Query(query, before.Truncate(MinuteRollup))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []EventCount
+	for rows.Next() {
+		var c EventCount
+		if err := rows.Scan(&c.Username, &c.Type, &c.Events); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// Compact deletes the like and stats events, which make up most rows, logged
+// before the given time in finished sessions, leaving their minute and hour
+// rollups in their place. Archived sessions are left alone, their events are
+// in the archive. It returns how many events were deleted.
+func (d *DB) Compact(before time.Time) (int64, error) {
+	// Only whole minutes can be rebuilt from the rollups
+	before = before.Truncate(MinuteRollup)
+
+	rows, err := d.db.Query(`
+	SELECT id FROM sessions
+	WHERE ended_at IS NOT NULL AND archive IS NULL AND started_at < ?
+		AND (compacted_before IS NULL OR compacted_before < ?)
+	`, before, before)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, id := range ids {
+		tx, err := d.db.Begin()
+		if err != nil {
+			return total, err
+		}
+		result, err := tx.Exec(`DELETE FROM events WHERE session_id = ? AND type IN ('like', 'stats') AND timestamp < ?`, id, before)
+		if err != nil {
+			tx.Rollback()
+			return total, err
+		}
+		if _, err := tx.Exec(`UPDATE sessions SET compacted_before = ? WHERE id = ?`, before, id); err != nil {
+			tx.Rollback()
+			return total, err
+		}
+		if err := tx.Commit(); err != nil {
+			return total, err
+		}
+		n, _ := result.RowsAffected()
+		total += n
+	}
+	return total, nil
+}
@@ -0,0 +1,30 @@
+package database
+
+// EventScore is the sentiment and toxicity of a chat event
+type EventScore struct {
+	EventID   int64
+	Sentiment float64
+	Toxicity  float64
+}
+
+// SaveScores stores the scores of already saved chat events in one transaction
+func (d *DB) SaveScores(scores []EventScore) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`UPDATE events SET sentiment = ?, toxicity = ? WHERE id = ?`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, s := range scores {
+		if _, err := stmt.Exec(s.Sentiment, s.Toxicity, s.EventID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// StreamerSummary aggregates all sessions logged for a streamer
+type StreamerSummary struct {
+	Username string
+	Sessions int
+	Events   int
+	Diamonds int64
+	LastSeen time.Time
+}
+
+// SessionSummary is a session with totals over its events
+type SessionSummary struct {
+	Session
+	Events      int
+	PeakViewers int64
+	Diamonds    int64
+	Chats       int64
+	Gifts       int64
+	Likes       int64
+	Follows     int64
+	Shares      int64
+	// ViewerSum and ViewerSamples add up the viewer count updates, for
+	// averages over several sessions
+	ViewerSum     int64
+	ViewerSamples int64
+	// LastEventAt is zero for sessions without events
+	LastEventAt time.Time
+}
+
+// Length returns the session duration, using the last event for sessions
+// that never recorded an end, e.g. because the logger was killed
+func (s SessionSummary) Length() time.Duration {
+	if s.EndedAt.IsZero() && !s.LastEventAt.IsZero() {
+		return s.LastEventAt.Sub(s.StartedAt)
+	}
+	return s.Duration()
+}
+
+// parseTime parses timestamps returned by aggregate queries, which SQLite
+// hands back as text. PostgreSQL timestamps arrive in RFC 3339.
+func parseTime(value sql.NullString) time.Time {
+	if !value.Valid {
+		return time.Time{}
+	}
+	if t, err := time.Parse(time.RFC3339Nano, value.String); err == nil {
+		return t.Local()
+	}
+	for _, format := range sqlite3.SQLiteTimestampFormats {
+		if t, err := time.ParseInLocation(format, value.String, time.UTC); err == nil {
+			return t.Local()
+		}
+	}
+	return time.Time{}
+}
+
+// GetStreamerSummaries returns one summary per streamer, most recently seen first
+func (d *DB) GetStreamerSummaries() ([]StreamerSummary, error) {
+	query := `
+	SELECT s.username, COUNT(DISTINCT s.id), COALESCE(SUM(r.events), 0), COALESCE(SUM(r.diamonds), 0),
+		MAX(s.started_at)
+	FROM sessions s
+	LEFT JOIN rollups r ON r.session_id = s.id AND r.resolution = 3600
+	GROUP BY s.username
+	ORDER BY MAX(s.started_at) DESC
+	`
+	rows, err := d.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []StreamerSummary
+	for rows.Next() {
+		var summary StreamerSummary
+		var lastSeen sql.NullString
+		if err := rows.Scan(&summary.Username, &summary.Sessions, &summary.Events, &summary.Diamonds, &lastSeen); err != nil {
+			return nil, err
+		}
+		summary.LastSeen = parseTime(lastSeen)
+		summaries = append(summaries, summary)
+	}
+	return summaries, rows.Err()
+}
+
+// GetSessionSummaries returns the sessions of a streamer with their totals,
+// newest first. Totals come from the hourly rollups, so they include events
+// removed by compaction.
+func (d *DB) GetSessionSummaries(username string) ([]SessionSummary, error) {
+	query := `
+	SELECT s.id, s.username, s.started_at, s.ended_at, s.archive, COALESCE(SUM(r.events), 0),
+		COALESCE(MAX(r.viewer_max), 0), COALESCE(SUM(r.diamonds), 0),
+		COALESCE(SUM(r.chats), 0), COALESCE(SUM(r.gifts), 0), COALESCE(SUM(r.likes), 0),
+		COALESCE(SUM(r.follows), 0), COALESCE(SUM(r.shares), 0),
+		COALESCE(SUM(r.viewer_sum), 0), COALESCE(SUM(r.viewer_samples), 0),
+		MAX(r.last_event)
+	FROM sessions s
+	LEFT JOIN rollups r ON r.session_id = s.id AND r.resolution = 3600
+	WHERE s.username = ?
+	GROUP BY s.id
+	ORDER BY s.started_at DESC
+	`
+	rows, err := d.db.Query(query, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []SessionSummary
+	for rows.Next() {
+		var summary SessionSummary
+		var endedAt sql.NullTime
+		var archive sql.NullString
+		var lastEventAt sql.NullInt64
+		err := rows.Scan(&summary.ID, &summary.Username, &summary.StartedAt, &endedAt, &archive,
+			&summary.Events, &summary.PeakViewers, &summary.Diamonds, &summary.Chats, &summary.Gifts,
+			&summary.Likes, &summary.Follows, &summary.Shares, &summary.ViewerSum, &summary.ViewerSamples,
+			&lastEventAt)
+		if err != nil {
+			return nil, err
+		}
+		summary.EndedAt, summary.Archive = endedAt.Time, archive.String
+		if lastEventAt.Valid {
+			summary.LastEventAt = time.Unix(lastEventAt.Int64, 0)
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, rows.Err()
+}
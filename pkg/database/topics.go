@@ -0,0 +1,80 @@
+package database
+
+import (
+	"time"
+)
+
+// TopicCache holds text analytics computed from a session's chat. The data
+// is stored as is; Messages and LastEventID identify the chat rows it was
+// computed from, so a cache for a session that kept logging can be detected.
+type TopicCache struct {
+	SessionID   int64
+	Version     int
+	Messages    int64
+	LastEventID int64
+	Data        []byte
+	CreatedAt   time.Time
+}
+
+// GetChatEvents returns the chat events of a session in chronological order
+func (d *DB) GetChatEvents(sessionID int64) ([]Event, error) {
+	query := `
+	SELECT ` + eventColumns + `
+	FROM events
+	WHERE session_id = ? AND type = 'chat'
+	ORDER BY timestamp ASC, id ASC
+	`
+	rows, err := d.db.Query(query, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanEvents(rows)
+}
+
+// GetChatStamp returns the number of chat events of a session and the
+// highest event ID among them
+func (d *DB) GetChatStamp(sessionID int64) (messages, lastEventID int64, err error) {
+	query := `
+	SELECT COUNT(*), COALESCE(MAX(id), 0)
+	FROM events
+	WHERE session_id = ? AND type = 'chat'
+	`
+	err = d.db.QueryRow(query, sessionID).Scan(&messages, &lastEventID)
+	return messages, lastEventID, err
+}
+
+// GetTopicCache returns the cached analytics of a session, or sql.ErrNoRows
+// if there are none
+func (d *DB) GetTopicCache(sessionID int64) (*TopicCache, error) {
+	query := `
+	SELECT session_id, version, messages, last_event_id, data, created_at
+	FROM topic_cache
+	WHERE session_id = ?
+	`
+	var cache TopicCache
+	err := d.db.QueryRow(query, sessionID).Scan(&cache.SessionID, &cache.Version,
+		&cache.Messages, &cache.LastEventID, &cache.Data, &cache.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &cache, nil
+}
+
+// SaveTopicCache stores the analytics of a session, replacing older ones
+func (d *DB) SaveTopicCache(cache TopicCache) error {
+	query := `
+	INSERT INTO topic_cache (session_id, version, messages, last_event_id, data, created_at)
+	VALUES (?, ?, ?, ?, ?, ?)
+	ON CONFLICT (session_id) DO UPDATE SET
+		version = excluded.version,
+		messages = excluded.messages,
+		last_event_id = excluded.last_event_id,
+		data = excluded.data,
+		created_at = excluded.created_at
+	`
+	_, err := d.db.Exec(query, cache.SessionID, cache.Version, cache.Messages,
+		cache.LastEventID, cache.Data, cache.CreatedAt)
+	return err
+}
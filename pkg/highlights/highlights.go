@@ -0,0 +1,309 @@
+package highlights
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"tiktok-live-logger/pkg/database"
+)
+
+// Metrics tracked per time bucket
+const (
+	MetricChat    = "chat"
+	MetricGifts   = "gifts"
+	MetricLikes   = "likes"
+	MetricViewers = "viewers"
+)
+
+var metrics = []string{MetricChat, MetricGifts, MetricLikes, MetricViewers}
+
+// Options tune the detector. Zero values use the defaults.
+type Options struct {
+	// Bucket is the resolution of the time series
+	Bucket time.Duration
+	// Baseline is how far back the rolling baseline looks
+	Baseline time.Duration
+	// Threshold is the spike score a bucket needs to count, in robust
+	// standard deviations above the baseline
+	Threshold float64
+	// Limit is the number of highlights returned
+	Limit int
+}
+
+// DefaultOptions returns 30 second buckets against a 5 minute baseline
+func DefaultOptions() Options {
+	return Options{
+		Bucket:    30 * time.Second,
+		Baseline:  5 * time.Minute,
+		Threshold: 3,
+		Limit:     10,
+	}
+}
+
+func (o Options) withDefaults() Options {
+	d := DefaultOptions()
+	if o.Bucket <= 0 {
+		o.Bucket = d.Bucket
+	}
+	if o.Baseline <= 0 {
+		o.Baseline = d.Baseline
+	}
+	if o.Threshold <= 0 {
+		o.Threshold = d.Threshold
+	}
+	if o.Limit <= 0 {
+		o.Limit = d.Limit
+	}
+	return o
+}
+
+// Spike is a metric that rose above its baseline during a highlight
+type Spike struct {
+	Metric string `json:"metric"`
+	// Peak is the highest bucket value, Baseline the typical value before it
+	Peak     float64 `json:"peak"`
+	Baseline float64 `json:"baseline"`
+	Score    float64 `json:"score"`
+}
+
+// Highlight is a window of the stream where activity spiked
+type Highlight struct {
+	Rank  int       `json:"rank"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Offset is Start relative to the session start
+	Offset   time.Duration `json:"offset"`
+	Duration time.Duration `json:"duration"`
+	Score    float64       `json:"score"`
+	Spikes   []Spike       `json:"spikes"`
+	Chats    int64         `json:"chats"`
+	Diamonds int64         `json:"diamonds"`
+	// Messages are a few chat messages from the window, for context
+	Messages []string `json:"messages"`
+}
+
+// MarshalJSON writes the offset and duration in seconds
+func (h Highlight) MarshalJSON() ([]byte, error) {
+	type plain Highlight
+	return json.Marshal(struct {
+		plain
+		Offset   float64 `json:"offset"`
+		Duration float64 `json:"duration"`
+	}{plain(h), h.Offset.Seconds(), h.Duration.Seconds()})
+}
+
+// maxMessages bounds the sample chat messages per highlight
+const maxMessages = 3
+
+type bucket struct {
+	values   map[string]float64
+	chats    int64
+	diamonds int64
+	messages []string
+}
+
+// maxBuckets bounds the time series, which a tiny bucket over a long
+// session would make too large to score
+const maxBuckets = 100_000
+
+// Detect finds the windows of a session where chat rate, gift value, likes
+// or viewer count spiked relative to the preceding minutes. Events must be
+// in chronological order; events with timestamps outside the session, as
+// left by a wrong clock, are ignored. Highlights are ranked by score, best
+// first.
+func Detect(session database.Session, events []database.Event, opts Options) ([]Highlight, error) {
+	opts = opts.withDefaults()
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	start, end := session.StartedAt, session.EndedAt
+	if start.IsZero() {
+		start = events[0].Timestamp
+	}
+	if end.IsZero() {
+		// Still being logged
+		end = events[len(events)-1].Timestamp
+	}
+	if end.Before(start) {
+		return nil, nil
+	}
+	if n := end.Sub(start) / opts.Bucket; n >= maxBuckets {
+		return nil, fmt.Errorf("a %s session in %s buckets makes %d buckets, more than %d: use a longer bucket",
+			end.Sub(start).Round(time.Second), opts.Bucket, n+1, maxBuckets)
+	}
+	buckets := fill(start, end, events, opts.Bucket)
+
+	// Score every bucket against the median of the buckets before it
+	window := max(int(opts.Baseline/opts.Bucket), 2)
+	scores := make([]map[string]float64, len(buckets))
+	baselines := make([]map[string]float64, len(buckets))
+	for i := range buckets {
+		scores[i] = make(map[string]float64)
+		baselines[i] = make(map[string]float64)
+		if i < window/2 {
+			// Not enough history for a baseline yet
+			continue
+		}
+		for _, metric := range metrics {
+			history := make([]float64, 0, window)
+			for j := max(0, i-window); j < i; j++ {
+				history = append(history, buckets[j].values[metric])
+			}
+			median, spread := robustStats(history)
+			baselines[i][metric] = median
+			scores[i][metric] = (buckets[i].values[metric] - median) / spread
+		}
+	}
+
+	// Merge hot buckets at most one quiet bucket apart into highlights
+	var highlights []Highlight
+	lastHot := -1
+	for i, b := range buckets {
+		var spikes []Spike
+		var score float64
+		for _, metric := range metrics {
+			if s := scores[i][metric]; s >= opts.Threshold {
+				spikes = append(spikes, Spike{Metric: metric, Peak: b.values[metric], Baseline: baselines[i][metric], Score: s})
+				score += s
+			}
+		}
+		if len(spikes) == 0 {
+			continue
+		}
+
+		bucketStart := start.Add(time.Duration(i) * opts.Bucket)
+		if lastHot < 0 || i-lastHot > 2 {
+			highlights = append(highlights, Highlight{Start: bucketStart, Offset: bucketStart.Sub(session.StartedAt)})
+		} else if i-lastHot == 2 {
+			highlights[len(highlights)-1].add(buckets[i-1])
+		}
+		h := &highlights[len(highlights)-1]
+		h.add(b)
+		h.End = bucketStart.Add(opts.Bucket)
+		h.Score = math.Max(h.Score, score)
+		h.Spikes = mergeSpikes(h.Spikes, spikes)
+		lastHot = i
+	}
+
+	for i := range highlights {
+		highlights[i].Duration = highlights[i].End.Sub(highlights[i].Start)
+		sort.Slice(highlights[i].Spikes, func(a, b int) bool {
+			return highlights[i].Spikes[a].Score > highlights[i].Spikes[b].Score
+		})
+	}
+	sort.SliceStable(highlights, func(i, j int) bool { return highlights[i].Score > highlights[j].Score })
+	if len(highlights) > opts.Limit {
+		highlights = highlights[:opts.Limit]
+	}
+	for i := range highlights {
+		highlights[i].Rank = i + 1
+	}
+	return highlights, nil
+}
+
+// fill buckets the events between start and end. The viewer count carries
+// over buckets without a stats update.
+func fill(start, end time.Time, events []database.Event, size time.Duration) []bucket {
+	buckets := make([]bucket, int(end.Sub(start)/size)+1)
+	for i := range buckets {
+		buckets[i].values = make(map[string]float64)
+	}
+
+	for _, e := range events {
+		if e.Timestamp.Before(start) || e.Timestamp.After(end) {
+			continue
+		}
+		b := &buckets[int(e.Timestamp.Sub(start)/size)]
+		switch e.Type {
+		case "chat":
+			b.values[MetricChat]++
+			b.chats++
+			if len(b.messages) < maxMessages {
+				b.messages = append(b.messages, e.Content)
+			}
+		case "gift":
+			b.values[MetricGifts] += float64(e.Value)
+			b.diamonds += e.Value
+		case "like":
+			b.values[MetricLikes] += float64(e.Value)
+		case "stats":
+			b.values[MetricViewers] = math.Max(b.values[MetricViewers], float64(e.Value))
+		}
+	}
+
+	// Before the first update, use the first known count rather than zero
+	var viewers float64
+	for _, b := range buckets {
+		if v := b.values[MetricViewers]; v > 0 {
+			viewers = v
+			break
+		}
+	}
+	for i := range buckets {
+		if v, ok := buckets[i].values[MetricViewers]; ok && v > 0 {
+			viewers = v
+		}
+		buckets[i].values[MetricViewers] = viewers
+	}
+	return buckets
+}
+
+// robustStats returns the median and a spread based on the median absolute
+// deviation. The spread never drops below a floor, so a quiet stream where
+// nothing happened doesn't turn a single message into a highlight.
+func robustStats(values []float64) (median, spread float64) {
+	median = medianOf(values)
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - median)
+	}
+	// 1.4826 scales the MAD to a standard deviation for normal data
+	spread = 1.4826 * medianOf(deviations)
+	return median, math.Max(spread, math.Max(1, 0.1*median))
+}
+
+func medianOf(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+func (h *Highlight) add(b bucket) {
+	h.Chats += b.chats
+	h.Diamonds += b.diamonds
+	for _, msg := range b.messages {
+		if len(h.Messages) < maxMessages {
+			h.Messages = append(h.Messages, msg)
+		}
+	}
+}
+
+// mergeSpikes keeps the strongest spike per metric
+func mergeSpikes(existing, spikes []Spike) []Spike {
+	for _, s := range spikes {
+		found := false
+		for i := range existing {
+			if existing[i].Metric == s.Metric {
+				found = true
+				if s.Score > existing[i].Score {
+					existing[i] = s
+				}
+			}
+		}
+		if !found {
+			existing = append(existing, s)
+		}
+	}
+	return existing
+}
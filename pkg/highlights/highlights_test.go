@@ -0,0 +1,126 @@
+package highlights
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"tiktok-live-logger/pkg/database"
+)
+
+var start = time.Date(2026, 1, 31, 20, 0, 0, 0, time.UTC)
+
+// series returns a 20 minute session with two chat messages and a viewer
+// count every 30 second bucket, plus the given extra events
+func series(extra ...database.Event) (database.Session, []database.Event) {
+	session := database.Session{ID: 1, Username: "streamer", StartedAt: start, EndedAt: start.Add(20 * time.Minute)}
+	var events []database.Event
+	for i := range 40 {
+		at := start.Add(time.Duration(i) * 30 * time.Second)
+		events = append(events,
+			database.Event{Type: "stats", Value: 100, Timestamp: at},
+			database.Event{Type: "chat", Content: "alice: hi", Timestamp: at.Add(5 * time.Second)},
+			database.Event{Type: "chat", Content: "bob: hey", Timestamp: at.Add(10 * time.Second)},
+		)
+	}
+	events = append(events, extra...)
+	// Detect wants them in order
+	for i := 1; i < len(events); i++ {
+		for j := i; j > 0 && events[j].Timestamp.Before(events[j-1].Timestamp); j-- {
+			events[j], events[j-1] = events[j-1], events[j]
+		}
+	}
+	return session, events
+}
+
+// burst returns n chat messages within the 30 second bucket starting at offset
+func burst(offset time.Duration, n int) []database.Event {
+	var events []database.Event
+	for i := range n {
+		events = append(events, database.Event{Type: "chat", Content: "carol: wow", Timestamp: start.Add(offset + time.Duration(i)*time.Second)})
+	}
+	return events
+}
+
+func TestDetect(t *testing.T) {
+	// Chat bursts in buckets 20 and 22, one quiet bucket apart, and a large
+	// gift in bucket 30
+	extra := append(burst(10*time.Minute, 18), burst(11*time.Minute, 18)...)
+	extra = append(extra, database.Event{Type: "gift", Value: 500, Timestamp: start.Add(15*time.Minute + 3*time.Second)})
+	session, events := series(extra...)
+
+	found, err := Detect(session, events, Options{})
+	if err != nil {
+		t.Fatalf("Detect: %v", err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("found %d highlights, want 2: %+v", len(found), found)
+	}
+
+	gift, chat := found[0], found[1]
+	if gift.Rank != 1 || gift.Offset != 15*time.Minute || gift.Duration != 30*time.Second || gift.Diamonds != 500 {
+		t.Errorf("gift highlight = %+v", gift)
+	}
+	if len(gift.Spikes) != 1 || gift.Spikes[0].Metric != MetricGifts || gift.Spikes[0].Peak != 500 || gift.Spikes[0].Baseline != 0 {
+		t.Errorf("gift spikes = %+v", gift.Spikes)
+	}
+
+	// The bursts are merged into one highlight, counting the quiet bucket
+	// between them too
+	if chat.Rank != 2 || chat.Offset != 10*time.Minute || chat.Duration != 90*time.Second {
+		t.Errorf("chat highlight at +%s for %s, want +10m0s for 1m30s", chat.Offset, chat.Duration)
+	}
+	if chat.Chats != 20+2+20 {
+		t.Errorf("chat highlight has %d chats, want 42", chat.Chats)
+	}
+	if len(chat.Messages) != maxMessages {
+		t.Errorf("chat highlight has %d messages, want %d", len(chat.Messages), maxMessages)
+	}
+	if s := chat.Spikes; len(s) != 1 || s[0].Metric != MetricChat || s[0].Peak != 20 || s[0].Baseline != 2 || s[0].Score != 18 {
+		t.Errorf("chat spikes = %+v", s)
+	}
+}
+
+func TestDetectQuietSession(t *testing.T) {
+	session, events := series()
+	found, err := Detect(session, events, Options{})
+	if err != nil || len(found) != 0 {
+		t.Errorf("Detect = %+v, %v, want no highlights", found, err)
+	}
+	if found, err := Detect(session, nil, Options{}); err != nil || found != nil {
+		t.Errorf("Detect without events = %+v, %v", found, err)
+	}
+}
+
+func TestDetectBounds(t *testing.T) {
+	// Chat with a clock off by a year neither stretches the series over the
+	// year nor becomes a highlight
+	session, events := series(burst(365*24*time.Hour, 20)...)
+	found, err := Detect(session, events, Options{})
+	if err != nil || len(found) != 0 {
+		t.Errorf("Detect with an outlier = %+v, %v", found, err)
+	}
+
+	if _, err := Detect(session, events, Options{Bucket: time.Millisecond}); err == nil {
+		t.Error("Detect accepted 1.2 million buckets")
+	}
+}
+
+func TestRobustStats(t *testing.T) {
+	tests := []struct {
+		values         []float64
+		median, spread float64
+	}{
+		{[]float64{1, 2, 3, 4, 100}, 3, 1.4826},
+		{[]float64{0, 0, 0, 0}, 0, 1},
+		{[]float64{100, 100, 100}, 100, 10},
+		{[]float64{1, 3}, 2, 1.4826},
+		{nil, 0, 1},
+	}
+	for _, tt := range tests {
+		median, spread := robustStats(tt.values)
+		if median != tt.median || math.Abs(spread-tt.spread) > 1e-9 {
+			t.Errorf("robustStats(%v) = %v, %v, want %v, %v", tt.values, median, spread, tt.median, tt.spread)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package language
+
+import (
+	"strings"
+	"unicode"
+
+	"tiktok-live-logger/pkg/tiktok"
+	"tiktok-live-logger/pkg/topics"
+)
+
+// Undetermined is stored for messages whose language can't be told, such as
+// emoji-only messages or a single word shared by several languages
+const Undetermined = "und"
+
+// names maps the detected ISO 639-1 codes to English names
+var names = map[string]string{
+	"ar": "Arabic", "bn": "Bengali", "de": "German", "el": "Greek", "en": "English", "es": "Spanish",
+	"fa": "Persian", "fr": "French", "he": "Hebrew", "hi": "Hindi", "hy": "Armenian", "id": "Indonesian",
+	"it": "Italian", "ja": "Japanese", "ka": "Georgian", "km": "Khmer", "ko": "Korean", "lo": "Lao",
+	"my": "Burmese", "nl": "Dutch", "pl": "Polish", "pt": "Portuguese", "ru": "Russian", "ta": "Tamil",
+	"th": "Thai", "tl": "Filipino", "tr": "Turkish", "uk": "Ukrainian", "vi": "Vietnamese", "zh": "Chinese",
+	Undetermined: "Undetermined",
+}
+
+// Name returns the English name of a language code, or the code itself
+func Name(code string) string {
+	if name, ok := names[code]; ok {
+		return name
+	}
+	return code
+}
+
+// Processor returns a pipeline stage that detects the language of chat events
+func Processor() tiktok.Processor {
+	return tiktok.ProcessorFunc(func(event *tiktok.Event) {
+		if event.Type == "chat" {
+			event.Language = Detect(event.Message)
+		}
+	})
+}
+
+// scripts identify languages written in their own script. Languages sharing
+// a script are told apart by their letters below.
+var scripts = []struct {
+	table *unicode.RangeTable
+	code  string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Han, "zh"},
+	{unicode.Thai, "th"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Greek, "el"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Bengali, "bn"},
+	{unicode.Tamil, "ta"},
+	{unicode.Georgian, "ka"},
+	{unicode.Armenian, "hy"},
+	{unicode.Khmer, "km"},
+	{unicode.Lao, "lo"},
+	{unicode.Myanmar, "my"},
+	{unicode.Cyrillic, "ru"},
+}
+
+// Detect returns the ISO 639-1 code of the language a chat message is most
+// likely written in, or Undetermined. It works offline from the script of
+// the message and, for Latin script, from common words and letters.
+func Detect(text string) string {
+	counts := make(map[string]int)
+	latin, letters := 0, 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.Is(unicode.Latin, r) {
+			latin++
+			continue
+		}
+		for _, s := range scripts {
+			if unicode.Is(s.table, r) {
+				counts[s.code]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return Undetermined
+	}
+
+	// Another script than Latin makes up most of the message
+	if latin*2 < letters {
+		switch {
+		case counts["ja"] > 0:
+			// Japanese mixes kana with Chinese characters
+			return "ja"
+		case counts["ar"] > 0 && strings.ContainsAny(text, "پچژگک"):
+			return "fa"
+		case counts["ru"] > 0 && strings.ContainsAny(strings.ToLower(text), "іїєґ"):
+			return "uk"
+		}
+		best, bestCount := Undetermined, 0
+		for code, n := range counts {
+			if n > bestCount || (n == bestCount && code < best) {
+				best, bestCount = code, n
+			}
+		}
+		return best
+	}
+
+	return detectLatin(text)
+}
+
+// detectLatin scores the Latin script languages by their common words and
+// distinctive letters and returns the clear winner, if any
+func detectLatin(text string) string {
+	lower := strings.ToLower(text)
+	scores := make(map[string]float64)
+
+	for _, t := range topics.Tokenize(lower) {
+		if t.Kind != topics.Word {
+			continue
+		}
+		for _, code := range words[t.Text] {
+			scores[code]++
+		}
+	}
+	for _, hint := range letterHints {
+		if strings.ContainsAny(lower, hint.letters) {
+			for _, code := range hint.codes {
+				scores[code] += hint.weight
+			}
+		}
+	}
+
+	best, bestScore, runnerUp := Undetermined, 0.0, 0.0
+	for code, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore, runnerUp = code, score, bestScore
+		case score > runnerUp:
+			runnerUp = score
+		}
+	}
+	if bestScore < 1 || bestScore == runnerUp {
+		return Undetermined
+	}
+	return best
+}
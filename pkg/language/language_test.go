@@ -0,0 +1,69 @@
+package language
+
+import (
+	"testing"
+
+	"tiktok-live-logger/pkg/tiktok"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		// Scripts of their own
+		{"사랑해요", "ko"},
+		{"こんにちは", "ja"},
+		{"日本語を話します", "ja"},
+		{"你好吗", "zh"},
+		{"สวัสดีครับ", "th"},
+		{"مرحبا بك", "ar"},
+		{"چطوری رفیق", "fa"},
+		{"שלום לכולם", "he"},
+		{"Καλησπέρα", "el"},
+		{"नमस्ते दोस्तों", "hi"},
+		{"Привет всем", "ru"},
+		{"Привіт усім", "uk"},
+		{"hi Привет всем", "ru"},
+		// Latin script, by common words and letters
+		{"hello how are you doing today", "en"},
+		{"hola como estas amigo", "es"},
+		{"olá tudo bem com você", "pt"},
+		{"bonjour je suis très content", "fr"},
+		{"hallo wie geht es dir", "de"},
+		{"halo apa kabar semua", "id"},
+		{"merhaba nasılsın", "tr"},
+		// Nothing to go on
+		{"🔥🔥🔥", Undetermined},
+		{"12345 !!!", Undetermined},
+		{"", Undetermined},
+		{"xqzt", Undetermined},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := Detect(tt.text); got != tt.want {
+				t.Errorf("Detect(%q) = %s, want %s", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessor(t *testing.T) {
+	p := Processor()
+	chat := tiktok.Event{Type: "chat", Message: "hola como estas amigo"}
+	p.Process(&chat)
+	if chat.Language != "es" {
+		t.Errorf("chat language = %q, want es", chat.Language)
+	}
+	gift := tiktok.Event{Type: "gift", Content: "hola como estas amigo"}
+	p.Process(&gift)
+	if gift.Language != "" {
+		t.Errorf("gift language = %q, want none", gift.Language)
+	}
+}
+
+func TestName(t *testing.T) {
+	if Name("pt") != "Portuguese" || Name(Undetermined) != "Undetermined" || Name("xx") != "xx" {
+		t.Errorf("Name: pt = %s, und = %s, xx = %s", Name("pt"), Name(Undetermined), Name("xx"))
+	}
+}
@@ -0,0 +1,70 @@
+package language
+
+import "strings"
+
+// wordLists holds frequent words and chat slang of the Latin script languages
+// seen most in TikTok live chat. Words shared by several languages count for
+// each of them.
+var wordLists = map[string]string{
+	"en": `the and you are is it this that was what how who why with have has
+	not don't dont can love good nice hello hi hey thanks thank please your my
+	me we they he she so very really lol lmao omg wow yes yeah no where from
+	when will just like beautiful amazing welcome guys everyone stream today`,
+	"es": `el la los las que de y en un una es por para con no se lo como más
+	pero muy mi tu te yo qué hola gracias bien bueno buenas saludos amor eres
+	estás está hermosa bonita jaja jajaja donde dónde soy desde también todos
+	quiero tengo puedes`,
+	"pt": `o a os as que de e em um uma é não do da dos das no na para com por
+	você vc eu meu minha muito mais tá está obrigado obrigada oi olá bom boa
+	tudo linda lindo kkk kkkk rs amo sou onde também estou`,
+	"fr": `le la les de des du et est un une je tu il elle nous vous pas que qui
+	ce c'est dans pour avec sur très bonjour salut merci oui non mdr ptdr bien
+	belle je t'aime aime trop suis où aussi`,
+	"de": `der die das und ist ich du nicht ein eine mit auf für von zu es sie
+	wir ihr hallo danke ja nein gut schön sehr bin bist auch noch wie was wo
+	woher liebe`,
+	"it": `il lo la gli le di che è e un una per non sono sei con ciao grazie
+	bella bello molto anche come dove mi ti ho hai tutti buona buongiorno
+	buonasera`,
+	"nl": `de het een en is ik je jij niet van dat die met op voor zijn hoi hallo
+	dank bedankt mooi heel goed wat waar ook nog`,
+	"id": `yang dan di ke dari ini itu aku kamu saya tidak gak ga nggak ada apa
+	sudah udah mau bisa juga kak kakak halo hai terima kasih makasih cantik
+	bagus semangat wkwk wkwkwk banget dong sih aja`,
+	"tr": `bir ve bu da de ne için ile çok ben sen merhaba selam teşekkürler
+	güzel evet hayır var yok nasıl nerede abla abi canım kanka`,
+	"tl": `ang ng mga sa na ako ikaw ka po opo salamat maganda ganda ko mo siya
+	natin naman lang talaga hello idol sana kumusta kamusta pa ba`,
+	"pl": `nie jest się na to że w i z do jak co ja ty tak dzięki cześć pięknie
+	bardzo dobrze witam`,
+	"vi": `và là của có không em anh chị được cho này với những đẹp quá cảm ơn
+	xin chào`,
+}
+
+// words maps each listed word to the languages using it
+var words = func() map[string][]string {
+	index := make(map[string][]string)
+	for code, list := range wordLists {
+		for _, word := range strings.Fields(list) {
+			index[word] = append(index[word], code)
+		}
+	}
+	return index
+}()
+
+// letterHints are letters that appear in only a few languages
+var letterHints = []struct {
+	letters string
+	codes   []string
+	weight  float64
+}{
+	{"ñ¿¡", []string{"es"}, 2},
+	{"ãõ", []string{"pt"}, 2},
+	{"ç", []string{"pt", "fr", "tr"}, 0.5},
+	{"œêîûùë", []string{"fr"}, 1},
+	{"ß", []string{"de"}, 2},
+	{"äöü", []string{"de", "tr"}, 0.5},
+	{"ğşı", []string{"tr"}, 2},
+	{"łąęśżźćń", []string{"pl"}, 2},
+	{"ươđăạảấầẩẫậắằẳẵặẹẻẽếềểễệỉịọỏốồổỗộớờởỡợụủứừửữựỳỵỷỹ", []string{"vi"}, 3},
+}
@@ -1,48 +1,59 @@
 package logger
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"tiktok-live-logger/pkg/theme"
+
 	"github.com/charmbracelet/lipgloss"
 )
 
+// Console styles, built from the active theme by SetTheme
 var (
-	// Styles for different log levels
-	debugStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#A7A7A7")).
-		Padding(0, 1)
-
-	infoStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#00FF00")).
-		Padding(0, 1)
-
-	warnStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#FFA500")).
-		Padding(0, 1)
-
-	errorStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#FF0000")).
-		Padding(0, 1)
-
-	// File styles
-	fileStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#00FFFF")).
-		Padding(0, 1)
-
-	lineStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#FF00FF")).
-		Padding(0, 1)
-
-	// Time style
-	timeStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#FFFF00")).
-		Padding(0, 1)
+	debugStyle lipgloss.Style
+	infoStyle  lipgloss.Style
+	warnStyle  lipgloss.Style
+	errorStyle lipgloss.Style
+	fileStyle  lipgloss.Style
+	keyStyle   lipgloss.Style
+	timeStyle  lipgloss.Style
 )
 
+func init() {
+	SetTheme(theme.Default())
+}
+
+// SetTheme rebuilds the console log styles from a theme. It must be called
+// before any logger is created.
+func SetTheme(t theme.Theme) {
+	fg := func(color string) lipgloss.Style {
+		return lipgloss.NewStyle().Foreground(theme.Color(color))
+	}
+
+	debugStyle = fg(t.Muted).Padding(0, 1)
+	infoStyle = fg(t.Success).Padding(0, 1)
+	warnStyle = fg(t.Warning).Padding(0, 1)
+	errorStyle = fg(t.Error).Padding(0, 1)
+	fileStyle = fg(t.Info).Padding(0, 1)
+	keyStyle = fg(t.Accent)
+	timeStyle = fg(t.Muted).Padding(0, 1)
+
+	if t.Monochrome {
+		warnStyle = warnStyle.Bold(true)
+		errorStyle = errorStyle.Bold(true).Reverse(true)
+	}
+}
+
 type LogLevel int
 
 const (
@@ -52,113 +63,145 @@ const (
 	ERROR
 )
 
+// Options configures where and how log records are written
+type Options struct {
+	// Dir is the directory holding the active log file and its rotations
+	Dir string
+	// Name is added to the file name, e.g. the streamer tracked, so
+	// processes tracking different streamers write their own files
+	Name string
+	// Format is the file format, either "text" or "json"
+	Format string
+	Level  LogLevel
+	// Console enables styled output on stdout. It can be toggled later with
+	// SetConsole, e.g. while a TUI owns the terminal.
+	Console    bool
+	MaxSizeMB  int
+	MaxAgeDays int
+	MaxBackups int
+}
+
 type Logger struct {
-	level     LogLevel
-	logFile   *os.File
-	debugMode bool
+	handler slog.Handler
+	file    *rotatingFile
+	console *atomic.Bool
 }
 
-func NewLogger(debugMode bool) (*Logger, error) {
-	// Create logs directory if it doesn't exist
-	logDir := filepath.Join(os.Getenv("HOME"), ".tiktok-live-logger", "logs")
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create log directory: %w", err)
+// New creates a logger writing records to a rotating file in opts.Dir and,
+// while console output is enabled, to stdout
+func New(opts Options) (*Logger, error) {
+	file, err := newRotatingFile(
+		opts.Dir,
+		opts.Name,
+		int64(opts.MaxSizeMB)*1024*1024,
+		time.Duration(opts.MaxAgeDays)*24*time.Hour,
+		opts.MaxBackups,
+	)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create log file with timestamp
-	timestamp := time.Now().Format("2006-01-02-15-04-05")
-	logPath := filepath.Join(logDir, fmt.Sprintf("tiktok-live-%s.log", timestamp))
-	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open log file: %w", err)
+	handlerOpts := &slog.HandlerOptions{
+		AddSource:   true,
+		Level:       opts.Level.slogLevel(),
+		ReplaceAttr: shortenSource,
 	}
 
-	level := INFO
-	if debugMode {
-		level = DEBUG
+	var fileHandler slog.Handler
+	switch opts.Format {
+	case "json":
+		fileHandler = slog.NewJSONHandler(file, handlerOpts)
+	case "text", "":
+		fileHandler = slog.NewTextHandler(file, handlerOpts)
+	default:
+		file.Close()
+		return nil, fmt.Errorf("unknown log format: %s", opts.Format)
 	}
 
+	console := &atomic.Bool{}
+	console.Store(opts.Console)
+
 	return &Logger{
-		level:     level,
-		logFile:   logFile,
-		debugMode: debugMode,
+		handler: multiHandler{
+			fileHandler,
+			&consoleHandler{
+				out:     os.Stdout,
+				level:   opts.Level.slogLevel(),
+				enabled: console,
+				mu:      &sync.Mutex{},
+			},
+		},
+		file:    file,
+		console: console,
 	}, nil
 }
 
 func (l *Logger) Close() error {
-	if l.logFile != nil {
-		return l.logFile.Close()
+	if l.file != nil {
+		return l.file.Close()
 	}
 	return nil
 }
 
-func (l *Logger) log(level LogLevel, msg string, args ...interface{}) {
-	if level < l.level {
-		return
-	}
-
-	// Get caller information
-	_, file, line, _ := runtime.Caller(2)
-	file = filepath.Base(file)
-
-	// Format message
-	formattedMsg := fmt.Sprintf(msg, args...)
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
+// SetConsole enables or disables console output. Disable it while a TUI is
+// running so log lines don't draw over the screen.
+func (l *Logger) SetConsole(enabled bool) {
+	l.console.Store(enabled)
+}
 
-	// Create log entry
-	logEntry := fmt.Sprintf("[%s] [%s:%d] %s\n",
-		timestamp,
-		file,
-		line,
-		formattedMsg,
-	)
+// With returns a logger that adds the given key/value pairs to every record
+func (l *Logger) With(args ...interface{}) *Logger {
+	var r slog.Record
+	r.Add(args...)
+	var attrs []slog.Attr
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
 
-	// Write to file
-	if l.logFile != nil {
-		l.logFile.WriteString(logEntry)
+	return &Logger{
+		handler: l.handler.WithAttrs(attrs),
+		file:    l.file,
+		console: l.console,
 	}
+}
 
-	// Format for console output
-	var style lipgloss.Style
-	switch level {
-	case DEBUG:
-		style = debugStyle
-	case INFO:
-		style = infoStyle
-	case WARN:
-		style = warnStyle
-	case ERROR:
-		style = errorStyle
+func (l *Logger) log(level LogLevel, msg string, args ...interface{}) {
+	ctx := context.Background()
+	if !l.handler.Enabled(ctx, level.slogLevel()) {
+		return
 	}
 
-	// Create console output
-	consoleOutput := fmt.Sprintf("%s %s %s %s",
-		timeStyle.Render(timestamp),
-		fileStyle.Render(fmt.Sprintf("%s:%d", file, line)),
-		style.Render(fmt.Sprintf("[%s]", level.String())),
-		formattedMsg,
-	)
+	// Skip runtime.Callers, log and the exported wrapper
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:])
 
-	// Print to console
-	fmt.Println(consoleOutput)
+	r := slog.NewRecord(time.Now(), level.slogLevel(), msg, pcs[0])
+	r.Add(args...)
+	l.handler.Handle(ctx, r)
 }
 
+// Debug logs msg with optional key/value pairs at debug level
 func (l *Logger) Debug(msg string, args ...interface{}) {
 	l.log(DEBUG, msg, args...)
 }
 
+// Info logs msg with optional key/value pairs at info level
 func (l *Logger) Info(msg string, args ...interface{}) {
 	l.log(INFO, msg, args...)
 }
 
+// Warn logs msg with optional key/value pairs at warn level
 func (l *Logger) Warn(msg string, args ...interface{}) {
 	l.log(WARN, msg, args...)
 }
 
+// Error logs msg with optional key/value pairs at error level
 func (l *Logger) Error(msg string, args ...interface{}) {
 	l.log(ERROR, msg, args...)
 }
 
+// ErrorWithStack logs err together with the current goroutine's stack trace
 func (l *Logger) ErrorWithStack(err error, msg string, args ...interface{}) {
 	if err == nil {
 		return
@@ -168,8 +211,7 @@ func (l *Logger) ErrorWithStack(err error, msg string, args ...interface{}) {
 	stack := make([]byte, 4096)
 	stack = stack[:runtime.Stack(stack, false)]
 
-	// Log error with stack trace
-	l.Error("%s: %v\nStack trace:\n%s", fmt.Sprintf(msg, args...), err, stack)
+	l.log(ERROR, msg, append(args, "error", err, "stack", string(stack))...)
 }
 
 func (level LogLevel) String() string {
@@ -185,4 +227,163 @@ func (level LogLevel) String() string {
 	default:
 		return "UNKNOWN"
 	}
-} 
\ No newline at end of file
+}
+
+// ParseLevel converts a level name such as "debug" or "warn" to a LogLevel
+func ParseLevel(s string) (LogLevel, error) {
+	switch strings.ToUpper(s) {
+	case "DEBUG":
+		return DEBUG, nil
+	case "INFO":
+		return INFO, nil
+	case "WARN", "WARNING":
+		return WARN, nil
+	case "ERROR":
+		return ERROR, nil
+	default:
+		return INFO, fmt.Errorf("unknown log level: %s", s)
+	}
+}
+
+func (level LogLevel) slogLevel() slog.Level {
+	switch level {
+	case DEBUG:
+		return slog.LevelDebug
+	case WARN:
+		return slog.LevelWarn
+	case ERROR:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// shortenSource reduces the source attribute to file:line
+func shortenSource(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.SourceKey && len(groups) == 0 {
+		if src, ok := a.Value.Any().(*slog.Source); ok {
+			return slog.String(slog.SourceKey, fmt.Sprintf("%s:%d", filepath.Base(src.File), src.Line))
+		}
+	}
+	return a
+}
+
+// multiHandler fans records out to several handlers
+type multiHandler []slog.Handler
+
+func (h multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var firstErr error
+	for _, handler := range h {
+		if !handler.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := handler.Handle(ctx, r.Clone()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (h multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(multiHandler, len(h))
+	for i, handler := range h {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (h multiHandler) WithGroup(name string) slog.Handler {
+	handlers := make(multiHandler, len(h))
+	for i, handler := range h {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return handlers
+}
+
+// consoleHandler renders records as styled single lines for interactive use
+type consoleHandler struct {
+	out     io.Writer
+	level   slog.Level
+	enabled *atomic.Bool
+	attrs   []slog.Attr
+	group   string
+	mu      *sync.Mutex
+}
+
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.enabled.Load() && level >= h.level
+}
+
+func (h *consoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var style lipgloss.Style
+	var level LogLevel
+	switch {
+	case r.Level >= slog.LevelError:
+		style, level = errorStyle, ERROR
+	case r.Level >= slog.LevelWarn:
+		style, level = warnStyle, WARN
+	case r.Level >= slog.LevelInfo:
+		style, level = infoStyle, INFO
+	default:
+		style, level = debugStyle, DEBUG
+	}
+
+	source := ""
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		source = fmt.Sprintf("%s:%d", filepath.Base(frame.File), frame.Line)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s %s",
+		timeStyle.Render(r.Time.Format("2006-01-02 15:04:05")),
+		fileStyle.Render(source),
+		style.Render(fmt.Sprintf("[%s]", level.String())),
+		r.Message,
+	)
+
+	writeAttr := func(a slog.Attr) {
+		key := a.Key
+		if h.group != "" {
+			key = h.group + "." + key
+		}
+		fmt.Fprintf(&b, " %s=%v", keyStyle.Render(key), a.Value)
+	}
+	for _, a := range h.attrs {
+		writeAttr(a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		writeAttr(a)
+		return true
+	})
+	b.WriteString("\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.out, b.String())
+	return err
+}
+
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return &clone
+}
+
+func (h *consoleHandler) WithGroup(name string) slog.Handler {
+	clone := *h
+	if clone.group != "" {
+		name = clone.group + "." + name
+	}
+	clone.group = name
+	return &clone
+}
@@ -0,0 +1,180 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	logFileName = "tiktok-live-logger"
+	// timestampFormat names rotated files
+	timestampFormat = "2006-01-02-15-04-05.000"
+)
+
+var (
+	// backupSuffix matches the timestamp of rotated files
+	backupSuffix = regexp.MustCompile(`^-\d{4}-\d{2}-\d{2}-\d{2}-\d{2}-\d{2}\.\d{3}\.log$`)
+	// perRunFile matches the files of versions writing one file per run
+	perRunFile = regexp.MustCompile(`^tiktok-live-\d{4}-\d{2}-\d{2}-\d{2}-\d{2}-\d{2}\.log$`)
+	// unsafeName matches what may not go into a file name
+	unsafeName = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+// rotatingFile is an io.Writer that rotates the active log file once it exceeds
+// maxSize and prunes rotated files by age and count. Processes sharing a file
+// notice when another one rotated it and follow along.
+type rotatingFile struct {
+	mu         sync.Mutex
+	dir        string
+	base       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// newRotatingFile writes to tiktok-live-logger.log in dir, or to
+// tiktok-live-logger-<name>.log
+func newRotatingFile(dir, name string, maxSize int64, maxAge time.Duration, maxBackups int) (*rotatingFile, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	base := logFileName
+	if name = strings.Trim(unsafeName.ReplaceAllString(name, "_"), "."); name != "" {
+		base += "-" + name
+	}
+	r := &rotatingFile{
+		dir:        dir,
+		base:       base,
+		maxSize:    maxSize,
+		maxAge:     maxAge,
+		maxBackups: maxBackups,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	r.prune()
+	return r, nil
+}
+
+func (r *rotatingFile) path() string {
+	return filepath.Join(r.dir, r.base+".log")
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil || (r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize) {
+		// On failure the record still goes to the active file, and
+		// rotating is tried again with the next one
+		if err := r.rotate(); err != nil && r.file == nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate moves the active file aside and opens a new one. If that fails, the
+// active file is opened again; r.file is only nil if that fails too.
+func (r *rotatingFile) rotate() error {
+	current, err := r.file.Stat()
+	r.file.Close()
+	r.file = nil
+
+	// Another process writing the same file rotated it already
+	if info, serr := os.Stat(r.path()); err != nil || serr != nil || !os.SameFile(current, info) {
+		return r.open()
+	}
+
+	backup := filepath.Join(r.dir, r.base+"-"+time.Now().Format(timestampFormat)+".log")
+	if err := os.Rename(r.path(), backup); err != nil {
+		if oerr := r.open(); oerr != nil {
+			return oerr
+		}
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+
+	if err := r.open(); err != nil {
+		return err
+	}
+	go r.prune()
+	return nil
+}
+
+// prune removes rotated files of this log older than maxAge and all but the
+// newest maxBackups files. The shared log includes per-run files written by
+// older versions.
+func (r *rotatingFile) prune() {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return
+	}
+
+	type backup struct {
+		path    string
+		modTime time.Time
+	}
+	var backups []backup
+	for _, e := range entries {
+		name := e.Name()
+		rest, ok := strings.CutPrefix(name, r.base)
+		if !(ok && backupSuffix.MatchString(rest)) && !(r.base == logFileName && perRunFile.MatchString(name)) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: filepath.Join(r.dir, name), modTime: info.ModTime()})
+	}
+
+	// Newest first
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].modTime.After(backups[j].modTime)
+	})
+
+	cutoff := time.Now().Add(-r.maxAge)
+	for i, b := range backups {
+		expired := r.maxAge > 0 && b.modTime.Before(cutoff)
+		excess := r.maxBackups > 0 && i >= r.maxBackups
+		if expired || excess {
+			os.Remove(b.path)
+		}
+	}
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	return r.file.Close()
+}
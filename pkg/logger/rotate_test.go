@@ -0,0 +1,134 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestRotateAtMaxSize(t *testing.T) {
+	dir := t.TempDir()
+	r, err := newRotatingFile(dir, "alice", 100, 0, 0)
+	if err != nil {
+		t.Fatalf("newRotatingFile: %v", err)
+	}
+	defer r.Close()
+
+	records := []string{strings.Repeat("a", 59) + "\n", strings.Repeat("b", 39) + "\n", strings.Repeat("c", 59) + "\n"}
+	for _, record := range records {
+		if _, err := r.Write([]byte(record)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		// Rotated files are named by the millisecond
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	// The first two records fit in 100 bytes, the third starts a new file
+	active, err := os.ReadFile(filepath.Join(dir, "tiktok-live-logger-alice.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(active) != records[2] {
+		t.Errorf("active file = %q, want the last record", active)
+	}
+	var backups []string
+	for _, name := range listDir(t, dir) {
+		if name != "tiktok-live-logger-alice.log" {
+			backups = append(backups, name)
+		}
+	}
+	if len(backups) != 1 {
+		t.Fatalf("files after rotating = %v, want one backup", backups)
+	}
+	if !backupSuffix.MatchString(strings.TrimPrefix(backups[0], "tiktok-live-logger-alice")) {
+		t.Errorf("backup %q isn't named by its rotation time", backups[0])
+	}
+	rotated, _ := os.ReadFile(filepath.Join(dir, backups[0]))
+	if string(rotated) != records[0]+records[1] {
+		t.Errorf("backup = %q, want the first two records", rotated)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	// write creates a file last modified age ago
+	write := func(name string, age time.Duration) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backup := func(base string, age time.Duration) string {
+		name := base + "-" + now.Add(-age).Format(timestampFormat) + ".log"
+		write(name, age)
+		return name
+	}
+
+	var alice []string
+	for _, age := range []time.Duration{time.Hour, 2 * time.Hour, 3 * time.Hour, 4 * time.Hour} {
+		alice = append(alice, backup("tiktok-live-logger-alice", age))
+	}
+	bob := backup("tiktok-live-logger-bob", 48*time.Hour)
+	shared := backup("tiktok-live-logger", 48*time.Hour)
+	perRun := "tiktok-live-" + now.Add(-72*time.Hour).Format("2006-01-02-15-04-05") + ".log"
+	write(perRun, 72*time.Hour)
+	write("notes.txt", 72*time.Hour)
+
+	// alice keeps her two newest backups; nobody else's files are touched
+	r, err := newRotatingFile(dir, "alice", 100, 24*time.Hour, 2)
+	if err != nil {
+		t.Fatalf("newRotatingFile: %v", err)
+	}
+	r.Close()
+	want := []string{alice[0], alice[1], bob, shared, perRun, "notes.txt", "tiktok-live-logger-alice.log"}
+	slices.Sort(want)
+	if got := listDir(t, dir); !slices.Equal(got, want) {
+		t.Errorf("files after pruning alice's log = %v, want %v", got, want)
+	}
+
+	// The shared log prunes its own backups and the per-run files of older
+	// versions by age, but not the backups of named logs
+	r, err = newRotatingFile(dir, "", 100, 24*time.Hour, 5)
+	if err != nil {
+		t.Fatalf("newRotatingFile: %v", err)
+	}
+	r.Close()
+	want = []string{alice[0], alice[1], bob, "notes.txt", "tiktok-live-logger-alice.log", "tiktok-live-logger.log"}
+	slices.Sort(want)
+	if got := listDir(t, dir); !slices.Equal(got, want) {
+		t.Errorf("files after pruning the shared log = %v, want %v", got, want)
+	}
+}
+
+func TestLogFileName(t *testing.T) {
+	dir := t.TempDir()
+	r, err := newRotatingFile(dir, "../we ird/.name.", 0, 0, 0)
+	if err != nil {
+		t.Fatalf("newRotatingFile: %v", err)
+	}
+	r.Close()
+	if got := listDir(t, dir); !slices.Equal(got, []string{"tiktok-live-logger-_we_ird_.name.log"}) {
+		t.Errorf("files = %v", got)
+	}
+}
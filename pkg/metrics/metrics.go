@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "tiktok_live"
+
+// Tracker states reported by the tracker_state gauge
+const (
+	StateConnecting = "connecting"
+	StateLive       = "live"
+	StateEnded      = "ended"
+	StateError      = "error"
+)
+
+var trackerStates = []string{StateConnecting, StateLive, StateEnded, StateError}
+
+var (
+	registry = prometheus.NewRegistry()
+
+	EventsReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_received_total",
+		Help:      "Events received from TikTok, by streamer and event type.",
+	}, []string{"streamer", "type"})
+
+	EventsPersisted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_persisted_total",
+		Help:      "Events written to the database, by streamer and event type.",
+	}, []string{"streamer", "type"})
+
+	EventsRendered = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ui_events_total",
+		Help:      "Events delivered to the TUI, by streamer.",
+	}, []string{"streamer"})
+
+	GiftDiamonds = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "gift_diamonds_total",
+		Help:      "Diamond value of gifts received, by streamer.",
+	}, []string{"streamer"})
+
+	DBWriteDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_write_duration_seconds",
+		Help:      "Latency of database writes, by operation.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"operation"})
+
+	DBWriteErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_write_errors_total",
+		Help:      "Failed database writes, by operation.",
+	}, []string{"operation"})
+
+	Reconnects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconnects_total",
+		Help:      "Websocket reconnect attempts, by streamer.",
+	}, []string{"streamer"})
+
+	Viewers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "viewers",
+		Help:      "Current viewer count, by streamer.",
+	}, []string{"streamer"})
+
+	TrackerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tracker_state",
+		Help:      "Current tracker state, 1 for the active state and 0 otherwise.",
+	}, []string{"streamer", "state"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		EventsReceived,
+		EventsPersisted,
+		EventsRendered,
+		GiftDiamonds,
+		DBWriteDuration,
+		DBWriteErrors,
+		Reconnects,
+		Viewers,
+		TrackerState,
+	)
+}
+
+// SetTrackerState marks state as the active tracker state for streamer
+func SetTrackerState(streamer, state string) {
+	for _, s := range trackerStates {
+		value := 0.0
+		if s == state {
+			value = 1
+		}
+		TrackerState.WithLabelValues(streamer, s).Set(value)
+	}
+}
+
+// ObserveDBWrite records the duration and outcome of a database write started at start
+func ObserveDBWrite(operation string, start time.Time, err error) {
+	DBWriteDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		DBWriteErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+// Handler returns an http.Handler serving all metrics in Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Serve starts an HTTP server exposing /metrics on addr in the background.
+// The listener is opened synchronously so address errors are reported to the caller.
+func Serve(addr string) (*http.Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go srv.Serve(ln)
+
+	return srv, nil
+}
@@ -0,0 +1,245 @@
+package moderation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"tiktok-live-logger/pkg/tiktok"
+)
+
+// Flag reasons stored alongside flagged events
+const (
+	ReasonWord    = "word"
+	ReasonPattern = "pattern"
+	ReasonRepeat  = "repeat"
+	ReasonRate    = "rate"
+	ReasonLink    = "link"
+)
+
+// Config controls which checks run on chat messages. Zero values disable the
+// corresponding check.
+type Config struct {
+	Enabled bool `json:"enabled"`
+	// BannedWords are matched case-insensitively against whole words.
+	// Entries containing spaces are matched as phrases.
+	BannedWords []string `json:"banned_words"`
+	// Patterns are regular expressions matched against the message text
+	Patterns []string `json:"patterns"`
+	// MaxRepeats flags a user sending the same message this many times
+	// within RepeatWindowSeconds
+	MaxRepeats          int `json:"max_repeats"`
+	RepeatWindowSeconds int `json:"repeat_window_seconds"`
+	// MaxMessagesPerMinute flags users chatting faster than this
+	MaxMessagesPerMinute int  `json:"max_messages_per_minute"`
+	FlagLinks            bool `json:"flag_links"`
+}
+
+// DefaultConfig returns a moderate set of spam checks without any word lists
+func DefaultConfig() Config {
+	return Config{
+		Enabled:              true,
+		MaxRepeats:           3,
+		RepeatWindowSeconds:  60,
+		MaxMessagesPerMinute: 15,
+		FlagLinks:            true,
+	}
+}
+
+var linkPattern = regexp.MustCompile(`(?i)(https?://|www\.)\S+|\b[a-z0-9-]+\.(com|net|org|io|ly|gg|me|tv|xyz|link|ru|co|app)\b`)
+
+type message struct {
+	text string
+	at   time.Time
+}
+
+// Filter flags chat events. It keeps a short per-user history for repeat and
+// rate detection and is safe for concurrent use.
+type Filter struct {
+	words        map[string]bool
+	phrases      []string
+	patterns     []*regexp.Regexp
+	maxRepeats   int
+	repeatWindow time.Duration
+	maxRate      int
+	flagLinks    bool
+
+	mu      sync.Mutex
+	history map[string][]message
+	lastGC  time.Time
+}
+
+// New compiles cfg into a Filter
+func New(cfg Config) (*Filter, error) {
+	f := &Filter{
+		words:        make(map[string]bool),
+		maxRepeats:   cfg.MaxRepeats,
+		repeatWindow: time.Duration(cfg.RepeatWindowSeconds) * time.Second,
+		maxRate:      cfg.MaxMessagesPerMinute,
+		flagLinks:    cfg.FlagLinks,
+		history:      make(map[string][]message),
+	}
+
+	for _, w := range cfg.BannedWords {
+		w = strings.ToLower(strings.TrimSpace(w))
+		switch {
+		case w == "":
+		case strings.ContainsFunc(w, unicode.IsSpace):
+			f.phrases = append(f.phrases, w)
+		default:
+			f.words[w] = true
+		}
+	}
+
+	for _, p := range cfg.Patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid moderation pattern %q: %w", p, err)
+		}
+		f.patterns = append(f.patterns, re)
+	}
+
+	return f, nil
+}
+
+// Process implements tiktok.Processor by appending flag reasons to chat events
+func (f *Filter) Process(event *tiktok.Event) {
+	if event.Type != "chat" {
+		return
+	}
+	event.Flags = append(event.Flags, f.Check(userKey(event), event.Message, event.Timestamp)...)
+}
+
+// Check returns the reasons a message from user sent at the given time is flagged
+func (f *Filter) Check(user, text string, at time.Time) []string {
+	var reasons []string
+	lower := strings.ToLower(text)
+
+	if f.hasBannedWord(lower) {
+		reasons = append(reasons, ReasonWord)
+	}
+
+	for _, re := range f.patterns {
+		if re.MatchString(text) {
+			reasons = append(reasons, ReasonPattern)
+			break
+		}
+	}
+
+	if f.flagLinks && linkPattern.MatchString(text) {
+		reasons = append(reasons, ReasonLink)
+	}
+
+	normalized := normalize(lower)
+	if normalized == "" {
+		// Emoji or punctuation only
+		normalized = lower
+	}
+	repeated, tooFast := f.track(user, normalized, at)
+	if repeated {
+		reasons = append(reasons, ReasonRepeat)
+	}
+	if tooFast {
+		reasons = append(reasons, ReasonRate)
+	}
+
+	return reasons
+}
+
+func (f *Filter) hasBannedWord(lower string) bool {
+	if len(f.words) > 0 {
+		for _, token := range strings.FieldsFunc(lower, isSeparator) {
+			if f.words[token] {
+				return true
+			}
+		}
+	}
+	for _, phrase := range f.phrases {
+		if strings.Contains(lower, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// track records the message and reports whether it exceeds the repeat or rate limits
+func (f *Filter) track(user, text string, at time.Time) (repeated, tooFast bool) {
+	if f.maxRepeats <= 0 && f.maxRate <= 0 {
+		return false, false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Keep enough history for both checks
+	keep := time.Minute
+	if f.repeatWindow > keep {
+		keep = f.repeatWindow
+	}
+
+	history := prune(f.history[user], at.Add(-keep))
+	history = append(history, message{text: text, at: at})
+	f.history[user] = history
+
+	if f.maxRepeats > 0 {
+		since := at.Add(-f.repeatWindow)
+		count := 0
+		for _, m := range history {
+			if m.text == text && !m.at.Before(since) {
+				count++
+			}
+		}
+		repeated = count >= f.maxRepeats
+	}
+
+	if f.maxRate > 0 {
+		since := at.Add(-time.Minute)
+		count := 0
+		for _, m := range history {
+			if !m.at.Before(since) {
+				count++
+			}
+		}
+		tooFast = count > f.maxRate
+	}
+
+	// Drop idle users now and then so the map doesn't grow for the whole stream
+	if at.Sub(f.lastGC) > keep {
+		for u, h := range f.history {
+			if len(prune(h, at.Add(-keep))) == 0 {
+				delete(f.history, u)
+			}
+		}
+		f.lastGC = at
+	}
+
+	return repeated, tooFast
+}
+
+func prune(history []message, before time.Time) []message {
+	i := 0
+	for i < len(history) && history[i].at.Before(before) {
+		i++
+	}
+	return history[i:]
+}
+
+// normalize collapses whitespace and punctuation so trivial variations of a
+// spammed message still count as repeats
+func normalize(lower string) string {
+	return strings.Join(strings.FieldsFunc(lower, isSeparator), " ")
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+func userKey(event *tiktok.Event) string {
+	if event.UserID != 0 {
+		return fmt.Sprintf("%d", event.UserID)
+	}
+	return event.Nickname
+}
@@ -0,0 +1,134 @@
+package moderation
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"tiktok-live-logger/pkg/tiktok"
+)
+
+var start = time.Date(2026, 1, 31, 20, 0, 0, 0, time.UTC)
+
+func TestCheckContent(t *testing.T) {
+	f, err := New(Config{
+		Enabled:     true,
+		BannedWords: []string{"Scam", " free coins ", ""},
+		Patterns:    []string{`(?i)\bdm me\b`},
+		FlagLinks:   true,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"clean", "great stream!", nil},
+		{"word", "this is a SCAM!!", []string{ReasonWord}},
+		{"word inside another word", "scammer? scampi", nil},
+		{"word between punctuation", "total...scam...", []string{ReasonWord}},
+		{"phrase", "get Free Coins now", []string{ReasonWord}},
+		{"phrase words apart", "free stuff, coins", nil},
+		{"pattern", "DM me for prizes", []string{ReasonPattern}},
+		{"pattern not matching", "admire me", nil},
+		{"url", "see https://example.com/x", []string{ReasonLink}},
+		{"www", "www.example.org", []string{ReasonLink}},
+		{"bare domain", "visit prizes.xyz", []string{ReasonLink}},
+		{"dotted words", "it is 3.5 or so.", nil},
+		{"all at once", "scam, dm me at scam.com", []string{ReasonWord, ReasonPattern, ReasonLink}},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Every message from another user, so repeats don't interfere
+			got := f.Check(tt.name, tt.text, start.Add(time.Duration(i)*time.Second))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Check(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckRepeats(t *testing.T) {
+	f, err := New(Config{MaxRepeats: 3, RepeatWindowSeconds: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(user, text string, offset time.Duration) bool {
+		return slices.Contains(f.Check(user, text, start.Add(offset)), ReasonRepeat)
+	}
+
+	// Trivial variations count as the same message
+	if check("alice", "follow me", 0) || check("alice", "Follow me!", 10*time.Second) {
+		t.Error("flagged before the third repeat")
+	}
+	if !check("alice", "FOLLOW   me!!", 20*time.Second) {
+		t.Error("third repeat within a minute not flagged")
+	}
+	// Other users and other messages are counted apart
+	if check("bob", "follow me", 25*time.Second) || check("alice", "hello", 30*time.Second) {
+		t.Error("flagged another user or message")
+	}
+	// Emoji-only messages are compared as they are
+	check("carol", "🔥🔥", 0)
+	check("carol", "🔥🔥", time.Second)
+	if !check("carol", "🔥🔥", 2*time.Second) || check("carol", "❤️", 3*time.Second) {
+		t.Error("emoji repeats not told apart")
+	}
+
+	// Once the first two fall out of the window, the count starts over
+	if check("alice", "follow me", 75*time.Second) {
+		t.Error("repeat outside the window flagged")
+	}
+}
+
+func TestCheckRate(t *testing.T) {
+	f, err := New(Config{MaxMessagesPerMinute: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tooFast := func(user string, offset time.Duration) bool {
+		return slices.Contains(f.Check(user, "msg", start.Add(offset)), ReasonRate)
+	}
+
+	for i := range 5 {
+		if tooFast("alice", time.Duration(i)*time.Second) {
+			t.Fatalf("message %d flagged, the limit is 5", i+1)
+		}
+	}
+	if !tooFast("alice", 5*time.Second) {
+		t.Error("sixth message within a minute not flagged")
+	}
+	if tooFast("bob", 6*time.Second) {
+		t.Error("another user flagged")
+	}
+	// A minute after the first messages, they no longer count
+	if tooFast("alice", 62*time.Second) {
+		t.Error("message after the window expired flagged")
+	}
+}
+
+func TestProcess(t *testing.T) {
+	f, err := New(Config{BannedWords: []string{"scam"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chat := tiktok.Event{Type: "chat", Nickname: "alice", Message: "scam", Timestamp: start, Flags: []string{"other"}}
+	f.Process(&chat)
+	if !slices.Equal(chat.Flags, []string{"other", ReasonWord}) {
+		t.Errorf("chat flags = %v", chat.Flags)
+	}
+	gift := tiktok.Event{Type: "gift", Nickname: "alice", Message: "scam", Timestamp: start}
+	f.Process(&gift)
+	if gift.Flags != nil {
+		t.Errorf("gift flags = %v, want none", gift.Flags)
+	}
+}
+
+func TestNewRejectsBadPattern(t *testing.T) {
+	if _, err := New(Config{Patterns: []string{"("}}); err == nil {
+		t.Error("New accepted an invalid pattern")
+	}
+}
@@ -0,0 +1,224 @@
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"tiktok-live-logger/pkg/tiktok"
+)
+
+// Redacted replaces message text matched by a redaction pattern
+const Redacted = "[redacted]"
+
+// Config controls how viewers are stored. Zero values store events as they
+// come in.
+type Config struct {
+	// Enabled stores viewer nicknames and user IDs as HMAC pseudonyms
+	Enabled bool `json:"enabled"`
+	// Salt keys the pseudonyms. It is generated when logging starts if
+	// empty; with another salt the same viewer gets other pseudonyms.
+	Salt string `json:"salt"`
+	// RedactContacts replaces email addresses and phone numbers in chat
+	// messages
+	RedactContacts bool `json:"redact_contacts"`
+	// RedactPatterns are regular expressions whose matches in chat messages
+	// are replaced
+	RedactPatterns []string `json:"redact_patterns"`
+}
+
+// DefaultConfig stores events as they come in
+func DefaultConfig() Config {
+	return Config{}
+}
+
+// Validate checks the redaction patterns
+func (c Config) Validate() error {
+	_, err := compile(c.RedactPatterns)
+	return err
+}
+
+var contactPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}\b`),
+	// Seven or more digits, optionally grouped by spaces, dots, dashes or
+	// parentheses, such as +1 (555) 123-4567
+	regexp.MustCompile(`\+?\(?\d(?:[\s.()-]{0,2}\d){6,}`),
+}
+
+func compile(patterns []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", p, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// NewSalt returns a random salt for Config.Salt
+func NewSalt() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Scrubber removes viewer identities from events before they are stored
+type Scrubber struct {
+	pseudonymize bool
+	salt         []byte
+	redact       []*regexp.Regexp
+
+	mu sync.Mutex
+	// seen holds the nicknames of the viewers seen so far, which are also
+	// pseudonymized where chat messages name them
+	seen map[string]bool
+}
+
+// New compiles cfg into a Scrubber. Pseudonyms need a salt.
+func New(cfg Config) (*Scrubber, error) {
+	if cfg.Enabled && cfg.Salt == "" {
+		return nil, fmt.Errorf("privacy mode needs a salt")
+	}
+	redact, err := compile(cfg.RedactPatterns)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.RedactContacts {
+		redact = slices.Concat(contactPatterns, redact)
+	}
+	return &Scrubber{pseudonymize: cfg.Enabled, salt: []byte(cfg.Salt), redact: redact, seen: make(map[string]bool)}, nil
+}
+
+// mentionPattern matches @mentions of TikTok usernames, along with the
+// character before them so the @ in an email address isn't taken for one
+var mentionPattern = regexp.MustCompile(`(^|[^\p{L}\p{N}_.])@([\p{L}\p{N}_.]*[\p{L}\p{N}_])`)
+
+// minKnownName is the shortest nickname replaced where a message names it
+// without an @, so short names don't replace parts of ordinary words
+const minKnownName = 3
+
+// Scrub returns the event with the nickname and user ID replaced by their
+// pseudonyms, and chat messages with @mentions and the nicknames of viewers
+// seen earlier pseudonymized and the redaction patterns applied. Viewers a
+// message names without an @ before they were seen themselves keep their
+// names.
+func (s *Scrubber) Scrub(event tiktok.Event) tiktok.Event {
+	nickname := event.Nickname
+	if s.pseudonymize && nickname != "" {
+		s.remember(nickname)
+		event.Nickname = s.Nickname(nickname)
+	}
+	if s.pseudonymize && event.UserID != 0 {
+		event.UserID = s.UserID(event.UserID)
+	}
+
+	if event.Type == "chat" && event.Message != "" {
+		message := event.Message
+		// Redact first, so the patterns don't match inside pseudonyms
+		for _, re := range s.redact {
+			message = re.ReplaceAllString(message, Redacted)
+		}
+		if s.pseudonymize {
+			message = s.names(message)
+		}
+		if message != event.Message || event.Nickname != nickname {
+			event.Message = message
+			event.Content = event.Nickname + ": " + message
+		}
+		return event
+	}
+	if s.pseudonymize {
+		// Content names the viewer for every event but stats
+		if nickname != "" {
+			event.Content = replaceName(event.Content, nickname, event.Nickname)
+		}
+		event.Content = s.names(event.Content)
+	}
+	return event
+}
+
+// remember adds a nickname to the ones looked for in messages
+func (s *Scrubber) remember(nickname string) {
+	if utf8.RuneCountInString(nickname) < minKnownName {
+		return
+	}
+	s.mu.Lock()
+	s.seen[nickname] = true
+	s.mu.Unlock()
+}
+
+// names pseudonymizes the @mentions in text and the nicknames seen so far
+func (s *Scrubber) names(text string) string {
+	text = mentionPattern.ReplaceAllStringFunc(text, func(match string) string {
+		m := mentionPattern.FindStringSubmatch(match)
+		return m[1] + "@" + s.Nickname(m[2])
+	})
+	s.mu.Lock()
+	var named []string
+	for nickname := range s.seen {
+		if strings.Contains(text, nickname) {
+			named = append(named, nickname)
+		}
+	}
+	s.mu.Unlock()
+	// Longer names first, so "ann" doesn't cut into "ann marie"
+	slices.SortFunc(named, func(a, b string) int { return len(b) - len(a) })
+	for _, nickname := range named {
+		text = replaceName(text, nickname, s.Nickname(nickname))
+	}
+	return text
+}
+
+// replaceName replaces name in text where it isn't part of a longer word
+func replaceName(text, name, replacement string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(text, name)
+		if i < 0 {
+			b.WriteString(text)
+			return b.String()
+		}
+		before, _ := utf8.DecodeLastRuneInString(text[:i])
+		after, _ := utf8.DecodeRuneInString(text[i+len(name):])
+		b.WriteString(text[:i])
+		if isWordRune(before) || isWordRune(after) {
+			b.WriteString(name)
+		} else {
+			b.WriteString(replacement)
+		}
+		text = text[i+len(name):]
+	}
+}
+
+func isWordRune(r rune) bool {
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsNumber(r) || r == '_')
+}
+
+// Nickname returns the pseudonym stored for a nickname
+func (s *Scrubber) Nickname(nickname string) string {
+	return "viewer-" + hex.EncodeToString(s.sum("nickname", nickname)[:6])
+}
+
+// UserID returns the pseudonym stored for a user ID, a positive number like
+// the IDs themselves
+func (s *Scrubber) UserID(id int64) int64 {
+	sum := s.sum("user_id", fmt.Sprint(id))
+	return int64(binary.BigEndian.Uint64(sum) >> 1)
+}
+
+func (s *Scrubber) sum(kind, value string) []byte {
+	mac := hmac.New(sha256.New, s.salt)
+	mac.Write([]byte(kind + ":" + value))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,105 @@
+package privacy
+
+import (
+	"strings"
+	"testing"
+
+	"tiktok-live-logger/pkg/tiktok"
+)
+
+func chat(nickname, message string) tiktok.Event {
+	return tiktok.Event{Type: "chat", Nickname: nickname, UserID: 42, Message: message, Content: nickname + ": " + message}
+}
+
+func TestScrub(t *testing.T) {
+	s, err := New(Config{Enabled: true, Salt: "salt", RedactContacts: true})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	alice, bob := s.Nickname("alice"), s.Nickname("bob_99")
+	// bob_99 is seen before the messages naming him
+	s.Scrub(chat("bob_99", "hello"))
+
+	tests := []struct {
+		name    string
+		event   tiktok.Event
+		content string
+	}{
+		{"nickname", chat("alice", "hi"), alice + ": hi"},
+		{"mention", chat("alice", "@carol.x thanks!"), alice + ": @" + s.Nickname("carol.x") + " thanks!"},
+		{"known nickname", chat("alice", "welcome bob_99, bob_990 isn't you"), alice + ": welcome " + bob + ", bob_990 isn't you"},
+		{"email", chat("alice", "mail me at a.b@example.com"), alice + ": mail me at " + Redacted},
+		{"phone", chat("alice", "call +1 (555) 123-4567 now"), alice + ": call " + Redacted + " now"},
+		{"gift", tiktok.Event{Type: "gift", Nickname: "alice", UserID: 42, Content: "alice sent Rose (x1)"}, alice + " sent Rose (x1)"},
+		{"content not starting with the nickname", tiktok.Event{Type: "share", Nickname: "alice", Content: "the stream was shared by alice"}, "the stream was shared by " + alice},
+		{"stats", tiktok.Event{Type: "stats", Content: "Viewer count: 100", Value: 100}, "Viewer count: 100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.Scrub(tt.event)
+			if got.Content != tt.content {
+				t.Errorf("content = %q, want %q", got.Content, tt.content)
+			}
+			if tt.event.Nickname != "" && got.Nickname != s.Nickname(tt.event.Nickname) {
+				t.Errorf("nickname = %q", got.Nickname)
+			}
+			if tt.event.UserID != 0 && (got.UserID == tt.event.UserID || got.UserID != s.UserID(tt.event.UserID) || got.UserID <= 0) {
+				t.Errorf("user ID = %d", got.UserID)
+			}
+			if got.Type == "chat" && !strings.HasSuffix(got.Content, ": "+got.Message) {
+				t.Errorf("message %q doesn't match content %q", got.Message, got.Content)
+			}
+		})
+	}
+}
+
+func TestScrubLeavesEventsAlone(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		event  tiktok.Event
+	}{
+		{"privacy off", Config{}, chat("alice", "hi @bob, mail a@b.com")},
+		{"nothing to redact", Config{RedactContacts: true}, chat("alice", "hi @bob")},
+		{"short nickname", Config{Enabled: true, Salt: "salt"}, chat("al", "all is well, almost")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(tt.config)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			s.Scrub(tt.event)
+			got := s.Scrub(tt.event)
+			if tt.config.Enabled {
+				// Only the viewer's own name changes
+				want := s.Nickname("al") + ": " + tt.event.Message
+				if got.Content != want || got.Message != tt.event.Message {
+					t.Errorf("Scrub = %q / %q, want %q", got.Content, got.Message, want)
+				}
+				return
+			}
+			if got.Content != tt.event.Content || got.Message != tt.event.Message ||
+				got.Nickname != tt.event.Nickname || got.UserID != tt.event.UserID {
+				t.Errorf("Scrub changed %+v to %+v", tt.event, got)
+			}
+		})
+	}
+}
+
+func TestPseudonymsDependOnSalt(t *testing.T) {
+	a, _ := New(Config{Enabled: true, Salt: "one"})
+	b, _ := New(Config{Enabled: true, Salt: "two"})
+	if a.Nickname("alice") != a.Nickname("alice") {
+		t.Error("pseudonyms aren't stable")
+	}
+	if a.Nickname("alice") == b.Nickname("alice") || a.UserID(42) == b.UserID(42) {
+		t.Error("different salts give the same pseudonyms")
+	}
+	if _, err := New(Config{Enabled: true}); err == nil {
+		t.Error("New accepted privacy mode without a salt")
+	}
+	if _, err := New(Config{RedactPatterns: []string{"("}}); err == nil {
+		t.Error("New accepted an invalid pattern")
+	}
+}
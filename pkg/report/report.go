@@ -0,0 +1,322 @@
+package report
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	texttemplate "text/template"
+
+	"tiktok-live-logger/pkg/database"
+	"tiktok-live-logger/pkg/language"
+	"tiktok-live-logger/pkg/sentiment"
+	"tiktok-live-logger/pkg/topics"
+)
+
+//go:embed templates/*.tmpl
+var builtinTemplates embed.FS
+
+// Template file names, looked up first in the override directory
+const (
+	HTMLTemplate     = "report.html.tmpl"
+	MarkdownTemplate = "report.md.tmpl"
+)
+
+// Count pairs a label such as a nickname or word with how often it occurred
+type Count struct {
+	Label string
+	Count int64
+}
+
+// Point is a single sample of a time series
+type Point struct {
+	Time  time.Time
+	Value int64
+}
+
+// Totals summarizes a session
+type Totals struct {
+	Events      int
+	Chats       int
+	Gifts       int
+	Diamonds    int64
+	Likes       int64
+	Follows     int
+	Shares      int
+	Flagged     int
+	PeakViewers int64
+	AvgViewers  int64
+}
+
+// Data is everything a report template can render
+type Data struct {
+	Session      database.Session
+	Duration     time.Duration
+	Totals       Totals
+	Viewers      []Point
+	TopGifters   []Count
+	GiftTimeline []database.Event
+	TopChatters  []Count
+	Words        []Count
+	// Languages counts chat messages per detected language
+	Languages []Count
+	// Sentiment covers chat messages that were scored, per minute in
+	// SentimentTimeline
+	Sentiment         sentiment.Summary
+	SentimentTimeline []sentiment.Point
+	Transcript        []database.Event
+	GeneratedAt       time.Time
+}
+
+// Build computes the report data for a session from its events, which must
+// be in chronological order
+func Build(session database.Session, events []database.Event) *Data {
+	data := &Data{
+		Session:     session,
+		Duration:    session.Duration(),
+		Transcript:  events,
+		GeneratedAt: time.Now(),
+	}
+
+	gifters := make(map[string]int64)
+	chatters := make(map[string]int64)
+	words := make(map[string]int64)
+	languages := make(map[string]int64)
+	var viewerSum int64
+
+	for _, event := range events {
+		data.Totals.Events++
+		if len(event.Flags) > 0 {
+			data.Totals.Flagged++
+		}
+
+		switch event.Type {
+		case "chat":
+			data.Totals.Chats++
+			chatters[nickname(event)]++
+			// Counted as by the topics command, so both agree
+			for _, token := range topics.Tokenize(event.Message()) {
+				if token.Kind == topics.Word && !topics.IsStopword(token.Text) {
+					words[token.Text]++
+				}
+			}
+			if event.Language != "" {
+				languages[fmt.Sprintf("%s (%s)", language.Name(event.Language), event.Language)]++
+			}
+		case "gift":
+			data.Totals.Gifts++
+			data.Totals.Diamonds += event.Value
+			gifters[nickname(event)] += event.Value
+			data.GiftTimeline = append(data.GiftTimeline, event)
+		case "like":
+			data.Totals.Likes += event.Value
+		case "follow":
+			data.Totals.Follows++
+		case "share":
+			data.Totals.Shares++
+		case "stats":
+			data.Viewers = append(data.Viewers, Point{Time: event.Timestamp, Value: event.Value})
+			viewerSum += event.Value
+			if event.Value > data.Totals.PeakViewers {
+				data.Totals.PeakViewers = event.Value
+			}
+		}
+	}
+
+	if len(data.Viewers) > 0 {
+		data.Totals.AvgViewers = viewerSum / int64(len(data.Viewers))
+	}
+	data.TopGifters = top(gifters, 10)
+	data.TopChatters = top(chatters, 10)
+	data.Words = top(words, 100)
+	data.Languages = top(languages, len(languages))
+	data.Sentiment = sentiment.Summarize(events)
+	data.SentimentTimeline = sentiment.Timeline(events, session.StartedAt, time.Minute)
+
+	return data
+}
+
+func nickname(event database.Event) string {
+	if event.Nickname != "" {
+		return event.Nickname
+	}
+	// Events logged before nicknames were stored start with the nickname
+	if i := strings.Index(event.Content, ": "); i > 0 && event.Type == "chat" {
+		return event.Content[:i]
+	}
+	if i := strings.Index(event.Content, " sent "); i > 0 {
+		return event.Content[:i]
+	}
+	return "unknown"
+}
+
+func top(counts map[string]int64, n int) []Count {
+	result := make([]Count, 0, len(counts))
+	for label, count := range counts {
+		result = append(result, Count{Label: label, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Label < result[j].Label
+	})
+	if len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
+
+// Render executes the named template against data. A file with the same name
+// in templateDir takes precedence over the built-in template.
+func Render(w io.Writer, name string, templateDir string, data *Data) error {
+	source, err := loadTemplate(name, templateDir)
+	if err != nil {
+		return err
+	}
+
+	if strings.HasSuffix(name, ".html.tmpl") {
+		tmpl, err := template.New(name).Funcs(template.FuncMap(funcs)).Parse(source)
+		if err != nil {
+			return fmt.Errorf("failed to parse template %s: %w", name, err)
+		}
+		return tmpl.Execute(w, data)
+	}
+
+	tmpl, err := texttemplate.New(name).Funcs(funcs).Parse(source)
+	if err != nil {
+		return fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+	return tmpl.Execute(w, data)
+}
+
+func loadTemplate(name, templateDir string) (string, error) {
+	if templateDir != "" {
+		data, err := os.ReadFile(filepath.Join(templateDir, name))
+		if err == nil {
+			return string(data), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to read template %s: %w", name, err)
+		}
+	}
+
+	data, err := builtinTemplates.ReadFile("templates/" + name)
+	if err != nil {
+		return "", fmt.Errorf("unknown template %s", name)
+	}
+	return string(data), nil
+}
+
+// funcs are available to both HTML and Markdown templates
+var funcs = texttemplate.FuncMap{
+	"clock": func(t time.Time) string {
+		return t.Format("15:04:05")
+	},
+	"datetime": func(t time.Time) string {
+		if t.IsZero() {
+			return "in progress"
+		}
+		return t.Format("2006-01-02 15:04:05")
+	},
+	"duration": func(d time.Duration) string {
+		return d.Round(time.Second).String()
+	},
+	"offset": func(start, t time.Time) string {
+		d := t.Sub(start).Round(time.Second)
+		if d < 0 {
+			d = 0
+		}
+		return fmt.Sprintf("%02d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+	},
+	"chartPoints":     chartPoints,
+	"sentimentPoints": sentimentPoints,
+	"fontSize": func(count, max int64) int {
+		if max == 0 {
+			return 12
+		}
+		return 12 + int(24*count/max)
+	},
+	"maxCount": func(counts []Count) int64 {
+		if len(counts) == 0 {
+			return 0
+		}
+		return counts[0].Count
+	},
+	"mdEscape": func(s string) string {
+		return strings.NewReplacer("|", `\|`, "\n", " ", "*", `\*`, "_", `\_`).Replace(s)
+	},
+	"percent": func(count int64, total int) string {
+		if total == 0 {
+			return "0%"
+		}
+		return fmt.Sprintf("%.0f%%", float64(count)/float64(total)*100)
+	},
+	"join": strings.Join,
+	"add":  func(a, b int) int { return a + b },
+}
+
+// chartPoints scales a series into an SVG polyline "points" attribute of the
+// given width and height
+func chartPoints(points []Point, width, height int) string {
+	if len(points) == 0 {
+		return ""
+	}
+
+	start, end := points[0].Time, points[len(points)-1].Time
+	span := end.Sub(start).Seconds()
+	var max int64
+	for _, p := range points {
+		if p.Value > max {
+			max = p.Value
+		}
+	}
+
+	var b strings.Builder
+	for i, p := range points {
+		x := 0.0
+		if span > 0 {
+			x = p.Time.Sub(start).Seconds() / span * float64(width)
+		}
+		y := float64(height)
+		if max > 0 {
+			y -= float64(p.Value) / float64(max) * float64(height)
+		}
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%.1f,%.1f", x, y)
+	}
+	return b.String()
+}
+
+// sentimentPoints scales a sentiment timeline into an SVG polyline "points"
+// attribute, with -1 at the bottom, 0 in the middle and 1 at the top
+func sentimentPoints(points []sentiment.Point, width, height int) string {
+	if len(points) == 0 {
+		return ""
+	}
+
+	start, end := points[0].Time, points[len(points)-1].Time
+	span := end.Sub(start).Seconds()
+
+	var b strings.Builder
+	for i, p := range points {
+		x := 0.0
+		if span > 0 {
+			x = p.Time.Sub(start).Seconds() / span * float64(width)
+		}
+		y := (1 - p.Average) / 2 * float64(height)
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%.1f,%.1f", x, y)
+	}
+	return b.String()
+}
@@ -0,0 +1,346 @@
+package retention
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"tiktok-live-logger/pkg/database"
+)
+
+// Vacuum modes
+const (
+	VacuumIncremental = "incremental"
+	VacuumFull        = "full"
+	VacuumNone        = "none"
+)
+
+// eventTypes are the types rules can name
+var eventTypes = []string{"chat", "gift", "like", "follow", "share", "stats"}
+
+// Age is a duration written like "7d", "36h" or "1m". Zero means forever.
+type Age time.Duration
+
+// ParseAge parses a Go duration, optionally in days ("30d") or weeks ("2w").
+// An empty string or "forever" is zero.
+func ParseAge(s string) (Age, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "forever" {
+		return 0, nil
+	}
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			f, err := strconv.ParseFloat(n, 64)
+			if err != nil || f < 0 {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return Age(f * float64(unit)), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q: use e.g. 30d, 12h or 1m", s)
+	}
+	return Age(d), nil
+}
+
+func (a Age) String() string {
+	d := time.Duration(a)
+	switch {
+	case d == 0:
+		return "forever"
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	// Drop the zero parts time.Duration adds, as in "1h0m0s"
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+func (a Age) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+func (a *Age) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("age must be a string such as \"30d\": %w", err)
+	}
+	age, err := ParseAge(s)
+	if err != nil {
+		return err
+	}
+	*a = age
+	return nil
+}
+
+// Rule says how long the events of a streamer and type are kept. The first
+// rule matching an event applies to it; events no rule matches are kept.
+type Rule struct {
+	// Streamer and Types select the events. Empty values match any.
+	Streamer string   `json:"streamer,omitempty"`
+	Types    []string `json:"types,omitempty"`
+	// MaxAge deletes events older than this. Zero keeps them forever.
+	MaxAge Age `json:"max_age,omitempty"`
+	// Downsample keeps one stats event per Downsample interval, the one
+	// with the highest viewer count, once events are older than
+	// DownsampleAfter
+	Downsample      Age `json:"downsample,omitempty"`
+	DownsampleAfter Age `json:"downsample_after,omitempty"`
+}
+
+func (r Rule) selector() database.EventSelector {
+	return database.EventSelector{Username: strings.TrimPrefix(r.Streamer, "@"), Types: r.Types}
+}
+
+// Target describes the events a rule selects
+func (r Rule) Target() string {
+	var parts []string
+	if r.Streamer != "" {
+		parts = append(parts, "@"+strings.TrimPrefix(r.Streamer, "@"))
+	}
+	if len(r.Types) > 0 {
+		parts = append(parts, strings.Join(r.Types, ", "))
+	} else {
+		parts = append(parts, "all events")
+	}
+	return strings.Join(parts, " ")
+}
+
+// Policy describes what a rule does to the events it selects
+func (r Rule) Policy() string {
+	var parts []string
+	if r.Downsample > 0 {
+		parts = append(parts, fmt.Sprintf("one per %s after %s", r.Downsample, r.DownsampleAfter))
+	}
+	if r.MaxAge > 0 {
+		parts = append(parts, fmt.Sprintf("deleted after %s", r.MaxAge))
+	} else {
+		parts = append(parts, "kept forever")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Config holds the retention rules, in order of precedence
+type Config struct {
+	Rules []Rule `json:"rules"`
+	// Interval applies the rules periodically while logging. Zero only
+	// applies them with the clean command.
+	Interval Age `json:"interval,omitempty"`
+	// CompactAfter replaces like and stats events older than this with
+	// their minute and hour rollups. Zero keeps them.
+	CompactAfter Age `json:"compact_after,omitempty"`
+	// Vacuum is how the database file is shrunk after events were deleted:
+	// incremental, full or none
+	Vacuum string `json:"vacuum"`
+}
+
+// DefaultConfig has no rules, so nothing is deleted until rules are added
+func DefaultConfig() Config {
+	return Config{Vacuum: VacuumIncremental}
+}
+
+// Validate checks the rules for mistakes
+func (c Config) Validate() error {
+	for i, r := range c.Rules {
+		for _, t := range r.Types {
+			if !slices.Contains(eventTypes, t) {
+				return fmt.Errorf("retention rule %d: unknown event type %q: must be one of %s",
+					i+1, t, strings.Join(eventTypes, ", "))
+			}
+		}
+		if r.Downsample > 0 {
+			if len(r.Types) != 1 || r.Types[0] != "stats" {
+				return fmt.Errorf("retention rule %d: downsampling is only supported for stats events", i+1)
+			}
+			if r.MaxAge > 0 && r.DownsampleAfter >= r.MaxAge {
+				return fmt.Errorf("retention rule %d: downsample_after must be shorter than max_age", i+1)
+			}
+		}
+	}
+	switch c.Vacuum {
+	case "", VacuumIncremental, VacuumFull, VacuumNone:
+	default:
+		return fmt.Errorf("unknown vacuum mode %q: must be incremental, full or none", c.Vacuum)
+	}
+	return nil
+}
+
+// Action is what a rule did, or would do, to the events of a streamer and type
+type Action struct {
+	// Rule is the 1-based rule, or zero for compaction
+	Rule     int
+	Kind     string // "delete", "downsample" or "compact"
+	Username string
+	Type     string
+	Events   int64
+}
+
+// Result sums up an Apply
+type Result struct {
+	Actions []Action
+	// Deleted counts events deleted for their age, Downsampled those
+	// removed by downsampling and Compacted those replaced by rollups
+	Deleted     int64
+	Downsampled int64
+	Compacted   int64
+	Sessions    int64
+}
+
+// Apply runs the rules against the database at time now. With dryRun it only
+// counts the events that would be removed.
+func Apply(db *database.DB, cfg Config, now time.Time, dryRun bool) (*Result, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	var earlier []database.EventSelector
+	for i, rule := range cfg.Rules {
+		sel := database.EventSelection{Match: rule.selector(), Except: slices.Clone(earlier)}
+		earlier = append(earlier, rule.selector())
+
+		if rule.MaxAge > 0 {
+			old := sel
+			old.Before = now.Add(-time.Duration(rule.MaxAge))
+			counts, err := db.CountEvents(old)
+			if err != nil {
+				return nil, fmt.Errorf("failed to count events of rule %d: %w", i+1, err)
+			}
+			// Likes and viewer counts of compacted sessions are only left
+			// in their rollups
+			compacted, err := db.CountCompacted(old)
+			if err != nil {
+				return nil, fmt.Errorf("failed to count compacted events of rule %d: %w", i+1, err)
+			}
+			for _, c := range mergeCounts(counts, compacted) {
+				result.Actions = append(result.Actions, Action{Rule: i + 1, Kind: "delete", Username: c.Username, Type: c.Type, Events: c.Events})
+				result.Deleted += c.Events
+			}
+			if !dryRun && len(counts) > 0 {
+				if _, err := db.DeleteEvents(old); err != nil {
+					return nil, fmt.Errorf("failed to delete events of rule %d: %w", i+1, err)
+				}
+			}
+			if !dryRun && len(compacted) > 0 {
+				if _, err := db.DeleteCompacted(old); err != nil {
+					return nil, fmt.Errorf("failed to delete compacted events of rule %d: %w", i+1, err)
+				}
+			}
+		}
+
+		if rule.Downsample > 0 {
+			thin := sel
+			thin.Before = now.Add(-time.Duration(rule.DownsampleAfter))
+			if rule.MaxAge > 0 {
+				// Older events are deleted above
+				thin.After = now.Add(-time.Duration(rule.MaxAge))
+			}
+			events, err := db.GetSelectedEvents(thin)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get events of rule %d: %w", i+1, err)
+			}
+			ids, counts := redundant(events, time.Duration(rule.Downsample))
+			for _, c := range counts {
+				result.Actions = append(result.Actions, Action{Rule: i + 1, Kind: "downsample", Username: c.Username, Type: c.Type, Events: c.Events})
+			}
+			result.Downsampled += int64(len(ids))
+			if !dryRun && len(ids) > 0 {
+				if _, err := db.DeleteEventsByID(ids); err != nil {
+					return nil, fmt.Errorf("failed to downsample events of rule %d: %w", i+1, err)
+				}
+			}
+		}
+	}
+
+	if cfg.CompactAfter > 0 {
+		before := now.Add(-time.Duration(cfg.CompactAfter))
+		counts, err := db.CountCompactable(before)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count events to compact: %w", err)
+		}
+		for _, c := range counts {
+			result.Actions = append(result.Actions, Action{Kind: "compact", Username: c.Username, Type: c.Type, Events: c.Events})
+			result.Compacted += c.Events
+		}
+		if !dryRun && len(counts) > 0 {
+			if _, err := db.Compact(before); err != nil {
+				return nil, fmt.Errorf("failed to compact events: %w", err)
+			}
+		}
+	}
+
+	if !dryRun {
+		n, err := db.DeleteEmptySessions()
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete empty sessions: %w", err)
+		}
+		result.Sessions = n
+	}
+	return result, nil
+}
+
+// mergeCounts adds up two sets of counts per streamer and type, in order
+func mergeCounts(a, b []database.EventCount) []database.EventCount {
+	merged := slices.Clone(a)
+	for _, c := range b {
+		i := slices.IndexFunc(merged, func(m database.EventCount) bool {
+			return m.Username == c.Username && m.Type == c.Type
+		})
+		if i < 0 {
+			merged = append(merged, c)
+		} else {
+			merged[i].Events += c.Events
+		}
+	}
+	slices.SortFunc(merged, func(a, b database.EventCount) int {
+		return strings.Compare(a.Username+"\x00"+a.Type, b.Username+"\x00"+b.Type)
+	})
+	return merged
+}
+
+// redundant returns the IDs of the events to drop so that one event with the
+// highest value is left per session and interval, and how many are dropped
+// per streamer and type. Events must be ordered by session and time.
+func redundant(events []database.Event, interval time.Duration) ([]int64, []database.EventCount) {
+	var ids []int64
+	dropped := make(map[[2]string]int64)
+
+	type key struct {
+		session int64
+		bucket  time.Time
+	}
+	var current key
+	var best *database.Event
+	for i := range events {
+		e := &events[i]
+		k := key{e.SessionID, e.Timestamp.Truncate(interval)}
+		switch {
+		case best == nil || k != current:
+			current, best = k, e
+			continue
+		case e.Value > best.Value:
+			best, e = e, best
+		}
+		ids = append(ids, e.ID)
+		dropped[[2]string{e.Username, e.Type}]++
+	}
+
+	var counts []database.EventCount
+	for k, n := range dropped {
+		counts = append(counts, database.EventCount{Username: k[0], Type: k[1], Events: n})
+	}
+	slices.SortFunc(counts, func(a, b database.EventCount) int {
+		return strings.Compare(a.Username+"\x00"+a.Type, b.Username+"\x00"+b.Type)
+	})
+	return ids, counts
+}
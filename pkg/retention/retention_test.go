@@ -0,0 +1,102 @@
+package retention
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tiktok-live-logger/pkg/database"
+)
+
+func TestMaxAgeAfterCompaction(t *testing.T) {
+	db, err := database.NewDB(filepath.Join(t.TempDir(), "events.db"))
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	defer db.Close()
+
+	// Ten minutes of likes, viewer counts and chat
+	start := time.Date(2026, 1, 1, 20, 0, 0, 0, time.UTC)
+	id, err := db.StartSession("streamer1", start)
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	for i := range 10 {
+		at := start.Add(time.Duration(i) * time.Minute)
+		for _, e := range []database.Event{
+			{Type: "chat", Content: "alice: hi", Nickname: "alice"},
+			{Type: "like", Content: "bob sent 5 likes", Nickname: "bob", Value: 5},
+			{Type: "like", Content: "bob sent 5 likes", Nickname: "bob", Value: 5},
+			{Type: "stats", Content: "Viewer count: 100", Value: 100},
+		} {
+			e.SessionID, e.Username, e.Timestamp = id, "streamer1", at
+			if _, err := db.SaveEvent(e); err != nil {
+				t.Fatalf("SaveEvent: %v", err)
+			}
+		}
+	}
+	if err := db.EndSession(id, start.Add(10*time.Minute)); err != nil {
+		t.Fatalf("EndSession: %v", err)
+	}
+
+	// Compact everything, then delete likes and viewer counts logged in
+	// the first four minutes
+	now := start.Add(30 * 24 * time.Hour)
+	if _, err := Apply(db, Config{CompactAfter: Age(24 * time.Hour)}, now, false); err != nil {
+		t.Fatalf("Apply compaction: %v", err)
+	}
+	cfg := Config{Rules: []Rule{{Types: []string{"like", "stats"}, MaxAge: Age(now.Sub(start.Add(4 * time.Minute)))}}}
+	dry, err := Apply(db, cfg, now, true)
+	if err != nil {
+		t.Fatalf("Apply dry run: %v", err)
+	}
+	if dry.Deleted != 12 {
+		t.Errorf("dry run would delete %d events, want 12", dry.Deleted)
+	}
+	result, err := Apply(db, cfg, now, false)
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if result.Deleted != 12 {
+		t.Errorf("deleted %d events, want 12", result.Deleted)
+	}
+
+	events, err := db.GetEventsBySession(id)
+	if err != nil {
+		t.Fatalf("GetEventsBySession: %v", err)
+	}
+	var chats, likes, stats int
+	for _, e := range events {
+		switch e.Type {
+		case "chat":
+			chats++
+		case "like":
+			likes += int(e.Value)
+			if e.Timestamp.Before(start.Add(4 * time.Minute)) {
+				t.Errorf("like event at %v survived max_age", e.Timestamp)
+			}
+		case "stats":
+			stats++
+		}
+	}
+	if chats != 10 || likes != 60 || stats != 6 {
+		t.Errorf("got %d chats, %d likes and %d viewer counts, want 10, 60 and 6", chats, likes, stats)
+	}
+
+	rollups, err := db.GetRollups(id, database.HourRollup, time.Time{})
+	if err != nil {
+		t.Fatalf("GetRollups: %v", err)
+	}
+	if len(rollups) != 1 || rollups[0].Events != 28 || rollups[0].Likes != 60 || rollups[0].ViewerSamples != 6 {
+		t.Errorf("hour rollups = %+v", rollups)
+	}
+
+	// Nothing is left to delete the second time
+	again, err := Apply(db, cfg, now, false)
+	if err != nil {
+		t.Fatalf("second Apply: %v", err)
+	}
+	if again.Deleted != 0 {
+		t.Errorf("second Apply deleted %d events", again.Deleted)
+	}
+}
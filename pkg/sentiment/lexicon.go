@@ -0,0 +1,194 @@
+package sentiment
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"unicode"
+
+	"tiktok-live-logger/pkg/topics"
+)
+
+// LexiconScorer is the name of the built-in scorer
+const LexiconScorer = "lexicon"
+
+func init() {
+	Register(LexiconScorer, func(cfg Config) (Scorer, error) {
+		l := NewLexicon()
+		if cfg.LexiconFile != "" {
+			if err := l.LoadFile(cfg.LexiconFile); err != nil {
+				return nil, err
+			}
+		}
+		return l, nil
+	})
+}
+
+// Lexicon scores messages by looking their words and emojis up in weighted
+// lists for English, Spanish, Portuguese, French, German, Indonesian and
+// Turkish. Negations flip the valence of the words after them, intensifiers
+// strengthen it and exclamation marks add emphasis. A Lexicon is read-only
+// once loaded and safe for concurrent use.
+type Lexicon struct {
+	// words map to a valence from -4 to 4
+	words map[string]float64
+	// toxic words and phrases map to a severity from 0 to 1
+	toxic   map[string]float64
+	phrases map[string]float64
+}
+
+// NewLexicon returns the built-in lexicon
+func NewLexicon() *Lexicon {
+	l := &Lexicon{
+		words:   make(map[string]float64, len(valences)),
+		toxic:   make(map[string]float64),
+		phrases: make(map[string]float64),
+	}
+	for word, v := range valences {
+		l.words[word] = v
+	}
+	for word, severity := range toxicWords {
+		l.addToxic(word, severity)
+	}
+	return l
+}
+
+// lexiconFile is the format read by LoadFile
+type lexiconFile struct {
+	Words map[string]float64 `json:"words"`
+	Toxic map[string]float64 `json:"toxic"`
+}
+
+// LoadFile adds the words of a JSON file such as
+//
+//	{"words": {"pog": 3, "mid": -1.5}, "toxic": {"ratio": 0.3}}
+//
+// to the lexicon, replacing built-in entries for the same words. Word
+// valences run from -4 to 4, toxic severities from 0 to 1.
+func (l *Lexicon) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read lexicon file: %w", err)
+	}
+	var file lexiconFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse lexicon file %s: %w", path, err)
+	}
+	for word, v := range file.Words {
+		l.words[strings.ToLower(word)] = math.Max(-4, math.Min(4, v))
+	}
+	for word, severity := range file.Toxic {
+		l.addToxic(word, severity)
+	}
+	return nil
+}
+
+func (l *Lexicon) addToxic(word string, severity float64) {
+	word = strings.ToLower(strings.TrimSpace(word))
+	severity = math.Max(0, math.Min(1, severity))
+	if strings.ContainsFunc(word, unicode.IsSpace) {
+		l.phrases[word] = severity
+	} else if word != "" {
+		l.toxic[word] = severity
+	}
+}
+
+// Score implements Scorer
+func (l *Lexicon) Score(text string) Scores {
+	tokens := topics.Tokenize(text)
+	var sum float64
+	// clean is the chance the message is harmless, lowered by every toxic word
+	clean := 1.0
+
+	for i, t := range tokens {
+		if t.Joined || (t.Kind != topics.Word && t.Kind != topics.Emoji) {
+			continue
+		}
+		key := t.Text
+		if t.Kind == topics.Emoji {
+			key = stripSkinTone(key)
+		}
+
+		severity, toxic := l.toxic[key]
+		if toxic {
+			clean *= 1 - severity
+		}
+		v, ok := l.lookup(key)
+		if !ok {
+			if !toxic {
+				continue
+			}
+			// Insults read as negative even when not in the word list
+			v = -2 * severity
+		}
+
+		if i > 0 {
+			if b, ok := boosters[tokens[i-1].Text]; ok {
+				v *= b
+			}
+		}
+		if t.Kind == topics.Word && negated(tokens, i) {
+			v *= -0.74
+		}
+		sum += v
+	}
+
+	lower := strings.ToLower(text)
+	for phrase, severity := range l.phrases {
+		if strings.Contains(lower, phrase) {
+			clean *= 1 - severity
+			sum -= 2 * severity
+		}
+	}
+
+	// Exclamation marks add emphasis to whatever was said
+	if sum != 0 {
+		sum += math.Copysign(0.3*float64(min(strings.Count(text, "!"), 3)), sum)
+	}
+
+	return Scores{
+		// Squash the unbounded sum into -1..1, as VADER does
+		Sentiment: sum / math.Sqrt(sum*sum+15),
+		Toxicity:  1 - clean,
+	}
+}
+
+// lookup finds a word's valence, also trying it with doubled letters
+// collapsed so "loove" matches "love"
+func (l *Lexicon) lookup(word string) (float64, bool) {
+	if v, ok := l.words[word]; ok {
+		return v, true
+	}
+	var b strings.Builder
+	var last rune
+	for _, r := range word {
+		if r != last {
+			b.WriteRune(r)
+		}
+		last = r
+	}
+	v, ok := l.words[b.String()]
+	return v, ok
+}
+
+// negated reports whether the word at i follows a negation within three
+// words, or is followed by the Turkish "değil"
+func negated(tokens []topics.Token, i int) bool {
+	for j := max(0, i-3); j < i; j++ {
+		if negations[tokens[j].Text] {
+			return true
+		}
+	}
+	return i+1 < len(tokens) && tokens[i+1].Text == "değil"
+}
+
+func stripSkinTone(emoji string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 0x1F3FB && r <= 0x1F3FF {
+			return -1
+		}
+		return r
+	}, emoji)
+}
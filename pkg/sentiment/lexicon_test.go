@@ -0,0 +1,93 @@
+package sentiment
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLexiconScore(t *testing.T) {
+	l := NewLexicon()
+	const (
+		negative = -1
+		neutral  = 0
+		positive = 1
+	)
+	tests := []struct {
+		text  string
+		want  int
+		toxic bool
+	}{
+		{"I love this stream", positive, false},
+		{"amazing!!! 🔥🔥", positive, false},
+		{"lovee it", positive, false},
+		{"this is terrible", negative, false},
+		{"I hate it 😡", negative, false},
+		{"not good", negative, false},
+		{"you are stupid", negative, true},
+		{"hello, where are you from?", neutral, false},
+		{"12345", neutral, false},
+		{"", neutral, false},
+		{"qué genial", positive, false},
+		{"que incrível", positive, false},
+		{"muito ruim", negative, false},
+		{"c'est nul", negative, false},
+		{"echt schlecht", negative, false},
+		{"keren banget", positive, false},
+		{"çok güzel", positive, false},
+		{"güzel değil", negative, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			s := l.Score(tt.text)
+			var got int
+			switch {
+			case s.Sentiment > 0:
+				got = positive
+			case s.Sentiment < 0:
+				got = negative
+			}
+			if got != tt.want {
+				t.Errorf("Score(%q).Sentiment = %v, want sign %d", tt.text, s.Sentiment, tt.want)
+			}
+			if s.Sentiment < -1 || s.Sentiment > 1 {
+				t.Errorf("Score(%q).Sentiment = %v, out of range", tt.text, s.Sentiment)
+			}
+			if (s.Toxicity > 0) != tt.toxic {
+				t.Errorf("Score(%q).Toxicity = %v", tt.text, s.Toxicity)
+			}
+		})
+	}
+}
+
+func TestLexiconIntensity(t *testing.T) {
+	l := NewLexicon()
+	good, veryGood, exclaimed := l.Score("good").Sentiment, l.Score("very good").Sentiment, l.Score("good!!!").Sentiment
+	if !(veryGood > good && exclaimed > good) {
+		t.Errorf("good = %v, very good = %v, good!!! = %v; want boosted scores higher", good, veryGood, exclaimed)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lexicon.json")
+	if err := os.WriteFile(path, []byte(`{"words": {"Pog": 9, "good": -1}, "toxic": {"ratio": 0.3, "touch grass": 2}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l := NewLexicon()
+	if err := l.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if l.words["pog"] != 4 || l.words["good"] != -1 {
+		t.Errorf("words: pog = %v, good = %v, want 4 and -1", l.words["pog"], l.words["good"])
+	}
+	if s := l.Score("go touch grass"); s.Toxicity != 1 || s.Sentiment >= 0 {
+		t.Errorf("Score with a toxic phrase = %+v", s)
+	}
+	if s := l.Score("ratio"); math.Abs(s.Toxicity-0.3) > 1e-9 {
+		t.Errorf("Score(ratio).Toxicity = %v, want 0.3", s.Toxicity)
+	}
+	if err := l.LoadFile(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadFile of a missing file succeeded")
+	}
+}
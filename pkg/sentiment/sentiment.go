@@ -0,0 +1,102 @@
+package sentiment
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"tiktok-live-logger/pkg/tiktok"
+)
+
+// Scores rate a single chat message
+type Scores struct {
+	// Sentiment runs from -1 (negative) through 0 (neutral) to 1 (positive)
+	Sentiment float64 `json:"sentiment"`
+	// Toxicity runs from 0 (harmless) to 1 (abusive)
+	Toxicity float64 `json:"toxicity"`
+}
+
+// Scorer rates chat messages. Implementations must be safe for concurrent use
+// and must not need network access, since scoring runs on every message of a
+// live stream.
+type Scorer interface {
+	Score(text string) Scores
+}
+
+// Config selects and configures the scorer
+type Config struct {
+	Enabled bool `json:"enabled"`
+	// Scorer names a registered scorer. Empty selects the lexicon scorer.
+	Scorer string `json:"scorer,omitempty"`
+	// LexiconFile is a JSON file with words added to or overriding the
+	// built-in lexicon, see LoadLexicon
+	LexiconFile string `json:"lexicon_file,omitempty"`
+}
+
+// DefaultConfig returns the built-in lexicon scorer, switched off
+func DefaultConfig() Config {
+	return Config{Scorer: LexiconScorer}
+}
+
+// Thresholds used to classify scored messages
+const (
+	// Positive and negative messages score at least this far from zero
+	Neutral = 0.05
+	// Toxic messages score at least this
+	ToxicThreshold = 0.5
+)
+
+// Factory creates a scorer from the config
+type Factory func(cfg Config) (Scorer, error)
+
+var (
+	mu        sync.Mutex
+	factories = map[string]Factory{}
+)
+
+// Register makes a scorer available under name for the scorer config key
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	factories[name] = factory
+}
+
+// Names returns the registered scorers, sorted
+func Names() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates the scorer selected by cfg
+func New(cfg Config) (Scorer, error) {
+	name := cfg.Scorer
+	if name == "" {
+		name = LexiconScorer
+	}
+	mu.Lock()
+	factory, ok := factories[name]
+	mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown sentiment scorer %q: must be one of %s", name, strings.Join(Names(), ", "))
+	}
+	return factory(cfg)
+}
+
+// Processor returns a pipeline stage that scores chat events as they arrive
+func Processor(scorer Scorer) tiktok.Processor {
+	return tiktok.ProcessorFunc(func(event *tiktok.Event) {
+		if event.Type != "chat" {
+			return
+		}
+		s := scorer.Score(event.Message)
+		event.Scored = true
+		event.Sentiment, event.Toxicity = s.Sentiment, s.Toxicity
+	})
+}
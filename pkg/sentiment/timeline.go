@@ -0,0 +1,68 @@
+package sentiment
+
+import (
+	"time"
+
+	"tiktok-live-logger/pkg/database"
+)
+
+// Summary totals the scored chat messages of a session
+type Summary struct {
+	Messages int     `json:"messages"`
+	Average  float64 `json:"average"`
+	Positive int     `json:"positive"`
+	Negative int     `json:"negative"`
+	Toxic    int     `json:"toxic"`
+}
+
+// Point is the chat sentiment of one time bucket
+type Point struct {
+	Time time.Time `json:"time"`
+	Summary
+}
+
+func (s *Summary) add(event database.Event) {
+	s.Average = (s.Average*float64(s.Messages) + event.Sentiment) / float64(s.Messages+1)
+	s.Messages++
+	switch {
+	case event.Sentiment >= Neutral:
+		s.Positive++
+	case event.Sentiment <= -Neutral:
+		s.Negative++
+	}
+	if event.Toxicity >= ToxicThreshold {
+		s.Toxic++
+	}
+}
+
+// Summarize totals the scored chat events among events
+func Summarize(events []database.Event) Summary {
+	var s Summary
+	for _, e := range events {
+		if e.Type == "chat" && e.Scored {
+			s.add(e)
+		}
+	}
+	return s
+}
+
+// Timeline buckets the scored chat events by time, starting at start.
+// Events must be in chronological order. Buckets without scored messages
+// are left out.
+func Timeline(events []database.Event, start time.Time, bucket time.Duration) []Point {
+	var points []Point
+	for _, e := range events {
+		if e.Type != "chat" || !e.Scored {
+			continue
+		}
+		at := start.Add(e.Timestamp.Sub(start) / bucket * bucket)
+		if e.Timestamp.Before(start) {
+			at = start
+		}
+		if len(points) == 0 || points[len(points)-1].Time.Before(at) {
+			points = append(points, Point{Time: at})
+		}
+		points[len(points)-1].add(e)
+	}
+	return points
+}
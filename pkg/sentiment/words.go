@@ -0,0 +1,119 @@
+package sentiment
+
+// valences rate common chat words and emojis from -4 (very negative) to 4
+// (very positive). Words are lowercase, as produced by topics.Tokenize.
+var valences = map[string]float64{
+	// English
+	"love": 3, "loved": 3, "lovely": 2.8, "amazing": 2.8, "awesome": 3, "great": 3, "good": 1.9,
+	"nice": 1.8, "cool": 1.3, "beautiful": 2.9, "pretty": 2.2, "cute": 2, "best": 3.2, "perfect": 2.7,
+	"wow": 2.3, "happy": 2.7, "fun": 2.3, "funny": 1.9, "lol": 1.8, "lmao": 2, "haha": 1.7, "hahaha": 2,
+	"thanks": 1.9, "thank": 1.5, "ty": 1.5, "welcome": 2, "congrats": 2.4, "congratulations": 2.9,
+	"glad": 2, "enjoy": 2.2, "like": 1.5, "likes": 1.5, "fire": 1.5, "goat": 2.5, "legend": 2.3,
+	"queen": 1.8, "king": 1.5, "slay": 2, "yay": 2.4, "yes": 1.2, "excellent": 3.2, "fantastic": 3.1,
+	"incredible": 2.6, "wonderful": 3.1, "brilliant": 2.8, "sweet": 2, "talented": 2.3, "support": 1.7,
+	"bad": -2.5, "sad": -2.1, "boring": -1.3, "bored": -1.1, "hate": -2.7, "hated": -3.2, "terrible": -2.9,
+	"awful": -2, "worst": -3.1, "ugly": -2.6, "cringe": -1.7, "angry": -2.3, "annoying": -1.7,
+	"disappointed": -1.9, "disappointing": -2.2, "lame": -1.7, "sucks": -1.5, "fake": -2.1, "scam": -2.5,
+	"horrible": -2.5, "gross": -2.1, "sorry": -0.3, "cry": -1.6, "crying": -2.1, "rip": -1.5, "mid": -1,
+	"trash": -2.3, "stupid": -2.4, "dumb": -2.3, "pathetic": -2.6, "wtf": -1.5, "meh": -0.6,
+
+	// Spanish
+	"amor": 3, "amo": 3, "encanta": 3, "hermosa": 2.9, "hermoso": 2.9, "bonita": 2.2, "bonito": 2.2,
+	"linda": 2.2, "lindo": 2.2, "genial": 3, "increíble": 2.6, "bueno": 1.9, "buena": 1.9, "excelente": 3.2,
+	"gracias": 1.9, "feliz": 2.7, "jaja": 1.7, "jajaja": 2, "guapa": 2.3, "guapo": 2.3, "mejor": 2,
+	"gusta": 1.5, "divertido": 2.3, "felicidades": 2.9, "malo": -2.5, "mala": -2.5, "feo": -2.6, "fea": -2.6,
+	"triste": -2.1, "aburrido": -1.3, "odio": -2.7, "peor": -3, "asco": -2.5,
+	"estafa": -2.5, "falso": -2.1, "falsa": -2.1,
+
+	// Portuguese
+	"maravilhoso": 3.1, "ótimo": 3, "otimo": 3, "bom": 1.9, "boa": 1.9,
+	"obrigado": 1.9, "obrigada": 1.9, "legal": 1.8, "incrível": 2.6, "kkk": 1.7, "kkkk": 2,
+	"rs": 1.2, "parabéns": 2.9, "gata": 2.2, "top": 2, "perfeita": 2.7, "perfeito": 2.7,
+	"ruim": -2.5, "chato": -1.7, "chata": -1.7, "feio": -2.6, "péssimo": -3, "odeio": -2.7, "pior": -3,
+
+	// French
+	"magnifique": 3, "génial": 3, "super": 2.2, "bien": 1.5, "belle": 2.5, "beau": 2.5, "merci": 1.9,
+	"adore": 3, "j'adore": 3, "aime": 2.5, "j'aime": 2.5, "mdr": 1.8, "ptdr": 2, "bravo": 2.4,
+	"mignon": 2, "mignonne": 2, "heureux": 2.7, "heureuse": 2.7, "nul": -2.3, "nulle": -2.3, "mauvais": -2.5,
+	"moche": -2.6, "déteste": -2.7, "ennuyeux": -1.3, "pire": -3,
+
+	// German
+	"liebe": 3, "toll": 2.8, "schön": 2.5, "gut": 1.9, "danke": 1.9, "geil": 2.3,
+	"wunderbar": 3.1, "mega": 2, "hübsch": 2.2, "lustig": 1.9, "glücklich": 2.7, "schlecht": -2.5,
+	"langweilig": -1.3, "hässlich": -2.6, "hasse": -2.7, "traurig": -2.1, "schlimm": -2.2, "blöd": -2.2,
+
+	// Indonesian
+	"cantik": 2.5, "bagus": 1.9, "keren": 2.3, "mantap": 2.5, "suka": 1.5, "sayang": 2.5, "makasih": 1.9,
+	"kasih": 1.9, "lucu": 1.9, "wkwk": 1.7, "wkwkwk": 2, "semangat": 2, "ganteng": 2.3,
+	"senang": 2.7, "hebat": 2.8, "jelek": -2.6, "benci": -2.7, "sedih": -2.1, "bosan": -1.3,
+	"buruk": -2.5, "parah": -1.5,
+
+	// Turkish
+	"güzel": 2.5, "harika": 3, "seviyorum": 3, "sevdim": 2.7, "iyi": 1.9, "teşekkürler": 1.9,
+	"süper": 2.2, "mükemmel": 3.1, "tatlı": 2, "hahah": 1.7, "kötü": -2.5, "çirkin": -2.6,
+	"nefret": -2.7, "üzgün": -2.1, "sıkıcı": -1.3, "berbat": -3,
+
+	// Emojis, with variation selectors and skin tones removed
+	"❤": 3, "🧡": 3, "💛": 3, "💚": 3, "💙": 3, "💜": 3, "🖤": 2, "🤍": 3, "💕": 3, "💖": 3, "💗": 3,
+	"💓": 3, "💞": 3, "💘": 3, "😍": 3, "🥰": 3, "😘": 2.7, "😊": 2.2, "☺": 2.2, "😁": 2.2, "😀": 2,
+	"😃": 2, "😄": 2.2, "😆": 2, "😂": 1.8, "🤣": 1.8, "😅": 0.8, "😉": 1.5, "🤩": 3, "🥳": 2.7, "🎉": 2.5,
+	"👍": 1.8, "👏": 2, "🙌": 2, "🙏": 1.2, "💯": 2, "🔥": 2, "✨": 1.5, "🌹": 2, "💐": 2, "🌟": 2,
+	"⭐": 1.5, "👑": 1.8, "💪": 1.8, "🤗": 2.2, "😻": 2.8, "🫶": 3,
+	"😢": -2, "😭": -1, "😞": -2.2, "😔": -2, "😟": -1.8, "😕": -1.2, "🙁": -1.8, "☹": -1.8,
+	"😠": -2.5, "😡": -2.8, "🤬": -3, "👎": -2, "💔": -2.5, "🤮": -2.8, "🤢": -2.2, "💩": -1.8,
+	"🙄": -1.5, "😒": -1.5, "😤": -1.8, "😴": -1, "🥱": -1.2, "🤡": -1.5,
+}
+
+// toxicWords are insults and profanity with a severity from 0 to 1. Entries
+// with spaces are matched as phrases.
+var toxicWords = map[string]float64{
+	// English
+	"fuck": 0.6, "fucking": 0.5, "fck": 0.5, "shit": 0.4, "bitch": 0.8, "bastard": 0.7, "asshole": 0.8,
+	"idiot": 0.7, "stupid": 0.5, "dumb": 0.4, "moron": 0.7, "loser": 0.6, "retard": 0.9, "retarded": 0.9,
+	"whore": 0.9, "slut": 0.9, "cunt": 0.9, "dick": 0.6, "pussy": 0.6, "stfu": 0.6, "kys": 1,
+	"ugly": 0.3, "trash": 0.3, "pathetic": 0.4,
+	"kill yourself": 1, "shut up": 0.5, "go die": 0.9, "nobody likes you": 0.7,
+
+	// Spanish
+	"puta": 0.8, "puto": 0.8, "mierda": 0.5, "pendejo": 0.7, "pendeja": 0.7, "idiota": 0.7,
+	"estúpido": 0.6, "estúpida": 0.6, "cabrón": 0.7, "gilipollas": 0.8, "imbécil": 0.7, "zorra": 0.8,
+	"cállate": 0.5, "muérete": 1,
+
+	// Portuguese
+	"merda": 0.5, "porra": 0.5, "caralho": 0.6, "otário": 0.7, "otária": 0.7, "burro": 0.5,
+	"burra": 0.5, "vagabunda": 0.8, "viado": 0.9, "vadia": 0.8, "cala a boca": 0.5,
+
+	// French
+	"merde": 0.5, "putain": 0.5, "connard": 0.8, "connasse": 0.8, "salope": 0.9, "débile": 0.6,
+	"enculé": 0.9, "pute": 0.8, "ta gueule": 0.6, "ferme ta gueule": 0.7,
+
+	// German
+	"scheiße": 0.5, "scheisse": 0.5, "arschloch": 0.8, "hurensohn": 0.9, "fotze": 0.9, "wichser": 0.8,
+	"schlampe": 0.9, "halt's maul": 0.6, "halt die fresse": 0.7,
+
+	// Indonesian
+	"anjing": 0.7, "bangsat": 0.8, "goblok": 0.7, "tolol": 0.7, "bodoh": 0.5, "kontol": 0.9,
+	"babi": 0.6, "bego": 0.5, "asu": 0.7,
+
+	// Turkish
+	"siktir": 0.8, "orospu": 0.9, "aptal": 0.6, "salak": 0.6, "gerizekalı": 0.7, "piç": 0.8, "amk": 0.7,
+
+	// Emojis
+	"🖕": 0.8,
+}
+
+// boosters strengthen the valence of the word after them
+var boosters = map[string]float64{
+	"very": 1.3, "so": 1.3, "really": 1.3, "too": 1.2, "super": 1.3, "extremely": 1.5, "absolutely": 1.4,
+	"muy": 1.3, "tan": 1.3, "muito": 1.3, "tão": 1.3, "très": 1.3, "trop": 1.3, "sehr": 1.3,
+	"echt": 1.2, "sangat": 1.3, "sekali": 1.3, "çok": 1.3,
+}
+
+// negations flip the valence of the words after them
+var negations = map[string]bool{
+	"not": true, "no": true, "never": true, "don't": true, "doesn't": true, "isn't": true, "wasn't": true,
+	"aren't": true, "didn't": true, "can't": true, "won't": true, "dont": true, "doesnt": true,
+	"isnt": true, "cant": true, "nunca": true, "nada": true, "jamás": true, "não": true, "nao": true,
+	"pas": true, "jamais": true, "nicht": true, "kein": true, "keine": true, "nie": true, "tidak": true,
+	"bukan": true, "gak": true, "nggak": true, "enggak": true, "ga": true,
+}
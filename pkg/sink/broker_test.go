@@ -0,0 +1,155 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// publishAll publishes the events through the outbox and waits for the
+// broker to acknowledge them
+func publishAll(t *testing.T, config PublishConfig, events ...[2]string) {
+	t.Helper()
+	config.Outbox = filepath.Join(t.TempDir(), "outbox.db")
+	p, err := NewPublish(config, func(err error) { t.Logf("publish: %v", err) })
+	if err != nil {
+		t.Fatalf("NewPublish: %v", err)
+	}
+	for _, e := range events {
+		if err := p.Write(testEvent(e[0], e[1])); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func decodeMessage(t *testing.T, data []byte) Message {
+	t.Helper()
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatalf("failed to decode message: %v", err)
+	}
+	return msg
+}
+
+func TestNATSRoundTrip(t *testing.T) {
+	srv, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Start()
+	defer srv.Shutdown()
+	if !srv.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server didn't start")
+	}
+
+	conn, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	js, err := jetstream.New(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stream, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "EVENTS", Subjects: []string{"tiktok.events.>"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publishAll(t, PublishConfig{Broker: NATS, URL: srv.ClientURL()},
+		[2]string{"streamer1", "alice: one"},
+		[2]string{"some.one", "bob: two"},
+		[2]string{"streamer1", "carol: three"},
+	)
+
+	var first *jetstream.RawStreamMsg
+	for seq, want := range []struct{ subject, content string }{
+		{"tiktok.events.streamer1", "alice: one"},
+		{"tiktok.events.some_one", "bob: two"},
+		{"tiktok.events.streamer1", "carol: three"},
+	} {
+		raw, err := stream.GetMsg(ctx, uint64(seq+1))
+		if err != nil {
+			t.Fatalf("GetMsg(%d): %v", seq+1, err)
+		}
+		msg := decodeMessage(t, raw.Data)
+		if raw.Subject != want.subject || msg.Content != want.content {
+			t.Errorf("message %d on %s = %q, want %q on %s", seq+1, raw.Subject, msg.Content, want.content, want.subject)
+		}
+		if id := raw.Header.Get(jetstream.MsgIDHeader); id == "" || id != msg.ID {
+			t.Errorf("message %d has Nats-Msg-Id %q and id %q", seq+1, id, msg.ID)
+		}
+		if first == nil {
+			first = raw
+		}
+	}
+
+	// Publishing a message again, as at-least-once delivery may, is dropped
+	// by JetStream
+	publisher, err := newNATS(srv.ClientURL(), "tiktok.events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer publisher.Close()
+	again := Pending{ID: first.Header.Get(jetstream.MsgIDHeader), Streamer: "streamer1", Data: first.Data}
+	if err := publisher.Publish(ctx, []Pending{again}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	info, err := stream.Info(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.State.Msgs != 3 {
+		t.Errorf("stream holds %d messages after a duplicate, want 3", info.State.Msgs)
+	}
+}
+
+func TestRedisRoundTrip(t *testing.T) {
+	srv := miniredis.RunT(t)
+	publishAll(t, PublishConfig{Broker: Redis, URL: "redis://" + srv.Addr() + "/0"},
+		[2]string{"streamer1", "alice: one"},
+		[2]string{"streamer2", "bob: two"},
+		[2]string{"streamer1", "carol: three"},
+	)
+
+	for stream, want := range map[string][]string{
+		"tiktok:events:streamer1": {"alice: one", "carol: three"},
+		"tiktok:events:streamer2": {"bob: two"},
+	} {
+		entries, err := srv.Stream(stream)
+		if err != nil {
+			t.Fatalf("stream %s: %v", stream, err)
+		}
+		if len(entries) != len(want) {
+			t.Fatalf("stream %s has %d entries, want %d", stream, len(entries), len(want))
+		}
+		for i, e := range entries {
+			if len(e.Values) != 4 || e.Values[0] != "id" || e.Values[2] != "event" {
+				t.Fatalf("stream %s entry %d has fields %v, want id and event", stream, i, e.Values)
+			}
+			msg := decodeMessage(t, []byte(e.Values[3]))
+			if msg.ID != e.Values[1] || msg.Content != want[i] {
+				t.Errorf("stream %s entry %d = %q with id %q, want %q with id %q", stream, i, msg.Content, msg.ID, want[i], e.Values[1])
+			}
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package sink
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"tiktok-live-logger/pkg/database"
+)
+
+// Fsync policies
+const (
+	FsyncAlways   = "always"
+	FsyncInterval = "interval"
+	FsyncNever    = "never"
+)
+
+// FileConfig configures the file sink, which appends events as NDJSON to
+// one file per streamer and day
+type FileConfig struct {
+	Enabled bool   `json:"enabled"`
+	Dir     string `json:"dir"`
+	// MaxSizeMB starts a new part of the day's file once it would grow
+	// beyond this size. Zero never starts a new part.
+	MaxSizeMB int `json:"max_size_mb"`
+	// Fsync is when writes are flushed to disk: after every event
+	// (always), every FsyncSeconds (interval), or when the operating
+	// system decides and on close (never)
+	Fsync        string `json:"fsync"`
+	FsyncSeconds int    `json:"fsync_seconds"`
+}
+
+// Validate checks the fsync policy and sizes
+func (c FileConfig) Validate() error {
+	if c.Dir == "" {
+		return fmt.Errorf("file sink needs a directory")
+	}
+	if c.MaxSizeMB < 0 {
+		return fmt.Errorf("invalid file sink max_size_mb %d", c.MaxSizeMB)
+	}
+	switch c.Fsync {
+	case FsyncAlways, FsyncNever:
+	case FsyncInterval:
+		if c.FsyncSeconds <= 0 {
+			return fmt.Errorf("invalid file sink fsync_seconds %d: must be positive", c.FsyncSeconds)
+		}
+	default:
+		return fmt.Errorf("unknown fsync policy %q: must be always, interval or never", c.Fsync)
+	}
+	return nil
+}
+
+// File is a sink appending events as JSON lines to
+// <dir>/<streamer>/<streamer>-<date>.ndjson, by UTC date of the event. Once
+// a file reaches the size limit, the day continues in numbered parts:
+// <streamer>-<date>.1.ndjson, .2 and so on. Files are never renamed or
+// rewritten, so shippers can tail them.
+type File struct {
+	config  FileConfig
+	mu      sync.Mutex
+	files   map[string]*dayFile
+	dirty   bool
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// dayFile is the open part of a streamer's file for a day
+type dayFile struct {
+	day  string
+	part int
+	file *os.File
+	size int64
+}
+
+// NewFile opens a file sink
+func NewFile(config FileConfig) (*File, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(config.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create file sink directory: %w", err)
+	}
+	f := &File{config: config, files: make(map[string]*dayFile)}
+	if config.Fsync == FsyncInterval {
+		f.stop, f.stopped = make(chan struct{}), make(chan struct{})
+		go f.syncLoop(time.Duration(config.FsyncSeconds) * time.Second)
+	}
+	return f, nil
+}
+
+func (f *File) Write(event *database.Event) error {
+	line, err := json.Marshal(NewRecord(*event))
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	line = append(line, '\n')
+
+	if err := checkStreamer(event.Username); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	df, err := f.open(event.Username, event.Timestamp.UTC().Format("2006-01-02"), int64(len(line)))
+	if err != nil {
+		return err
+	}
+	n, err := df.file.Write(line)
+	df.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write event file: %w", err)
+	}
+	f.dirty = true
+	if f.config.Fsync == FsyncAlways {
+		return f.sync()
+	}
+	return nil
+}
+
+// open returns the file to append n bytes to for a streamer and day
+func (f *File) open(streamer, day string, n int64) (*dayFile, error) {
+	maxSize := int64(f.config.MaxSizeMB) << 20
+	df := f.files[streamer]
+	if df != nil && df.day == day && (maxSize == 0 || df.size == 0 || df.size+n <= maxSize) {
+		return df, nil
+	}
+
+	part := 0
+	if df != nil {
+		// The interval sync only sees open files
+		if f.config.Fsync != FsyncNever {
+			if err := df.file.Sync(); err != nil {
+				return nil, fmt.Errorf("failed to sync event file: %w", err)
+			}
+		}
+		if err := df.file.Close(); err != nil {
+			return nil, fmt.Errorf("failed to close event file: %w", err)
+		}
+		delete(f.files, streamer)
+		if df.day == day {
+			part = df.part + 1
+		}
+	}
+
+	dir := filepath.Join(f.config.Dir, streamer)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create event directory: %w", err)
+	}
+	// Continue after the parts written by earlier runs, in the last one
+	// if it has room
+	for ; ; part++ {
+		path := partPath(dir, streamer, day, part)
+		info, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open event file: %w", err)
+		}
+		if _, err := os.Stat(partPath(dir, streamer, day, part+1)); err == nil {
+			continue
+		}
+		if maxSize == 0 || info.Size()+n <= maxSize {
+			break
+		}
+	}
+
+	file, err := os.OpenFile(partPath(dir, streamer, day, part), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open event file: %w", err)
+	}
+	df = &dayFile{day: day, part: part, file: file, size: info.Size()}
+	f.files[streamer] = df
+	return df, nil
+}
+
+// checkStreamer rejects usernames that can't be used as a file name, such
+// as ones that would lead outside the sink directory
+func checkStreamer(streamer string) error {
+	if streamer == "" || streamer == "." || streamer == ".." || strings.ContainsAny(streamer, "/\\:\x00") {
+		return fmt.Errorf("invalid streamer name %q for event files", streamer)
+	}
+	return nil
+}
+
+func partPath(dir, streamer, day string, part int) string {
+	name := streamer + "-" + day
+	if part > 0 {
+		name += fmt.Sprintf(".%d", part)
+	}
+	return filepath.Join(dir, name+".ndjson")
+}
+
+// sync flushes the open files to disk, if anything was written since the
+// last time
+func (f *File) sync() error {
+	if !f.dirty {
+		return nil
+	}
+	for _, df := range f.files {
+		if err := df.file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync event file: %w", err)
+		}
+	}
+	f.dirty = false
+	return nil
+}
+
+func (f *File) syncLoop(interval time.Duration) {
+	defer close(f.stopped)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+			f.mu.Lock()
+			// A failed sync is retried on the next tick and on close
+			f.sync()
+			f.mu.Unlock()
+		}
+	}
+}
+
+// Close flushes and closes the open files
+func (f *File) Close() error {
+	if f.stop != nil {
+		close(f.stop)
+		<-f.stopped
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.dirty = true
+	err := f.sync()
+	for streamer, df := range f.files {
+		if cerr := df.file.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to close event file: %w", cerr)
+		}
+		delete(f.files, streamer)
+	}
+	return err
+}
@@ -0,0 +1,212 @@
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tiktok-live-logger/pkg/database"
+)
+
+func testEvent(streamer, content string) *database.Event {
+	return &database.Event{
+		SessionID: 1,
+		Username:  streamer,
+		Type:      "chat",
+		Content:   content,
+		Timestamp: time.Date(2026, 1, 31, 20, 0, 0, 0, time.UTC),
+	}
+}
+
+func openTestFile(t *testing.T, config FileConfig) *File {
+	t.Helper()
+	f, err := NewFile(config)
+	if err != nil {
+		t.Fatalf("NewFile: %v", err)
+	}
+	return f
+}
+
+// readLines returns the records in an event file
+func readLines(t *testing.T, path string) []Record {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open event file: %v", err)
+	}
+	defer file.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("%s holds an invalid line: %v", path, err)
+		}
+		records = append(records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestFileSplitsPerStreamerAndDay(t *testing.T) {
+	dir := t.TempDir()
+	config := FileConfig{Enabled: true, Dir: dir, MaxSizeMB: 1, Fsync: FsyncNever}
+	f := openTestFile(t, config)
+
+	// Three of these fit in a megabyte, the fourth starts a new part
+	big := strings.Repeat("x", 300<<10)
+	day2 := time.Date(2026, 2, 1, 0, 30, 0, 0, time.UTC)
+	var events []*database.Event
+	for range 4 {
+		events = append(events, testEvent("streamer1", big))
+	}
+	next := testEvent("streamer1", "next day")
+	next.Timestamp = day2
+	events = append(events, next, testEvent("streamer2", "hi"))
+	for _, e := range events {
+		if err := f.Write(e); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	want := map[string]int{
+		"streamer1/streamer1-2026-01-31.ndjson":   3,
+		"streamer1/streamer1-2026-01-31.1.ndjson": 1,
+		"streamer1/streamer1-2026-02-01.ndjson":   1,
+		"streamer2/streamer2-2026-01-31.ndjson":   1,
+	}
+	for name, n := range want {
+		if records := readLines(t, filepath.Join(dir, name)); len(records) != n {
+			t.Errorf("%s holds %d events, want %d", name, len(records), n)
+		}
+	}
+	info, err := os.Stat(filepath.Join(dir, "streamer1/streamer1-2026-01-31.ndjson"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() > 1<<20 {
+		t.Errorf("first part is %d bytes, over the limit", info.Size())
+	}
+
+	// A new run continues in the last part of the day while it has room
+	f = openTestFile(t, config)
+	if err := f.Write(testEvent("streamer1", "again")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	records := readLines(t, filepath.Join(dir, "streamer1/streamer1-2026-01-31.1.ndjson"))
+	if len(records) != 2 || records[1].Content != "again" {
+		t.Errorf("last part holds %d events after the second run, want 2", len(records))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "streamer1/streamer1-2026-01-31.2.ndjson")); !os.IsNotExist(err) {
+		t.Errorf("second run started a new part: %v", err)
+	}
+}
+
+func TestFileFsync(t *testing.T) {
+	for _, tt := range []struct {
+		fsync string
+		// dirty is whether unsynced writes are left right after Write
+		dirty bool
+	}{
+		{FsyncAlways, false},
+		{FsyncInterval, true},
+		{FsyncNever, true},
+	} {
+		t.Run(tt.fsync, func(t *testing.T) {
+			f := openTestFile(t, FileConfig{Enabled: true, Dir: t.TempDir(), Fsync: tt.fsync, FsyncSeconds: 1})
+			if err := f.Write(testEvent("streamer1", "hi")); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			f.mu.Lock()
+			dirty := f.dirty
+			f.mu.Unlock()
+			if dirty != tt.dirty {
+				t.Errorf("after Write, dirty = %v, want %v", dirty, tt.dirty)
+			}
+
+			if tt.fsync == FsyncInterval {
+				// The sync loop catches up within the interval
+				deadline := time.Now().Add(3 * time.Second)
+				for dirty && time.Now().Before(deadline) {
+					time.Sleep(50 * time.Millisecond)
+					f.mu.Lock()
+					dirty = f.dirty
+					f.mu.Unlock()
+				}
+				if dirty {
+					t.Error("interval sync didn't run")
+				}
+			}
+
+			if err := f.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+			if f.dirty || len(f.files) != 0 {
+				t.Errorf("after Close, dirty = %v with %d open files", f.dirty, len(f.files))
+			}
+		})
+	}
+}
+
+func TestFileConfigValidate(t *testing.T) {
+	valid := FileConfig{Dir: "events", MaxSizeMB: 100, Fsync: FsyncInterval, FsyncSeconds: 1}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate(%+v) = %v", valid, err)
+	}
+	for name, change := range map[string]func(c *FileConfig){
+		"no directory":      func(c *FileConfig) { c.Dir = "" },
+		"negative size":     func(c *FileConfig) { c.MaxSizeMB = -1 },
+		"unknown policy":    func(c *FileConfig) { c.Fsync = "sometimes" },
+		"no policy":         func(c *FileConfig) { c.Fsync = "" },
+		"zero interval":     func(c *FileConfig) { c.FsyncSeconds = 0 },
+		"negative interval": func(c *FileConfig) { c.FsyncSeconds = -5 },
+	} {
+		c := valid
+		change(&c)
+		if err := c.Validate(); err == nil {
+			t.Errorf("%s: Validate(%+v) accepted it", name, c)
+		}
+		if _, err := NewFile(c); err == nil {
+			t.Errorf("%s: NewFile accepted it", name)
+		}
+	}
+}
+
+func TestCheckStreamer(t *testing.T) {
+	for _, name := range []string{"streamer1", "some.user_name", "..streamer"} {
+		if err := checkStreamer(name); err != nil {
+			t.Errorf("checkStreamer(%q) = %v", name, err)
+		}
+	}
+
+	dir := t.TempDir()
+	f := openTestFile(t, FileConfig{Enabled: true, Dir: filepath.Join(dir, "events"), Fsync: FsyncNever})
+	defer f.Close()
+	for _, name := range []string{"", ".", "..", "../evil", "a/b", `a\b`, "c:", "a\x00b"} {
+		if err := checkStreamer(name); err == nil {
+			t.Errorf("checkStreamer(%q) accepted it", name)
+		}
+		if err := f.Write(testEvent(name, "hi")); err == nil {
+			t.Errorf("Write accepted an event of streamer %q", name)
+		}
+	}
+	// Nothing was written next to the sink directory
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("directory around the sink holds %d entries (%v)", len(entries), err)
+	}
+}
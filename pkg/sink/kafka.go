@@ -0,0 +1,46 @@
+package sink
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// kafkaPublisher publishes to a Kafka topic with the streamer as message
+// key, so each streamer's events stay in order on one partition. Message IDs
+// are sent in the id header.
+type kafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+func newKafka(brokers, topic string) (*kafkaPublisher, error) {
+	return &kafkaPublisher{writer: &kafka.Writer{
+		Addr:         kafka.TCP(strings.Split(brokers, ",")...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchSize:    publishBatch,
+		BatchTimeout: 10 * time.Millisecond,
+		Transport:    &kafka.Transport{},
+		// Brokers with auto.create.topics.enable create the topic
+		AllowAutoTopicCreation: true,
+	}}, nil
+}
+
+func (p *kafkaPublisher) Publish(ctx context.Context, msgs []Pending) error {
+	batch := make([]kafka.Message, len(msgs))
+	for i, m := range msgs {
+		batch[i] = kafka.Message{
+			Key:     []byte(m.Streamer),
+			Value:   m.Data,
+			Headers: []kafka.Header{{Key: "id", Value: []byte(m.ID)}},
+		}
+	}
+	return p.writer.WriteMessages(ctx, batch...)
+}
+
+func (p *kafkaPublisher) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,85 @@
+//go:build kafka
+
+package sink
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// TestKafkaRoundTrip needs a broker: run it with -tags kafka and
+// TIKTOK_TEST_KAFKA_BROKERS set to host:9092
+func TestKafkaRoundTrip(t *testing.T) {
+	brokers := os.Getenv("TIKTOK_TEST_KAFKA_BROKERS")
+	if brokers == "" {
+		t.Skip("TIKTOK_TEST_KAFKA_BROKERS is not set")
+	}
+	topic := "tiktok-events-test-" + newID()[:8]
+	conn, err := kafka.Dial("tcp", strings.Split(brokers, ",")[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	err = conn.CreateTopics(kafka.TopicConfig{Topic: topic, NumPartitions: 3, ReplicationFactor: 1})
+	if err != nil {
+		t.Fatalf("failed to create topic: %v", err)
+	}
+	defer conn.DeleteTopics(topic)
+
+	events := [][2]string{
+		{"streamer1", "alice: one"},
+		{"streamer2", "bob: two"},
+		{"streamer1", "carol: three"},
+		{"streamer3", "dave: four"},
+		{"streamer1", "erin: five"},
+	}
+	publishAll(t, PublishConfig{Broker: Kafka, URL: brokers, Topic: topic}, events...)
+
+	// Each streamer's events are on one partition, in order
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	partitions := make(map[string]int)
+	contents := make(map[string][]string)
+	for partition := range 3 {
+		r := kafka.NewReader(kafka.ReaderConfig{Brokers: strings.Split(brokers, ","), Topic: topic, Partition: partition})
+		defer r.Close()
+		lag, err := r.ReadLag(ctx)
+		if err != nil {
+			t.Fatalf("ReadLag: %v", err)
+		}
+		for range lag {
+			m, err := r.ReadMessage(ctx)
+			if err != nil {
+				t.Fatalf("ReadMessage: %v", err)
+			}
+			msg := decodeMessage(t, m.Value)
+			streamer := string(m.Key)
+			if p, ok := partitions[streamer]; ok && p != partition {
+				t.Errorf("%s has events on partitions %d and %d", streamer, p, partition)
+			}
+			partitions[streamer] = partition
+			contents[streamer] = append(contents[streamer], msg.Content)
+			if msg.Streamer != streamer {
+				t.Errorf("message of %s has key %q", msg.Streamer, streamer)
+			}
+			if len(m.Headers) != 1 || m.Headers[0].Key != "id" || string(m.Headers[0].Value) != msg.ID {
+				t.Errorf("message %q has headers %v, want id %s", msg.Content, m.Headers, msg.ID)
+			}
+		}
+	}
+	want := map[string][]string{
+		"streamer1": {"alice: one", "carol: three", "erin: five"},
+		"streamer2": {"bob: two"},
+		"streamer3": {"dave: four"},
+	}
+	for streamer, w := range want {
+		if got := strings.Join(contents[streamer], ", "); got != strings.Join(w, ", ") {
+			t.Errorf("%s: got %s, want %s", streamer, got, strings.Join(w, ", "))
+		}
+	}
+}
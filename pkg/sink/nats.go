@@ -0,0 +1,66 @@
+package sink
+
+import (
+	"context"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// natsPublisher publishes to JetStream on <subject>.<streamer>, so a stream
+// on <subject>.> must exist. Message IDs are sent as Nats-Msg-Id, which lets
+// JetStream drop duplicates.
+type natsPublisher struct {
+	conn    *nats.Conn
+	js      jetstream.JetStream
+	subject string
+}
+
+func newNATS(url, subject string) (*natsPublisher, error) {
+	// Keep trying in the background, events wait in the outbox meanwhile
+	conn, err := nats.Connect(url, nats.Name("tiktok-live-logger"),
+		nats.RetryOnFailedConnect(true), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &natsPublisher{conn: conn, js: js, subject: subject}, nil
+}
+
+// subjectToken makes a streamer one subject token. Usernames may contain
+// dots, which separate tokens.
+func subjectToken(streamer string) string {
+	return strings.ReplaceAll(streamer, ".", "_")
+}
+
+func (p *natsPublisher) Publish(ctx context.Context, msgs []Pending) error {
+	futures := make([]jetstream.PubAckFuture, 0, len(msgs))
+	for _, m := range msgs {
+		msg := nats.NewMsg(p.subject + "." + subjectToken(m.Streamer))
+		msg.Data = m.Data
+		f, err := p.js.PublishMsgAsync(msg, jetstream.WithMsgID(m.ID))
+		if err != nil {
+			return err
+		}
+		futures = append(futures, f)
+	}
+	for _, f := range futures {
+		select {
+		case <-f.Ok():
+		case err := <-f.Err():
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (p *natsPublisher) Close() error {
+	return p.conn.Drain()
+}
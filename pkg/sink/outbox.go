@@ -0,0 +1,123 @@
+package sink
+
+import (
+	"cmp"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// outboxLease is how long a claimed batch is left to its process before
+// another one may publish it, e.g. after a crash
+const outboxLease = time.Minute
+
+// outbox holds encoded events until the broker acknowledged them. Several
+// processes can share one: they lease batches before publishing them.
+type outbox struct {
+	db    *sql.DB
+	owner string
+}
+
+// entry is an event waiting in the outbox
+type entry struct {
+	id      int64
+	message string
+	key     string
+	data    []byte
+}
+
+func openOutbox(path, owner string) (*outbox, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create outbox directory: %w", err)
+	}
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	db, err := sql.Open("sqlite3", path+sep+"_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open outbox: %w", err)
+	}
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS outbox (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		message TEXT NOT NULL,
+		key TEXT NOT NULL,
+		data BLOB NOT NULL,
+		owner TEXT NOT NULL DEFAULT '',
+		leased_until INTEGER NOT NULL DEFAULT 0
+	)`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open outbox: %w", err)
+	}
+	return &outbox{db: db, owner: owner}, nil
+}
+
+func (o *outbox) add(message, key string, data []byte) error {
+	_, err := o.db.Exec(`INSERT INTO outbox (message, key, data) VALUES (?, ?, ?)`, message, key, data)
+	return err
+}
+
+// claim leases up to limit of the oldest events no other process holds, in
+// the order they were added
+func (o *outbox) claim(limit int) ([]entry, error) {
+	now := time.Now()
+	rows, err := o.db.Query(`
+	UPDATE outbox SET owner = ?, leased_until = ?
+	WHERE id IN (SELECT id FROM outbox WHERE owner = ? OR leased_until < ? ORDER BY id LIMIT ?)
+	RETURNING id, message, key, data
+	`, o.owner, now.Add(outboxLease).Unix(), o.owner, now.Unix(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []entry
+	for rows.Next() {
+		var e entry
+		if err := rows.Scan(&e.id, &e.message, &e.key, &e.data); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	slices.SortFunc(entries, func(a, b entry) int { return cmp.Compare(a.id, b.id) })
+	return entries, rows.Err()
+}
+
+// remove deletes published events
+func (o *outbox) remove(entries []entry) error {
+	tx, err := o.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if _, err := tx.Exec(`DELETE FROM outbox WHERE id = ?`, e.id); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// release gives up the leases of this process, so the next one can publish
+// what is left right away
+func (o *outbox) release() error {
+	_, err := o.db.Exec(`UPDATE outbox SET owner = '', leased_until = 0 WHERE owner = ?`, o.owner)
+	return err
+}
+
+// pending counts the events no process is publishing
+func (o *outbox) pending() (int64, error) {
+	var n int64
+	err := o.db.QueryRow(`SELECT COUNT(*) FROM outbox WHERE leased_until < ?`, time.Now().Unix()).Scan(&n)
+	return n, err
+}
+
+func (o *outbox) close() error {
+	return o.db.Close()
+}
@@ -0,0 +1,105 @@
+package sink
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestOutbox(t *testing.T, path, owner string) *outbox {
+	t.Helper()
+	o, err := openOutbox(path, owner)
+	if err != nil {
+		t.Fatalf("openOutbox: %v", err)
+	}
+	t.Cleanup(func() { o.close() })
+	return o
+}
+
+func claimed(t *testing.T, o *outbox) []string {
+	t.Helper()
+	entries, err := o.claim(10)
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	var messages []string
+	for _, e := range entries {
+		messages = append(messages, e.message)
+	}
+	return messages
+}
+
+func pending(t *testing.T, o *outbox) int64 {
+	t.Helper()
+	n, err := o.pending()
+	if err != nil {
+		t.Fatalf("pending: %v", err)
+	}
+	return n
+}
+
+func TestOutboxLeases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.db")
+	a := openTestOutbox(t, path, "a")
+	b := openTestOutbox(t, path, "b")
+	for _, m := range []string{"m1", "m2", "m3"} {
+		if err := a.add(m, "streamer1", []byte(m)); err != nil {
+			t.Fatalf("add: %v", err)
+		}
+	}
+
+	entries, err := a.claim(2)
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if len(entries) != 2 || entries[0].message != "m1" || entries[1].message != "m2" {
+		t.Fatalf("a claimed %+v, want m1 and m2", entries)
+	}
+	// Leased events are left to their process, which gets them again
+	// after a failed publish
+	if got := claimed(t, b); len(got) != 1 || got[0] != "m3" {
+		t.Errorf("b claimed %v, want [m3]", got)
+	}
+	if got := claimed(t, a); len(got) != 2 {
+		t.Errorf("a claimed %v again, want m1 and m2", got)
+	}
+	if n := pending(t, a); n != 0 {
+		t.Errorf("pending = %d with every event leased", n)
+	}
+
+	// Once a's lease expires, as after a crash, b publishes its events
+	if _, err := a.db.Exec(`UPDATE outbox SET leased_until = ? WHERE owner = 'a'`, time.Now().Add(-time.Second).Unix()); err != nil {
+		t.Fatal(err)
+	}
+	entries, err = b.claim(10)
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if len(entries) != 3 || entries[0].message != "m1" || entries[2].message != "m3" {
+		t.Fatalf("b claimed %+v after the lease expired, want m1 to m3", entries)
+	}
+	if err := b.remove(entries); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if got := claimed(t, a); len(got) != 0 {
+		t.Errorf("a claimed %v after they were published", got)
+	}
+
+	// Released leases are free right away
+	if err := a.add("m4", "streamer1", []byte("m4")); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	claimed(t, a)
+	if got := claimed(t, b); len(got) != 0 {
+		t.Errorf("b claimed %v leased by a", got)
+	}
+	if err := a.release(); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if n := pending(t, b); n != 1 {
+		t.Errorf("pending = %d after release, want 1", n)
+	}
+	if got := claimed(t, b); len(got) != 1 || got[0] != "m4" {
+		t.Errorf("b claimed %v after release, want [m4]", got)
+	}
+}
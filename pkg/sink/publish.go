@@ -0,0 +1,269 @@
+package sink
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"tiktok-live-logger/pkg/database"
+)
+
+// Brokers events can be published to
+const (
+	NATS  = "nats"
+	Kafka = "kafka"
+	Redis = "redis"
+)
+
+// Schema is the version of the published message format. Fields are only
+// ever added within a version.
+const Schema = 1
+
+// publishBatch is the most events published at once
+const publishBatch = 100
+
+// PublishConfig configures publishing events to a message broker
+type PublishConfig struct {
+	// Broker is nats, kafka or redis. Empty disables publishing.
+	Broker string `json:"broker"`
+	// URL is nats://host:4222 for NATS, host:9092 for Kafka, with more
+	// brokers separated by commas, and redis://host:6379/0 for Redis
+	URL string `json:"url"`
+	// Topic is the NATS subject prefix, Kafka topic or Redis stream prefix.
+	// Empty uses tiktok.events, tiktok-events or tiktok:events.
+	Topic string `json:"topic,omitempty"`
+	// Outbox is the file events wait in until the broker acknowledged them
+	Outbox string `json:"outbox"`
+}
+
+// Validate checks the broker settings
+func (c PublishConfig) Validate() error {
+	switch c.Broker {
+	case "":
+		return nil
+	case NATS, Kafka, Redis:
+	default:
+		return fmt.Errorf("unknown broker %q: must be nats, kafka or redis", c.Broker)
+	}
+	if c.URL == "" {
+		return fmt.Errorf("%s publishing needs a broker URL", c.Broker)
+	}
+	if c.Outbox == "" {
+		return fmt.Errorf("publishing needs an outbox file")
+	}
+	return nil
+}
+
+// topic returns the configured topic or the broker's default
+func (c PublishConfig) topic() string {
+	if c.Topic != "" {
+		return c.Topic
+	}
+	switch c.Broker {
+	case Kafka:
+		return "tiktok-events"
+	case Redis:
+		return "tiktok:events"
+	}
+	return "tiktok.events"
+}
+
+// Message is the normalized event published to brokers, as JSON. ID is
+// unique per event, so consumers can drop the duplicates at-least-once
+// delivery may bring.
+type Message struct {
+	Schema int    `json:"schema"`
+	ID     string `json:"id"`
+	Record
+}
+
+// Publisher delivers messages to a broker, keyed by streamer. Publish
+// returns once the broker acknowledged all of them.
+type Publisher interface {
+	Publish(ctx context.Context, msgs []Pending) error
+	Close() error
+}
+
+// Pending is an encoded Message waiting to be published
+type Pending struct {
+	ID       string
+	Streamer string
+	Data     []byte
+}
+
+func newPublisher(config PublishConfig) (Publisher, error) {
+	switch config.Broker {
+	case NATS:
+		return newNATS(config.URL, config.topic())
+	case Kafka:
+		return newKafka(config.URL, config.topic())
+	case Redis:
+		return newRedis(config.URL, config.topic())
+	}
+	return nil, fmt.Errorf("unknown broker %q", config.Broker)
+}
+
+// Publish is a sink publishing events to a broker. Events are stored in a
+// local outbox first and published in the background, in order, until the
+// broker acknowledged them. Events the broker doesn't take before Close stay
+// in the outbox for the next run, so every event is delivered at least once.
+type Publish struct {
+	publisher Publisher
+	outbox    *outbox
+	onError   func(error)
+	wake      chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewPublish connects to the broker and starts publishing what is waiting in
+// the outbox. onError, if not nil, is called when publishing fails; the
+// events are retried.
+func NewPublish(config PublishConfig, onError func(error)) (*Publish, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	publisher, err := newPublisher(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", config.Broker, err)
+	}
+	return newPublish(publisher, config.Outbox, onError)
+}
+
+// newPublish starts publishing the outbox at path with publisher, which it
+// closes on failure
+func newPublish(publisher Publisher, path string, onError func(error)) (*Publish, error) {
+	outbox, err := openOutbox(path, newID())
+	if err != nil {
+		publisher.Close()
+		return nil, err
+	}
+	if onError == nil {
+		onError = func(error) {}
+	}
+	p := &Publish{
+		publisher: publisher,
+		outbox:    outbox,
+		onError:   onError,
+		wake:      make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go p.run()
+	return p, nil
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Write stores the event in the outbox to be published
+func (p *Publish) Write(event *database.Event) error {
+	id := newID()
+	data, err := json.Marshal(Message{Schema: Schema, ID: id, Record: NewRecord(*event)})
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	if err := p.outbox.add(id, event.Username, data); err != nil {
+		return fmt.Errorf("failed to add event to outbox: %w", err)
+	}
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (p *Publish) run() {
+	defer close(p.done)
+	backoff := time.Second
+	for {
+		n, err := p.publishNext(30 * time.Second)
+		if err != nil {
+			p.onError(err)
+			select {
+			case <-p.stop:
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, 30*time.Second)
+			continue
+		}
+		backoff = time.Second
+		if n == publishBatch {
+			continue
+		}
+		// Check the outbox now and then for events other processes
+		// left behind
+		select {
+		case <-p.stop:
+			return
+		case <-p.wake:
+		case <-time.After(outboxLease):
+		}
+	}
+}
+
+// publishNext publishes the next batch from the outbox, waiting up to
+// timeout for the broker, and returns how many events it held
+func (p *Publish) publishNext(timeout time.Duration) (int, error) {
+	entries, err := p.outbox.claim(publishBatch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read outbox: %w", err)
+	}
+	if len(entries) == 0 {
+		return 0, nil
+	}
+	msgs := make([]Pending, len(entries))
+	for i, e := range entries {
+		msgs[i] = Pending{ID: e.message, Streamer: e.key, Data: e.data}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := p.publisher.Publish(ctx, msgs); err != nil {
+		return 0, fmt.Errorf("failed to publish events: %w", err)
+	}
+	if err := p.outbox.remove(entries); err != nil {
+		return 0, fmt.Errorf("failed to remove published events from outbox: %w", err)
+	}
+	return len(entries), nil
+}
+
+// Close publishes what is left in the outbox for up to a few seconds and
+// disconnects. Events still waiting are published by the next run.
+func (p *Publish) Close() error {
+	var err error
+	p.closeOnce.Do(func() {
+		close(p.stop)
+		<-p.done
+
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			n, perr := p.publishNext(time.Until(deadline))
+			if perr != nil {
+				err = perr
+				break
+			}
+			if n == 0 {
+				break
+			}
+		}
+		if rerr := p.outbox.release(); rerr != nil && err == nil {
+			err = rerr
+		}
+		if left, _ := p.outbox.pending(); left > 0 && err == nil {
+			err = fmt.Errorf("%d events wait in the outbox for the next run", left)
+		}
+		p.publisher.Close()
+		p.outbox.close()
+	})
+	return err
+}
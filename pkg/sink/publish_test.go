@@ -0,0 +1,205 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"tiktok-live-logger/pkg/database"
+)
+
+// fakePublisher records what it is asked to publish, failing the first
+// failures calls
+type fakePublisher struct {
+	mu        sync.Mutex
+	failures  int
+	attempted []Pending
+	published []Pending
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, msgs []Pending) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.attempted = append(p.attempted, msgs...)
+	if p.failures != 0 {
+		p.failures--
+		return errors.New("broker unavailable")
+	}
+	p.published = append(p.published, msgs...)
+	return nil
+}
+
+func (p *fakePublisher) Close() error {
+	return nil
+}
+
+func (p *fakePublisher) ids() (attempted, published []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, m := range p.attempted {
+		attempted = append(attempted, m.ID)
+	}
+	for _, m := range p.published {
+		published = append(published, m.ID)
+	}
+	return attempted, published
+}
+
+// waitPublished waits for the publisher to have published n messages
+func (p *fakePublisher) waitPublished(t *testing.T, n int) []Pending {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		p.mu.Lock()
+		published := slices.Clone(p.published)
+		p.mu.Unlock()
+		if len(published) >= n {
+			return published
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("published %d messages, want %d", len(published), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func openTestPublish(t *testing.T, publisher Publisher, path string, onError func(error)) *Publish {
+	t.Helper()
+	p, err := newPublish(publisher, path, onError)
+	if err != nil {
+		t.Fatalf("newPublish: %v", err)
+	}
+	return p
+}
+
+func TestPublishDeliversInOrder(t *testing.T) {
+	fake := &fakePublisher{}
+	p := openTestPublish(t, fake, filepath.Join(t.TempDir(), "outbox.db"), nil)
+	for _, e := range []*database.Event{
+		testEvent("streamer1", "alice: one"),
+		testEvent("streamer2", "bob: two"),
+		testEvent("streamer1", "carol: three"),
+	} {
+		if err := p.Write(e); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	published := fake.waitPublished(t, 3)
+	for i, want := range []struct{ streamer, content string }{
+		{"streamer1", "alice: one"},
+		{"streamer2", "bob: two"},
+		{"streamer1", "carol: three"},
+	} {
+		var msg Message
+		if err := json.Unmarshal(published[i].Data, &msg); err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+		if published[i].Streamer != want.streamer || msg.Streamer != want.streamer || msg.Content != want.content {
+			t.Errorf("message %d = %s %+v, want %s %q", i, published[i].Streamer, msg, want.streamer, want.content)
+		}
+		if msg.Schema != Schema || msg.ID == "" || msg.ID != published[i].ID {
+			t.Errorf("message %d has schema %d and ID %q, published as %q", i, msg.Schema, msg.ID, published[i].ID)
+		}
+	}
+	if err := p.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+}
+
+func TestPublishRetriesFailures(t *testing.T) {
+	fake := &fakePublisher{failures: 1}
+	var mu sync.Mutex
+	var errs []error
+	p := openTestPublish(t, fake, filepath.Join(t.TempDir(), "outbox.db"), func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	})
+	if err := p.Write(testEvent("streamer1", "alice: hi")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	fake.waitPublished(t, 1)
+	attempted, published := fake.ids()
+	if len(attempted) != 2 || attempted[0] != attempted[1] || published[0] != attempted[0] {
+		t.Errorf("attempted %v and published %v, want the same message twice and once", attempted, published)
+	}
+	mu.Lock()
+	if len(errs) != 1 {
+		t.Errorf("onError was called %d times, want once", len(errs))
+	}
+	mu.Unlock()
+	if err := p.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+}
+
+func TestPublishKeepsEventsForNextRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.db")
+	down := &fakePublisher{failures: -1}
+	p := openTestPublish(t, down, path, nil)
+	for _, content := range []string{"alice: one", "bob: two"} {
+		if err := p.Write(testEvent("streamer1", content)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := p.Close(); err == nil {
+		t.Error("Close succeeded with the broker down")
+	}
+	if n := pending(t, openTestOutbox(t, path, "other")); n != 2 {
+		t.Errorf("%d events left in the outbox, want 2", n)
+	}
+
+	// The next run publishes them with the same IDs, so consumers can drop
+	// any duplicates
+	up := &fakePublisher{}
+	p = openTestPublish(t, up, path, nil)
+	defer p.Close()
+	up.waitPublished(t, 2)
+	attempted, _ := down.ids()
+	_, published := up.ids()
+	slices.Sort(attempted)
+	attempted = slices.Compact(attempted)
+	if !slices.Equal(attempted, slices.Sorted(slices.Values(published))) {
+		t.Errorf("published %v, want the IDs tried before %v", published, attempted)
+	}
+}
+
+func TestPublishTakesOverExpiredLeases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.db")
+
+	// A process that crashed after claiming an event
+	crashed := openTestOutbox(t, path, "crashed")
+	if err := crashed.add("lost", "streamer1", []byte(`{}`)); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	claimed(t, crashed)
+
+	fake := &fakePublisher{}
+	p := openTestPublish(t, fake, path, nil)
+	defer p.Close()
+	if err := p.Write(testEvent("streamer1", "alice: hi")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if published := fake.waitPublished(t, 1); published[0].ID == "lost" {
+		t.Fatal("published an event leased by another process")
+	}
+
+	// Once the lease expires, the event is published after all
+	if _, err := crashed.db.Exec(`UPDATE outbox SET leased_until = ?`, time.Now().Add(-time.Second).Unix()); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Write(testEvent("streamer1", "bob: hi")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	published := fake.waitPublished(t, 3)
+	if published[1].ID != "lost" {
+		t.Errorf("published %s second, want the expired event before the newer one", published[1].ID)
+	}
+}
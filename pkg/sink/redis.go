@@ -0,0 +1,46 @@
+package sink
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisPublisher adds events to one Redis stream per streamer,
+// <stream>:<streamer>, with the message ID in the id field and the JSON
+// message in the event field
+type redisPublisher struct {
+	client *redis.Client
+	stream string
+}
+
+// quietLogger drops the client's own logging, which would write over the
+// TUI. Failures reach the logger through Publish.
+type quietLogger struct{}
+
+func (quietLogger) Printf(ctx context.Context, format string, v ...any) {}
+
+func newRedis(url, stream string) (*redisPublisher, error) {
+	redis.SetLogger(quietLogger{})
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	return &redisPublisher{client: redis.NewClient(opts), stream: stream}, nil
+}
+
+func (p *redisPublisher) Publish(ctx context.Context, msgs []Pending) error {
+	pipe := p.client.Pipeline()
+	for _, m := range msgs {
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: p.stream + ":" + m.Streamer,
+			Values: []any{"id", m.ID, "event", m.Data},
+		})
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (p *redisPublisher) Close() error {
+	return p.client.Close()
+}
@@ -0,0 +1,164 @@
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"tiktok-live-logger/pkg/database"
+)
+
+// Sink stores or forwards logged events. The database sink sets the ID of
+// the events it stores.
+type Sink interface {
+	Write(event *database.Event) error
+	Close() error
+}
+
+// Config says where logged events go. Sessions are only recorded with the
+// database sink; events written elsewhere have no session ID then.
+type Config struct {
+	// Database stores events in the database, which list, report and the
+	// other commands read
+	Database bool          `json:"database"`
+	File     FileConfig    `json:"file"`
+	Publish  PublishConfig `json:"publish"`
+}
+
+// DefaultConfig stores events in the database only
+func DefaultConfig() Config {
+	return Config{
+		Database: true,
+		File: FileConfig{
+			MaxSizeMB:    100,
+			Fsync:        FsyncInterval,
+			FsyncSeconds: 1,
+		},
+	}
+}
+
+// Validate checks that events go somewhere and the sink settings
+func (c Config) Validate() error {
+	if !c.Database && !c.File.Enabled && c.Publish.Broker == "" {
+		return fmt.Errorf("no sink enabled: enable the database, the file sink or a broker")
+	}
+	if c.File.Enabled {
+		if err := c.File.Validate(); err != nil {
+			return err
+		}
+	}
+	return c.Publish.Validate()
+}
+
+// New opens the sinks enabled in the config, writing to db for the
+// database sink; db may be nil without it. onError is called when publishing
+// to a broker fails in the background.
+func New(config Config, db *database.DB, onError func(error)) (FanOut, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	var sinks FanOut
+	if config.Database {
+		if db == nil {
+			return nil, fmt.Errorf("database sink needs a database")
+		}
+		sinks = append(sinks, Database(db))
+	}
+	if config.File.Enabled {
+		file, err := NewFile(config.File)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, file)
+	}
+	if config.Publish.Broker != "" {
+		publish, err := NewPublish(config.Publish, onError)
+		if err != nil {
+			sinks.Close()
+			return nil, err
+		}
+		sinks = append(sinks, publish)
+	}
+	return sinks, nil
+}
+
+// FanOut writes each event to all of its sinks. A failing sink doesn't keep
+// the event from the others.
+type FanOut []Sink
+
+func (f FanOut) Write(event *database.Event) error {
+	var errs []error
+	for _, s := range f {
+		if err := s.Write(event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f FanOut) Close() error {
+	var errs []error
+	for _, s := range f {
+		if err := s.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Database returns a sink saving events with db.SaveEvent. Closing it
+// leaves db open.
+func Database(db *database.DB) Sink {
+	return dbSink{db: db}
+}
+
+type dbSink struct {
+	db *database.DB
+}
+
+func (s dbSink) Write(event *database.Event) error {
+	id, err := s.db.SaveEvent(*event)
+	event.ID = id
+	return err
+}
+
+func (s dbSink) Close() error {
+	return nil
+}
+
+// Record is the JSON form of an event written by sinks. Fields are only
+// ever added, so consumers can rely on the existing ones.
+type Record struct {
+	Streamer  string    `json:"streamer"`
+	SessionID int64     `json:"session_id,omitempty"`
+	Type      string    `json:"type"`
+	Content   string    `json:"content"`
+	Timestamp time.Time `json:"timestamp"`
+	UserID    int64     `json:"user_id,omitempty"`
+	Nickname  string    `json:"nickname,omitempty"`
+	Value     int64     `json:"value,omitempty"`
+	Flags     []string  `json:"flags,omitempty"`
+	Sentiment *float64  `json:"sentiment,omitempty"`
+	Toxicity  *float64  `json:"toxicity,omitempty"`
+	Language  string    `json:"language,omitempty"`
+}
+
+// NewRecord converts an event to its JSON form
+func NewRecord(e database.Event) Record {
+	r := Record{
+		Streamer:  e.Username,
+		SessionID: e.SessionID,
+		Type:      e.Type,
+		Content:   e.Content,
+		Timestamp: e.Timestamp,
+		UserID:    e.UserID,
+		Nickname:  e.Nickname,
+		Value:     e.Value,
+		Flags:     e.Flags,
+		Language:  e.Language,
+	}
+	if e.Scored {
+		r.Sentiment, r.Toxicity = &e.Sentiment, &e.Toxicity
+	}
+	return r
+}
@@ -0,0 +1,185 @@
+package subtitle
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Line is a single message to show on screen
+type Line struct {
+	At   time.Time
+	Text string
+}
+
+// Options controls cue timing and layout
+type Options struct {
+	// Offset is added to every cue time, e.g. to account for a recording
+	// that started before or after the session. It may be negative.
+	Offset time.Duration
+	// CueDuration is how long each line stays on screen
+	CueDuration time.Duration
+	// MaxLines is the number of lines shown at once; older lines scroll off
+	MaxLines int
+}
+
+// DefaultOptions shows each line for four seconds, three lines at a time
+func DefaultOptions() Options {
+	return Options{
+		CueDuration: 4 * time.Second,
+		MaxLines:    3,
+	}
+}
+
+// Cue is a span of time during which a fixed set of lines is shown
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	Lines []string
+}
+
+// Build converts lines into cues timed relative to start. Each line is shown
+// for opts.CueDuration and overlapping lines are stacked, keeping only the
+// newest opts.MaxLines on screen.
+func Build(lines []Line, start time.Time, opts Options) []Cue {
+	if opts.CueDuration <= 0 {
+		opts.CueDuration = DefaultOptions().CueDuration
+	}
+	if opts.MaxLines <= 0 {
+		opts.MaxLines = DefaultOptions().MaxLines
+	}
+
+	sorted := make([]Line, len(lines))
+	copy(sorted, lines)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].At.Before(sorted[j].At)
+	})
+
+	type span struct {
+		start, end time.Duration
+		text       string
+	}
+	spans := make([]span, len(sorted))
+	var bounds []time.Duration
+	for i, line := range sorted {
+		s := line.At.Sub(start) + opts.Offset
+		spans[i] = span{start: s, end: s + opts.CueDuration, text: line.Text}
+		bounds = append(bounds, spans[i].start, spans[i].end)
+	}
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+
+	var cues []Cue
+	first := 0
+	for i := 0; i+1 < len(bounds); i++ {
+		from, to := bounds[i], bounds[i+1]
+		if from == to || to <= 0 {
+			continue
+		}
+		if from < 0 {
+			from = 0
+		}
+
+		// Spans are sorted by start, so skip those that ended already
+		for first < len(spans) && spans[first].end <= from {
+			first++
+		}
+		var active []string
+		for _, s := range spans[first:] {
+			if s.start > from {
+				break
+			}
+			if s.end > from {
+				active = append(active, s.text)
+			}
+		}
+		if len(active) == 0 {
+			continue
+		}
+		if len(active) > opts.MaxLines {
+			active = active[len(active)-opts.MaxLines:]
+		}
+
+		// Extend the previous cue instead of repeating identical text
+		if n := len(cues); n > 0 && cues[n-1].End == from && equal(cues[n-1].Lines, active) {
+			cues[n-1].End = to
+			continue
+		}
+		cues = append(cues, Cue{Start: from, End: to, Lines: active})
+	}
+
+	return cues
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteSRT writes cues in SubRip format
+func WriteSRT(w io.Writer, cues []Cue) error {
+	bw := bufio.NewWriter(w)
+	for i, cue := range cues {
+		lines := sanitize(cue.Lines)
+		for j, line := range lines {
+			// SubRip has no escapes; a line with the timing arrow would be
+			// read as the start of the next cue
+			lines[j] = strings.ReplaceAll(line, "-->", "->")
+		}
+		fmt.Fprintf(bw, "%d\n%s --> %s\n%s\n\n",
+			i+1,
+			timestamp(cue.Start, ","),
+			timestamp(cue.End, ","),
+			strings.Join(lines, "\n"),
+		)
+	}
+	return bw.Flush()
+}
+
+// WriteVTT writes cues in WebVTT format
+func WriteVTT(w io.Writer, cues []Cue) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("WEBVTT\n\n")
+	for i, cue := range cues {
+		lines := sanitize(cue.Lines)
+		for j, line := range lines {
+			// Cue text must not contain the timing arrow or markup characters
+			line = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(line)
+			lines[j] = line
+		}
+		fmt.Fprintf(bw, "%d\n%s --> %s\n%s\n\n",
+			i+1,
+			timestamp(cue.Start, "."),
+			timestamp(cue.End, "."),
+			strings.Join(lines, "\n"),
+		)
+	}
+	return bw.Flush()
+}
+
+// sanitize keeps every message on one line so blank lines can't end a cue early
+func sanitize(lines []string) []string {
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		out[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return out
+}
+
+func timestamp(d time.Duration, sep string) string {
+	if d < 0 {
+		d = 0
+	}
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d",
+		ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
@@ -0,0 +1,128 @@
+package subtitle
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2026, 1, 31, 20, 0, 0, 0, time.UTC)
+
+func at(offset time.Duration, text string) Line {
+	return Line{At: start.Add(offset), Text: text}
+}
+
+func TestSRT(t *testing.T) {
+	lines := []Line{
+		at(1*time.Second, "alice: hi"),
+		at(2*time.Second, "bob: hello\n\nthere"),
+		at(3*time.Second, "carol: a --> b"),
+		at(4*time.Second, "dave: <b>bold</b>"),
+		at(3723*time.Second+500*time.Millisecond, "erin: late"),
+	}
+	cues := Build(lines, start, Options{CueDuration: 2 * time.Second, MaxLines: 2})
+
+	var b strings.Builder
+	if err := WriteSRT(&b, cues); err != nil {
+		t.Fatal(err)
+	}
+	want := `1
+00:00:01,000 --> 00:00:02,000
+alice: hi
+
+2
+00:00:02,000 --> 00:00:03,000
+alice: hi
+bob: hello there
+
+3
+00:00:03,000 --> 00:00:04,000
+bob: hello there
+carol: a -> b
+
+4
+00:00:04,000 --> 00:00:05,000
+carol: a -> b
+dave: <b>bold</b>
+
+5
+00:00:05,000 --> 00:00:06,000
+dave: <b>bold</b>
+
+6
+01:02:03,500 --> 01:02:05,500
+erin: late
+
+`
+	if b.String() != want {
+		t.Errorf("SRT =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestVTT(t *testing.T) {
+	// The recording started 2.5 seconds after the session, so the first
+	// line is already on screen when it starts
+	lines := []Line{
+		at(1*time.Second, "alice: hi"),
+		at(3*time.Second, "bob: 1 < 2 & 3 > 2 --> true"),
+	}
+	cues := Build(lines, start, Options{Offset: -2500 * time.Millisecond, CueDuration: 4 * time.Second, MaxLines: 3})
+
+	var b strings.Builder
+	if err := WriteVTT(&b, cues); err != nil {
+		t.Fatal(err)
+	}
+	want := `WEBVTT
+
+1
+00:00:00.000 --> 00:00:00.500
+alice: hi
+
+2
+00:00:00.500 --> 00:00:02.500
+alice: hi
+bob: 1 &lt; 2 &amp; 3 &gt; 2 --&gt; true
+
+3
+00:00:02.500 --> 00:00:04.500
+bob: 1 &lt; 2 &amp; 3 &gt; 2 --&gt; true
+
+`
+	if b.String() != want {
+		t.Errorf("VTT =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestBuildMaxLines(t *testing.T) {
+	var lines []Line
+	// Out of order, the way events of several sources may arrive
+	for _, i := range []int{3, 0, 4, 1, 2} {
+		lines = append(lines, at(time.Duration(i)*time.Second, string(rune('a'+i))))
+	}
+	cues := Build(lines, start, Options{CueDuration: 10 * time.Second, MaxLines: 2})
+
+	want := []Cue{
+		{0, 1 * time.Second, []string{"a"}},
+		{1 * time.Second, 2 * time.Second, []string{"a", "b"}},
+		{2 * time.Second, 3 * time.Second, []string{"b", "c"}},
+		{3 * time.Second, 4 * time.Second, []string{"c", "d"}},
+		{4 * time.Second, 13 * time.Second, []string{"d", "e"}},
+		{13 * time.Second, 14 * time.Second, []string{"e"}},
+	}
+	if len(cues) != len(want) {
+		t.Fatalf("cues = %v, want %v", cues, want)
+	}
+	for i := range want {
+		if cues[i].Start != want[i].Start || cues[i].End != want[i].End || !equal(cues[i].Lines, want[i].Lines) {
+			t.Errorf("cue %d = %v, want %v", i+1, cues[i], want[i])
+		}
+	}
+}
+
+func TestBuildBeforeRecording(t *testing.T) {
+	// Lines that left the screen before the recording started are dropped
+	cues := Build([]Line{at(0, "early"), at(10*time.Second, "late")}, start, Options{Offset: -5 * time.Second})
+	if len(cues) != 1 || cues[0].Start != 5*time.Second || cues[0].Lines[0] != "late" {
+		t.Errorf("cues = %v", cues)
+	}
+}
@@ -0,0 +1,232 @@
+package theme
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// Theme is the set of colors used by the TUI and the console log output.
+// Colors are hex values ("#FF5FAF") or ANSI color numbers ("205") and are
+// converted to the terminal's color profile when rendered. Empty colors use
+// the terminal's default.
+type Theme struct {
+	// Base is the built-in theme a user theme extends; its empty colors are
+	// taken from the base
+	Base string `json:"base,omitempty"`
+
+	Text    string `json:"text,omitempty"`
+	Muted   string `json:"muted,omitempty"`
+	Title   string `json:"title,omitempty"`
+	Accent  string `json:"accent,omitempty"`
+	Border  string `json:"border,omitempty"`
+	Focus   string `json:"focus,omitempty"`
+	Info    string `json:"info,omitempty"`
+	Success string `json:"success,omitempty"`
+	Warning string `json:"warning,omitempty"`
+	Error   string `json:"error,omitempty"`
+
+	// Highlights for flagged messages and search matches
+	FlaggedText       string `json:"flagged_text,omitempty"`
+	FlaggedBackground string `json:"flagged_background,omitempty"`
+	MatchText         string `json:"match_text,omitempty"`
+	MatchBackground   string `json:"match_background,omitempty"`
+
+	// Event type colors
+	Chat   string `json:"chat,omitempty"`
+	Gift   string `json:"gift,omitempty"`
+	Like   string `json:"like,omitempty"`
+	Follow string `json:"follow,omitempty"`
+	Share  string `json:"share,omitempty"`
+	Stats  string `json:"stats,omitempty"`
+
+	// Monochrome themes use no colors and mark highlights with text
+	// attributes and symbols instead
+	Monochrome bool `json:"monochrome,omitempty"`
+}
+
+// Names of the built-in themes. Auto picks dark or light from the terminal
+// background.
+const (
+	Auto         = "auto"
+	Dark         = "dark"
+	Light        = "light"
+	HighContrast = "high-contrast"
+	Mono         = "mono"
+)
+
+var builtin = map[string]Theme{
+	Dark: {
+		Text:              "#FAFAFA",
+		Muted:             "#A7A7A7",
+		Title:             "#FAFAFA",
+		Accent:            "205",
+		Border:            "62",
+		Focus:             "205",
+		Info:              "#00FFFF",
+		Success:           "#00FF00",
+		Warning:           "#FFA500",
+		Error:             "#FF0000",
+		FlaggedText:       "#FFFFFF",
+		FlaggedBackground: "#AF0000",
+		MatchText:         "#000000",
+		MatchBackground:   "#FFD700",
+		Chat:              "#FAFAFA",
+		Gift:              "#FFD700",
+		Like:              "#FF5FAF",
+		Follow:            "#5FD75F",
+		Share:             "#5FAFFF",
+		Stats:             "#8A8A8A",
+	},
+	Light: {
+		Text:              "#1C1C1C",
+		Muted:             "#6C6C6C",
+		Title:             "#000000",
+		Accent:            "#AF005F",
+		Border:            "#5F5FAF",
+		Focus:             "#AF005F",
+		Info:              "#005F87",
+		Success:           "#008700",
+		Warning:           "#AF5F00",
+		Error:             "#D70000",
+		FlaggedText:       "#FFFFFF",
+		FlaggedBackground: "#D70000",
+		MatchText:         "#000000",
+		MatchBackground:   "#FFD75F",
+		Chat:              "#1C1C1C",
+		Gift:              "#AF8700",
+		Like:              "#D7005F",
+		Follow:            "#008700",
+		Share:             "#005FAF",
+		Stats:             "#6C6C6C",
+	},
+	HighContrast: {
+		Text:              "#FFFFFF",
+		Muted:             "#D0D0D0",
+		Title:             "#FFFFFF",
+		Accent:            "#FFFF00",
+		Border:            "#FFFFFF",
+		Focus:             "#FFFF00",
+		Info:              "#00FFFF",
+		Success:           "#00FF00",
+		Warning:           "#FFFF00",
+		Error:             "#FF5F5F",
+		FlaggedText:       "#FFFFFF",
+		FlaggedBackground: "#D70000",
+		MatchText:         "#000000",
+		MatchBackground:   "#FFFF00",
+		Chat:              "#FFFFFF",
+		Gift:              "#FFFF00",
+		Like:              "#FF87FF",
+		Follow:            "#00FF00",
+		Share:             "#00FFFF",
+		Stats:             "#D0D0D0",
+	},
+	Mono: {
+		Monochrome: true,
+	},
+}
+
+// Builtin returns the names of the built-in themes
+func Builtin() []string {
+	names := make([]string, 0, len(builtin))
+	for name := range builtin {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve returns the theme with the given name, looking at user themes
+// before built-in ones. NO_COLOR and terminals without color support always
+// get the monochrome theme.
+func Resolve(name string, custom map[string]Theme) (Theme, error) {
+	t, err := resolve(name, custom, 0)
+	if err != nil {
+		return Theme{}, err
+	}
+	if lipgloss.ColorProfile() == termenv.Ascii {
+		return builtin[Mono], nil
+	}
+	return t, nil
+}
+
+func resolve(name string, custom map[string]Theme, depth int) (Theme, error) {
+	if depth > len(custom) {
+		return Theme{}, fmt.Errorf("theme %q extends itself", name)
+	}
+	if name == "" || name == Auto {
+		name = Light
+		if lipgloss.HasDarkBackground() {
+			name = Dark
+		}
+	}
+
+	if t, ok := custom[name]; ok {
+		if t.Monochrome {
+			return t, nil
+		}
+		base, err := resolve(t.Base, custom, depth+1)
+		if err != nil {
+			return Theme{}, err
+		}
+		return t.extend(base), nil
+	}
+	if t, ok := builtin[name]; ok {
+		return t, nil
+	}
+	return Theme{}, fmt.Errorf("unknown theme %q, built-in themes are %s, %s",
+		name, Auto, strings.Join(Builtin(), ", "))
+}
+
+// extend fills empty colors from base
+func (t Theme) extend(base Theme) Theme {
+	fields := []struct {
+		dst *string
+		src string
+	}{
+		{&t.Text, base.Text},
+		{&t.Muted, base.Muted},
+		{&t.Title, base.Title},
+		{&t.Accent, base.Accent},
+		{&t.Border, base.Border},
+		{&t.Focus, base.Focus},
+		{&t.Info, base.Info},
+		{&t.Success, base.Success},
+		{&t.Warning, base.Warning},
+		{&t.Error, base.Error},
+		{&t.FlaggedText, base.FlaggedText},
+		{&t.FlaggedBackground, base.FlaggedBackground},
+		{&t.MatchText, base.MatchText},
+		{&t.MatchBackground, base.MatchBackground},
+		{&t.Chat, base.Chat},
+		{&t.Gift, base.Gift},
+		{&t.Like, base.Like},
+		{&t.Follow, base.Follow},
+		{&t.Share, base.Share},
+		{&t.Stats, base.Stats},
+	}
+	for _, f := range fields {
+		if *f.dst == "" {
+			*f.dst = f.src
+		}
+	}
+	t.Monochrome = base.Monochrome
+	return t
+}
+
+// Default returns the dark theme, used until a configured theme is applied
+func Default() Theme {
+	return builtin[Dark]
+}
+
+// Color converts a theme color for use in lipgloss styles
+func Color(c string) lipgloss.TerminalColor {
+	if c == "" {
+		return lipgloss.NoColor{}
+	}
+	return lipgloss.Color(c)
+}
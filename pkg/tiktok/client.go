@@ -2,9 +2,12 @@ package tiktok
 
 import (
 	"fmt"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"tiktok-live-logger/pkg/logger"
+	"tiktok-live-logger/pkg/metrics"
 
 	"github.com/Davincible/gotiktoklive"
 	"github.com/pkg/errors"
@@ -13,18 +16,71 @@ import (
 type Client struct {
 	tiktok *gotiktoklive.TikTok
 	logger *logger.Logger
+	live   *gotiktoklive.Live
+	// username is read by the library's warn handler, which runs on the
+	// websocket goroutine
+	username atomic.Pointer[string]
+	// scrub is applied to events before their content is logged
+	scrub func(Event) Event
 }
 
-func NewClient(debugMode bool) (*Client, error) {
-	logger, err := logger.NewLogger(debugMode)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create logger: %w", err)
+// NewClient creates a client that reports through log. The library's own
+// handlers are redirected to log as well, since they print to stdout by default.
+func NewClient(log *logger.Logger) (*Client, error) {
+	if log == nil {
+		return nil, fmt.Errorf("logger is required")
 	}
 
-	return &Client{
+	c := &Client{
 		tiktok: gotiktoklive.NewTikTok(),
-		logger: logger,
-	}, nil
+		logger: log,
+	}
+
+	c.tiktok.SetInfoHandler(func(args ...interface{}) {
+		c.logger.Info(fmt.Sprint(args...), "source", "gotiktoklive")
+	})
+	c.tiktok.SetDebugHandler(func(args ...interface{}) {
+		c.logger.Debug(fmt.Sprint(args...), "source", "gotiktoklive")
+	})
+	c.tiktok.SetErrorHandler(func(args ...interface{}) {
+		c.logger.Error(fmt.Sprint(args...), "source", "gotiktoklive")
+	})
+	// The library has no reconnect callback, it reports each attempt
+	// through its warn handler
+	c.tiktok.SetWarnHandler(func(args ...interface{}) {
+		msg := fmt.Sprint(args...)
+		if username := c.username.Load(); username != nil && isReconnect(msg) {
+			metrics.Reconnects.WithLabelValues(*username).Inc()
+		}
+		c.logger.Warn(msg, "source", "gotiktoklive")
+	})
+
+	return c, nil
+}
+
+// The warnings gotiktoklive gives right before reconnecting its websocket
+const (
+	readFailedReconnect     = "Failed to read websocket message, attempting to reconnect: "
+	closedByServerReconnect = "Websocket connection was closed by server, attempting to reconnect..."
+)
+
+// isReconnect reports whether a library warning announces a reconnect attempt
+func isReconnect(msg string) bool {
+	return msg == closedByServerReconnect || strings.HasPrefix(msg, readFailedReconnect)
+}
+
+// SetScrub makes the client log the content of events as returned by scrub,
+// so log files don't hold what privacy mode keeps out of storage
+func (c *Client) SetScrub(scrub func(Event) Event) {
+	c.scrub = scrub
+}
+
+// logContent returns the content of event as it may be logged
+func (c *Client) logContent(event Event) string {
+	if c.scrub != nil {
+		event = c.scrub(event)
+	}
+	return event.Content
 }
 
 type LiveStats struct {
@@ -38,21 +94,62 @@ type Event struct {
 	Type      string
 	Content   string
 	Timestamp time.Time
+	UserID    int64
+	Nickname  string
+	// Message is the raw comment text of chat events
+	Message string
+	// Value is the diamonds a gift event adds (counting each gift of a streak
+	// once), the like count for likes
+	// and the viewer count for stats events
+	Value int64
+	// Flags holds moderation reasons added by pipeline processors
+	Flags []string
+	// Scored is set by the sentiment processor on chat events
+	Scored    bool
+	Sentiment float64
+	Toxicity  float64
+	// Language is the ISO 639-1 code set by the language processor
+	Language string
 }
 
 type EventHandler func(Event)
 
 func (c *Client) TrackUser(username string, onEvent EventHandler) error {
-	c.logger.Info("Starting to track user: %s", username)
+	log := c.logger.With("streamer", username)
+	log.Info("Starting to track user")
+	c.username.Store(&username)
+	metrics.SetTrackerState(username, metrics.StateConnecting)
 
 	live, err := c.tiktok.TrackUser(username)
 	if err != nil {
-		c.logger.ErrorWithStack(err, "Failed to track user: %s", username)
+		metrics.SetTrackerState(username, metrics.StateError)
+		log.ErrorWithStack(err, "Failed to track user")
 		return errors.Wrap(err, "failed to track user")
 	}
+	c.live = live
+	metrics.SetTrackerState(username, metrics.StateLive)
+
+	// Count every event before handing it to the caller
+	emit := func(event Event) {
+		metrics.EventsReceived.WithLabelValues(username, event.Type).Inc()
+		switch event.Type {
+		case "gift":
+			metrics.GiftDiamonds.WithLabelValues(username).Add(float64(event.Value))
+		case "stats":
+			metrics.Viewers.WithLabelValues(username).Set(float64(event.Value))
+		}
+		onEvent(event)
+	}
 
 	// Handle events from the channel
 	go func() {
+		defer func() {
+			metrics.SetTrackerState(username, metrics.StateEnded)
+			log.Info("Live stream ended")
+		}()
+
+		streaks := giftStreaks{}
+
 		for event := range live.Events {
 			switch e := event.(type) {
 			case gotiktoklive.ChatEvent:
@@ -60,27 +157,36 @@ func (c *Client) TrackUser(username string, onEvent EventHandler) error {
 					Type:      "chat",
 					Content:   fmt.Sprintf("%s: %s", e.User.Nickname, e.Comment),
 					Timestamp: time.Unix(e.Timestamp, 0),
+					UserID:    e.User.ID,
+					Nickname:  e.User.Nickname,
+					Message:   e.Comment,
 				}
-				c.logger.Debug("Chat message: %s", event.Content)
-				onEvent(event)
+				log.Debug("Chat message", "content", c.logContent(event))
+				emit(event)
 
 			case gotiktoklive.GiftEvent:
 				event := Event{
 					Type:      "gift",
 					Content:   fmt.Sprintf("%s sent %s (x%d)", e.User.Nickname, e.Name, e.RepeatCount),
 					Timestamp: time.Unix(e.Timestamp, 0),
+					UserID:    e.User.ID,
+					Nickname:  e.User.Nickname,
+					Value:     int64(e.Cost) * int64(streaks.count(e)),
 				}
-				c.logger.Info("Gift received: %s", event.Content)
-				onEvent(event)
+				log.Info("Gift received", "content", c.logContent(event), "diamonds", event.Value)
+				emit(event)
 
 			case gotiktoklive.LikeEvent:
 				event := Event{
 					Type:      "like",
 					Content:   fmt.Sprintf("%s sent %d likes", e.User.Nickname, e.Likes),
 					Timestamp: time.Now(),
+					UserID:    e.User.ID,
+					Nickname:  e.User.Nickname,
+					Value:     int64(e.Likes),
 				}
-				c.logger.Debug("Likes received: %s", event.Content)
-				onEvent(event)
+				log.Debug("Likes received", "content", c.logContent(event))
+				emit(event)
 
 			case gotiktoklive.UserEvent:
 				switch e.Event {
@@ -89,18 +195,22 @@ func (c *Client) TrackUser(username string, onEvent EventHandler) error {
 						Type:      "follow",
 						Content:   fmt.Sprintf("%s followed the streamer", e.User.Nickname),
 						Timestamp: time.Now(),
+						UserID:    e.User.ID,
+						Nickname:  e.User.Nickname,
 					}
-					c.logger.Info("New follower: %s", event.Content)
-					onEvent(event)
+					log.Info("New follower", "content", c.logContent(event))
+					emit(event)
 
 				case gotiktoklive.USER_SHARE:
 					event := Event{
 						Type:      "share",
 						Content:   fmt.Sprintf("%s shared the stream", e.User.Nickname),
 						Timestamp: time.Now(),
+						UserID:    e.User.ID,
+						Nickname:  e.User.Nickname,
 					}
-					c.logger.Info("Stream shared: %s", event.Content)
-					onEvent(event)
+					log.Info("Stream shared", "content", c.logContent(event))
+					emit(event)
 				}
 
 			case gotiktoklive.ViewersEvent:
@@ -108,24 +218,26 @@ func (c *Client) TrackUser(username string, onEvent EventHandler) error {
 					Type:      "stats",
 					Content:   fmt.Sprintf("Viewer count: %d", e.Viewers),
 					Timestamp: time.Now(),
+					Value:     int64(e.Viewers),
 				}
-				c.logger.Debug("Room stats updated: %s", event.Content)
-				onEvent(event)
+				log.Debug("Room stats updated", "viewers", event.Value)
+				emit(event)
 			}
 		}
 	}()
 
-	c.logger.Info("Successfully started tracking user: %s", username)
+	log.Info("Successfully started tracking user")
 	return nil
 }
 
 func (c *Client) GetLiveStats(username string) (*LiveStats, error) {
-	c.logger.Info("Getting live stats for user: %s", username)
+	log := c.logger.With("streamer", username)
+	log.Info("Getting live stats")
 
 	// Get room info directly from TikTok instance
 	roomInfo, err := c.tiktok.GetRoomInfo(username)
 	if err != nil {
-		c.logger.ErrorWithStack(err, "Failed to get room info for user: %s", username)
+		log.ErrorWithStack(err, "Failed to get room info")
 		return nil, errors.Wrap(err, "failed to get room info")
 	}
 
@@ -136,15 +248,47 @@ func (c *Client) GetLiveStats(username string) (*LiveStats, error) {
 		CommentCount: int64(roomInfo.Stats.DiggCount),
 	}
 
-	c.logger.Debug("Live stats for %s: viewers=%d, likes=%d, shares=%d, comments=%d",
-		username, stats.ViewerCount, stats.LikeCount, stats.ShareCount, stats.CommentCount)
+	log.Debug("Live stats",
+		"viewers", stats.ViewerCount,
+		"likes", stats.LikeCount,
+		"shares", stats.ShareCount,
+		"comments", stats.CommentCount)
 
 	return stats, nil
 }
 
+// LiveStatus tells whether a user is live right now
+type LiveStatus struct {
+	Live    bool
+	Title   string
+	Viewers int64
+}
+
+// CheckLive looks up a user's live room without connecting to it. Offline
+// users are not an error.
+func (c *Client) CheckLive(username string) (*LiveStatus, error) {
+	log := c.logger.With("streamer", username)
+	log.Debug("Checking live status")
+
+	roomInfo, err := c.tiktok.GetRoomInfo(username)
+	if errors.Is(err, gotiktoklive.ErrUserOffline) || errors.Is(err, gotiktoklive.ErrLiveHasEnded) {
+		return &LiveStatus{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to check live status")
+	}
+
+	return &LiveStatus{
+		Live:    true,
+		Title:   roomInfo.Title,
+		Viewers: int64(roomInfo.UserCount),
+	}, nil
+}
+
+// Close stops tracking. The logger is owned by the caller and left open.
 func (c *Client) Close() error {
-	if c.logger != nil {
-		return c.logger.Close()
+	if c.live != nil {
+		c.live.Close()
 	}
 	return nil
-} 
\ No newline at end of file
+}
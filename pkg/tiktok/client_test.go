@@ -0,0 +1,25 @@
+package tiktok
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestIsReconnect(t *testing.T) {
+	eof := errors.New("unexpected EOF")
+	tests := []struct {
+		msg  string
+		want bool
+	}{
+		{fmt.Sprint(fmt.Errorf("Failed to read websocket message, attempting to reconnect: %w", eof)), true},
+		{"Websocket connection was closed by server, attempting to reconnect...", true},
+		{fmt.Sprint(fmt.Errorf("Failed to re-open websocket connection: %w", eof)), false},
+		{"Message type not implemented: reconnect", false},
+	}
+	for _, tt := range tests {
+		if got := isReconnect(tt.msg); got != tt.want {
+			t.Errorf("isReconnect(%q) = %v, want %v", tt.msg, got, tt.want)
+		}
+	}
+}
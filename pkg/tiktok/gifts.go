@@ -0,0 +1,39 @@
+package tiktok
+
+import "github.com/Davincible/gotiktoklive"
+
+// streakableGift is the gift type TikTok sends again on every repeat of a
+// streak, with RepeatCount holding the running total
+const streakableGift = 1
+
+type streakKey struct {
+	user, gift int64
+}
+
+// giftStreaks remembers the repeat count last seen for each running streak,
+// so a streak of ten gifts is counted as ten and not 1+2+…+10
+type giftStreaks map[streakKey]int
+
+// count returns how many gifts e adds to what was already counted
+func (s giftStreaks) count(e gotiktoklive.GiftEvent) int {
+	if e.Type != streakableGift {
+		return e.RepeatCount
+	}
+	var key streakKey
+	if e.User != nil {
+		key.user = e.User.ID
+	}
+	key.gift = e.ID
+
+	n := e.RepeatCount - s[key]
+	if n < 0 {
+		// The previous streak ended without us seeing its last message
+		n = e.RepeatCount
+	}
+	if e.RepeatEnd {
+		delete(s, key)
+	} else {
+		s[key] = e.RepeatCount
+	}
+	return n
+}
@@ -0,0 +1,58 @@
+package tiktok
+
+import (
+	"testing"
+
+	"github.com/Davincible/gotiktoklive"
+)
+
+func TestGiftStreaks(t *testing.T) {
+	alice := &gotiktoklive.User{ID: 1}
+	bob := &gotiktoklive.User{ID: 2}
+	rose := func(user *gotiktoklive.User, repeat int, end bool) gotiktoklive.GiftEvent {
+		return gotiktoklive.GiftEvent{ID: 5655, Cost: 1, Type: streakableGift, User: user, RepeatCount: repeat, RepeatEnd: end}
+	}
+
+	streaks := giftStreaks{}
+	var events []gotiktoklive.GiftEvent
+	// alice sends a streak of ten, interleaved with a streak of three from bob
+	for i := 1; i <= 10; i++ {
+		events = append(events, rose(alice, i, false))
+		if i <= 3 {
+			events = append(events, rose(bob, i, false))
+		}
+	}
+	events = append(events,
+		rose(alice, 10, true),
+		rose(bob, 3, true),
+		// A second streak from alice starts from one again
+		rose(alice, 1, false),
+		rose(alice, 2, true),
+		// Gifts that can't be repeated count as sent
+		gotiktoklive.GiftEvent{ID: 1, Cost: 100, Type: 2, User: bob, RepeatCount: 1, RepeatEnd: true},
+	)
+
+	counts := map[int64]int{}
+	for _, e := range events {
+		counts[e.User.ID] += e.Cost * streaks.count(e)
+	}
+	if counts[1] != 12 || counts[2] != 103 {
+		t.Errorf("counted %d diamonds from alice and %d from bob, want 12 and 103", counts[1], counts[2])
+	}
+	if len(streaks) != 0 {
+		t.Errorf("%d streaks left running after all ended", len(streaks))
+	}
+}
+
+func TestGiftStreakMissedEnd(t *testing.T) {
+	streaks := giftStreaks{}
+	user := &gotiktoklive.User{ID: 1}
+	total := 0
+	// The end of the first streak is lost, then a new one starts
+	for _, repeat := range []int{1, 2, 3, 1, 2} {
+		total += streaks.count(gotiktoklive.GiftEvent{ID: 1, Type: streakableGift, User: user, RepeatCount: repeat})
+	}
+	if total != 5 {
+		t.Errorf("counted %d gifts, want 5", total)
+	}
+}
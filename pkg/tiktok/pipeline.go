@@ -0,0 +1,25 @@
+package tiktok
+
+// Processor inspects or annotates events on their way from TrackUser to the
+// EventHandler, e.g. to flag chat messages for moderation
+type Processor interface {
+	Process(event *Event)
+}
+
+// ProcessorFunc adapts a plain function to the Processor interface
+type ProcessorFunc func(event *Event)
+
+func (f ProcessorFunc) Process(event *Event) {
+	f(event)
+}
+
+// Pipeline returns an EventHandler that runs every processor in order before
+// passing the event on to handler
+func Pipeline(handler EventHandler, processors ...Processor) EventHandler {
+	return func(event Event) {
+		for _, p := range processors {
+			p.Process(&event)
+		}
+		handler(event)
+	}
+}
@@ -0,0 +1,85 @@
+package topics
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"tiktok-live-logger/pkg/database"
+)
+
+// ErrArchived is returned for archived sessions, whose chat is no longer in
+// the database to be counted
+var ErrArchived = errors.New("session is archived")
+
+// ForSession returns the counts of a session's chat. Cached counts are used
+// when they were computed from the same chat rows with the current tokenizer,
+// so sessions still being logged are recounted once new chat arrives.
+// Otherwise, or with refresh, the counts are computed and cached again.
+func ForSession(db *database.DB, sessionID int64, refresh bool) (*Counts, error) {
+	session, err := db.GetSession(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	// Counting an archived session would cache empty counts for it
+	if session.Archive != "" {
+		return nil, ErrArchived
+	}
+
+	messages, lastEventID, err := db.GetChatStamp(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count chat messages: %w", err)
+	}
+
+	if !refresh {
+		cache, err := db.GetTopicCache(sessionID)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+		case err != nil:
+			return nil, fmt.Errorf("failed to read topic cache: %w", err)
+		case cache.Version == Version && cache.Messages == messages && cache.LastEventID == lastEventID:
+			counts := NewCounts()
+			if err := json.Unmarshal(cache.Data, counts); err == nil {
+				return counts, nil
+			}
+			// A broken cache entry is recomputed below
+		}
+	}
+
+	events, err := db.GetChatEvents(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat events: %w", err)
+	}
+	counts := NewCounts()
+	for _, event := range events {
+		counts.Add(event.Message())
+	}
+
+	data, err := json.Marshal(counts)
+	if err != nil {
+		return nil, err
+	}
+	err = db.SaveTopicCache(database.TopicCache{
+		SessionID:   sessionID,
+		Version:     Version,
+		Messages:    int64(len(events)),
+		LastEventID: lastID(events, lastEventID),
+		Data:        data,
+		CreatedAt:   time.Now(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save topic cache: %w", err)
+	}
+	return counts, nil
+}
+
+// lastID returns the highest event ID, which may be newer than the stamp
+// taken before the events were read
+func lastID(events []database.Event, stamp int64) int64 {
+	for _, event := range events {
+		stamp = max(stamp, event.ID)
+	}
+	return stamp
+}
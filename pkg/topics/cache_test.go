@@ -0,0 +1,88 @@
+package topics
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tiktok-live-logger/pkg/database"
+)
+
+func TestForSessionCache(t *testing.T) {
+	db, err := database.NewDB(filepath.Join(t.TempDir(), "events.db"))
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	defer db.Close()
+
+	start := time.Date(2026, 1, 31, 20, 0, 0, 0, time.UTC)
+	id, err := db.StartSession("streamer", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chat := func(message string) {
+		t.Helper()
+		_, err := db.SaveEvent(database.Event{SessionID: id, Username: "streamer", Type: "chat",
+			Content: "alice: " + message, Nickname: "alice", Timestamp: start})
+		if err != nil {
+			t.Fatalf("SaveEvent: %v", err)
+		}
+	}
+	count := func(refresh bool) *Counts {
+		t.Helper()
+		c, err := ForSession(db, id, refresh)
+		if err != nil {
+			t.Fatalf("ForSession: %v", err)
+		}
+		return c
+	}
+	// poison replaces the cached counts, keeping the stamp, so a read that
+	// uses the cache can be told apart from a recount
+	poison := func(version int) {
+		t.Helper()
+		cache, err := db.GetTopicCache(id)
+		if err != nil {
+			t.Fatalf("GetTopicCache: %v", err)
+		}
+		cache.Version, cache.Data = version, []byte(`{"messages":99}`)
+		if err := db.SaveTopicCache(*cache); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	chat("good game")
+	if c := count(false); c.Messages != 1 || c.Words["game"] != 1 {
+		t.Fatalf("counts = %+v", c)
+	}
+
+	poison(Version)
+	if c := count(false); c.Messages != 99 {
+		t.Errorf("unchanged chat was recounted: %d messages", c.Messages)
+	}
+	if c := count(true); c.Messages != 1 {
+		t.Errorf("refresh used the cache: %d messages", c.Messages)
+	}
+
+	poison(Version)
+	chat("another game")
+	if c := count(false); c.Messages != 2 || c.Words["game"] != 2 {
+		t.Errorf("new chat wasn't counted: %+v", c)
+	}
+
+	poison(Version - 1)
+	if c := count(false); c.Messages != 2 {
+		t.Errorf("a cache of another tokenizer version was used: %d messages", c.Messages)
+	}
+
+	if _, err := db.ArchiveSession(id, "archive.json"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ForSession(db, id, false); !errors.Is(err, ErrArchived) {
+		t.Errorf("ForSession on an archived session: %v, want ErrArchived", err)
+	}
+	if _, err := db.GetTopicCache(id); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("archived session has a topic cache (%v)", err)
+	}
+}
@@ -0,0 +1,51 @@
+package topics
+
+import "strings"
+
+// stopwordLists holds common function words of the languages seen most in
+// TikTok live chat, plus chat filler
+var stopwordLists = []string{
+	// English
+	`a an and are as at be been but by can could did do does doing for from had
+	has have he her here him his how i if in into is it its i'm im me my no not
+	of on or our out she so than that the their them then there these they this
+	to too up us was we were what when where which who why will with would you
+	your u ur just yes yeah ok okay oh all am get got dont don't it's that's`,
+	// Spanish
+	`a al como con de del el ella en era es esta este esto eso fue ha la las le
+	les lo los me mi muy no nos o para pero por que qué se si sí su sus te tu tú
+	un una uno y ya yo`,
+	// Portuguese
+	`ao as com da das de do dos e ela ele em era essa esse está eu foi isso já
+	mais mas me meu minha na nas no nos não o os ou para pra por que se sem seu
+	sua são tá também tem um uma você vc`,
+	// French
+	`au aux avec ce ces c'est dans de des du elle en est et il ils je la le les
+	leur lui ma mais me mes moi mon ne nous on ou par pas pour qu que qui sa se
+	ses son sur ta te tes toi ton tu un une vous y`,
+	// German
+	`aber als am an auch auf aus bei bin bist das dass dem den der des die du
+	ein eine einen er es für hat ich ihr im in ist ja mit mir nicht noch nur
+	sie sind so und von war was wie wir zu`,
+	// Indonesian and Malay
+	`ada aja aku akan apa atau dan dari di dia ini itu juga kak kamu ke mau
+	nya sama saya sudah udah untuk yang ya yg`,
+	// Turkish
+	`ama bir bu da de için ile mi ne o sen ve ben çok gibi`,
+}
+
+// stopwords are skipped when counting words and bigrams
+var stopwords = func() map[string]bool {
+	words := make(map[string]bool)
+	for _, list := range stopwordLists {
+		for _, word := range strings.Fields(list) {
+			words[word] = true
+		}
+	}
+	return words
+}()
+
+// IsStopword reports whether a lowercased word is too common to be a topic
+func IsStopword(word string) bool {
+	return stopwords[word]
+}
@@ -0,0 +1,207 @@
+package topics
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Kind is the kind of a token
+type Kind int
+
+const (
+	Word Kind = iota
+	Emoji
+	Mention
+	Hashtag
+)
+
+// Token is a normalized piece of a chat message
+type Token struct {
+	Kind Kind
+	Text string
+	// Joined marks words cut out of scripts written without spaces, such as
+	// Chinese, Japanese or Thai. They are character pairs rather than words.
+	Joined bool
+}
+
+var urlPattern = regexp.MustCompile(`(?i)(https?://|www\.)\S+`)
+
+// joinedScripts are written without spaces between words
+var joinedScripts = []*unicode.RangeTable{
+	unicode.Han, unicode.Hiragana, unicode.Katakana,
+	unicode.Thai, unicode.Lao, unicode.Khmer, unicode.Myanmar,
+}
+
+// Tokenize splits a chat message into lowercased words, emojis, mentions and
+// hashtags. Links and punctuation are dropped. Text in scripts without word
+// spaces is split into overlapping character pairs, since telling its words
+// apart needs a dictionary.
+func Tokenize(text string) []Token {
+	runes := []rune(urlPattern.ReplaceAllString(text, " "))
+	var tokens []Token
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case (r == '@' || r == '#' || r == '＠' || r == '＃') && i+1 < len(runes) && isNameRune(runes[i+1]) &&
+			(i == 0 || !isWordRune(runes[i-1])):
+			j := i + 1
+			for j < len(runes) && (isNameRune(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			name := strings.ToLower(strings.TrimRight(string(runes[i+1:j]), "."))
+			if r == '@' || r == '＠' {
+				tokens = append(tokens, Token{Kind: Mention, Text: "@" + name})
+			} else {
+				tokens = append(tokens, Token{Kind: Hashtag, Text: "#" + name})
+			}
+			i = j
+
+		case isEmoji(r):
+			j := emojiEnd(runes, i)
+			tokens = append(tokens, Token{Kind: Emoji, Text: strings.ReplaceAll(string(runes[i:j]), "\uFE0F", "")})
+			i = j
+
+		case isWordRune(r):
+			j := i + 1
+			for j < len(runes) {
+				// Keep apostrophes inside words, as in "don't"
+				if (runes[j] == '\'' || runes[j] == '’') && j+1 < len(runes) && unicode.IsLetter(runes[j+1]) {
+					j += 2
+					continue
+				}
+				if !isWordRune(runes[j]) {
+					break
+				}
+				j++
+			}
+			tokens = append(tokens, words(runes[i:j])...)
+			i = j
+
+		default:
+			i++
+		}
+	}
+	return tokens
+}
+
+// words normalizes a run of letters and digits, splitting it where it
+// switches between spaced and joined scripts
+func words(run []rune) []Token {
+	var tokens []Token
+	for len(run) > 0 {
+		joined := isJoined(run[0])
+		n := 1
+		for n < len(run) && isJoined(run[n]) == joined {
+			n++
+		}
+		part := run[:n]
+		run = run[n:]
+
+		if !joined {
+			word := squeeze(strings.ReplaceAll(strings.ToLower(string(part)), "’", "'"))
+			if len([]rune(word)) < 2 || isNumber(word) {
+				continue
+			}
+			tokens = append(tokens, Token{Kind: Word, Text: word})
+			continue
+		}
+
+		// Combining marks stay with the character they modify
+		var chars []string
+		for _, r := range part {
+			if unicode.Is(unicode.M, r) && len(chars) > 0 {
+				chars[len(chars)-1] += string(r)
+				continue
+			}
+			chars = append(chars, string(r))
+		}
+		if len(chars) == 1 {
+			tokens = append(tokens, Token{Kind: Word, Text: chars[0], Joined: true})
+		}
+		for k := 0; k+1 < len(chars); k++ {
+			tokens = append(tokens, Token{Kind: Word, Text: chars[k] + chars[k+1], Joined: true})
+		}
+	}
+	return tokens
+}
+
+// squeeze shortens letters repeated more than twice, so "soooo" and "sooo"
+// count as the same word
+func squeeze(word string) string {
+	var b strings.Builder
+	var last rune
+	repeats := 0
+	for _, r := range word {
+		if r == last {
+			repeats++
+		} else {
+			last, repeats = r, 1
+		}
+		if repeats <= 2 || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func isNumber(word string) bool {
+	for _, r := range word {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.M, r)
+}
+
+func isNameRune(r rune) bool {
+	return isWordRune(r) || r == '_'
+}
+
+func isJoined(r rune) bool {
+	return unicode.In(r, joinedScripts...)
+}
+
+// isEmoji reports whether r starts an emoji
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // pictographs, emoticons, flags
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // misc symbols and dingbats
+		return true
+	case r >= 0x2300 && r <= 0x23FF: // watch, hourglass, play buttons
+		return true
+	case r == 0x2B50 || r == 0x2B55 || (r >= 0x2B05 && r <= 0x2B1C) || r == 0x3030 || r == 0x303D:
+		return true
+	}
+	return false
+}
+
+// emojiEnd returns the end of the emoji sequence starting at i, including
+// skin tones, variation selectors, keycaps, tags, joined sequences such as
+// family emojis and flag pairs
+func emojiEnd(runes []rune, i int) int {
+	isFlag := func(r rune) bool { return r >= 0x1F1E6 && r <= 0x1F1FF }
+	if isFlag(runes[i]) && i+1 < len(runes) && isFlag(runes[i+1]) {
+		return i + 2
+	}
+
+	j := i + 1
+	for j < len(runes) {
+		r := runes[j]
+		switch {
+		case r == 0xFE0E || r == 0xFE0F, r >= 0x1F3FB && r <= 0x1F3FF, r == 0x20E3, r >= 0xE0020 && r <= 0xE007F:
+			j++
+		case r == 0x200D && j+1 < len(runes) && isEmoji(runes[j+1]):
+			j += 2
+		default:
+			return j
+		}
+	}
+	return j
+}
@@ -0,0 +1,46 @@
+package topics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	word := func(text string) Token { return Token{Kind: Word, Text: text} }
+	joined := func(text string) Token { return Token{Kind: Word, Text: text, Joined: true} }
+
+	tests := []struct {
+		name string
+		text string
+		want []Token
+	}{
+		{"words", "GG Everyone, don't leave!", []Token{word("gg"), word("everyone"), word("don't"), word("leave")}},
+		{"stretched letters", "sooooo goooood", []Token{word("soo"), word("good")}},
+		{"short words and numbers", "a 2025 x1 ok", []Token{word("x1"), word("ok")}},
+		{"links", "see https://example.com/a?b=1 and www.example.org now", []Token{word("see"), word("and"), word("now")}},
+		{"mentions and hashtags", "@Alice.B. #FYP hi", []Token{{Kind: Mention, Text: "@alice.b"}, {Kind: Hashtag, Text: "#fyp"}, word("hi")}},
+		{"email is not a mention", "me@example.com", []Token{word("me"), word("example"), word("com")}},
+		{"emojis", "🔥🔥 ❤️ wow", []Token{{Kind: Emoji, Text: "🔥"}, {Kind: Emoji, Text: "🔥"}, {Kind: Emoji, Text: "❤"}, word("wow")}},
+		{"chinese", "你好吗", []Token{joined("你好"), joined("好吗")}},
+		{"mixed scripts", "gg好", []Token{word("gg"), joined("好")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tokenize(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountsSkipStopwords(t *testing.T) {
+	c := NewCounts()
+	c.Add("good game")
+	c.Add("the game is over")
+	if c.Messages != 2 || c.Words["game"] != 2 || c.Words["the"] != 0 {
+		t.Errorf("words = %v after %d messages", c.Words, c.Messages)
+	}
+	if len(c.Bigrams) != 1 || c.Bigrams["good game"] != 1 {
+		t.Errorf("bigrams = %v, want only good game", c.Bigrams)
+	}
+}
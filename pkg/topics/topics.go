@@ -0,0 +1,132 @@
+package topics
+
+import (
+	"sort"
+)
+
+// Version changes whenever tokenizing changes, invalidating cached counts
+const Version = 1
+
+// Counts accumulates token frequencies over chat messages. It is stored as
+// JSON in the per-session cache, and counts of several sessions can be merged.
+type Counts struct {
+	Messages int64            `json:"messages"`
+	Words    map[string]int64 `json:"words"`
+	Bigrams  map[string]int64 `json:"bigrams"`
+	Emojis   map[string]int64 `json:"emojis"`
+	Mentions map[string]int64 `json:"mentions"`
+	Hashtags map[string]int64 `json:"hashtags"`
+}
+
+// NewCounts returns empty counts
+func NewCounts() *Counts {
+	return &Counts{
+		Words:    make(map[string]int64),
+		Bigrams:  make(map[string]int64),
+		Emojis:   make(map[string]int64),
+		Mentions: make(map[string]int64),
+		Hashtags: make(map[string]int64),
+	}
+}
+
+// Add counts the tokens of a chat message. Stopwords are skipped and break
+// bigrams, so "the game is over" yields no bigram but "good game" does.
+func (c *Counts) Add(message string) {
+	c.Messages++
+
+	var prev string
+	for _, token := range Tokenize(message) {
+		switch token.Kind {
+		case Emoji:
+			c.Emojis[token.Text]++
+		case Mention:
+			c.Mentions[token.Text]++
+		case Hashtag:
+			c.Hashtags[token.Text]++
+		case Word:
+			if IsStopword(token.Text) {
+				prev = ""
+				continue
+			}
+			c.Words[token.Text]++
+			// Joined words already are character pairs
+			if token.Joined {
+				prev = ""
+				continue
+			}
+			if prev != "" && prev != token.Text {
+				c.Bigrams[prev+" "+token.Text]++
+			}
+			prev = token.Text
+			continue
+		}
+		prev = ""
+	}
+}
+
+// Merge adds other's counts to c
+func (c *Counts) Merge(other *Counts) {
+	c.Messages += other.Messages
+	merge := func(dst, src map[string]int64) {
+		for label, count := range src {
+			dst[label] += count
+		}
+	}
+	merge(c.Words, other.Words)
+	merge(c.Bigrams, other.Bigrams)
+	merge(c.Emojis, other.Emojis)
+	merge(c.Mentions, other.Mentions)
+	merge(c.Hashtags, other.Hashtags)
+}
+
+// Count pairs a token with how often it occurred
+type Count struct {
+	Label string `json:"label"`
+	Count int64  `json:"count"`
+}
+
+// Summary holds the most frequent tokens of each kind
+type Summary struct {
+	Messages int64   `json:"messages"`
+	Words    []Count `json:"words"`
+	Bigrams  []Count `json:"bigrams"`
+	Emojis   []Count `json:"emojis"`
+	Mentions []Count `json:"mentions"`
+	Hashtags []Count `json:"hashtags"`
+}
+
+// Top returns the n most frequent tokens of each kind. Bigrams seen only
+// once are left out, as they are mostly noise.
+func (c *Counts) Top(n int) Summary {
+	bigrams := make(map[string]int64)
+	for label, count := range c.Bigrams {
+		if count > 1 {
+			bigrams[label] = count
+		}
+	}
+	return Summary{
+		Messages: c.Messages,
+		Words:    top(c.Words, n),
+		Bigrams:  top(bigrams, n),
+		Emojis:   top(c.Emojis, n),
+		Mentions: top(c.Mentions, n),
+		Hashtags: top(c.Hashtags, n),
+	}
+}
+
+func top(counts map[string]int64, n int) []Count {
+	result := make([]Count, 0, len(counts))
+	for label, count := range counts {
+		result = append(result, Count{Label: label, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Label < result[j].Label
+	})
+	if len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
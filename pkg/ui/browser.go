@@ -0,0 +1,512 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// StreamerItem is a row of the browser's streamer list
+type StreamerItem struct {
+	Username string
+	Sessions int
+	Events   int
+	Diamonds int64
+	LastSeen time.Time
+}
+
+// SessionItem is a row of the browser's session list
+type SessionItem struct {
+	ID          int64
+	Username    string
+	StartedAt   time.Time
+	Duration    time.Duration
+	Events      int
+	PeakViewers int64
+	Diamonds    int64
+	// Archived sessions have their events in an archive file
+	Archived bool
+}
+
+// BrowserSource provides the data shown by the history browser
+type BrowserSource interface {
+	Streamers() ([]StreamerItem, error)
+	Sessions(username string) ([]SessionItem, error)
+	Events(sessionID int64) ([]Event, error)
+}
+
+// playbackSpeeds are the selectable replay speed multipliers
+var playbackSpeeds = []int{1, 2, 5, 10, 30, 60}
+
+// maxPlaybackDelay shortens long silent stretches during replay
+const maxPlaybackDelay = 3 * time.Second
+
+type browserLevel int
+
+const (
+	levelStreamers browserLevel = iota
+	levelSessions
+	levelEvents
+	levelPlayback
+)
+
+type (
+	streamersMsg     []StreamerItem
+	sessionsMsg      []SessionItem
+	sessionEventsMsg struct {
+		events []Event
+		play   bool
+	}
+	browserErrorMsg error
+	playbackTickMsg struct{ gen int }
+)
+
+// browserKeyMap holds the browser's navigation keys. The session views reuse
+// the live view keys for filtering and search.
+type browserKeyMap struct {
+	Open   key.Binding
+	Play   key.Binding
+	Back   key.Binding
+	Toggle key.Binding
+	Faster key.Binding
+	Slower key.Binding
+	Quit   key.Binding
+}
+
+func newBrowserKeyMap() browserKeyMap {
+	return browserKeyMap{
+		Open:   key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "open")),
+		Play:   key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "play back")),
+		Back:   key.NewBinding(key.WithKeys("esc", "backspace"), key.WithHelp("esc", "back")),
+		Toggle: key.NewBinding(key.WithKeys(" "), key.WithHelp("space", "play/pause")),
+		Faster: key.NewBinding(key.WithKeys(">", "."), key.WithHelp(">/<", "faster/slower")),
+		Slower: key.NewBinding(key.WithKeys("<", ",")),
+		Quit:   key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "quit")),
+	}
+}
+
+type streamerListItem StreamerItem
+
+func (i streamerListItem) Title() string { return "@" + i.Username }
+
+func (i streamerListItem) Description() string {
+	return fmt.Sprintf("%d sessions · %d events · %d diamonds · last live %s",
+		i.Sessions, i.Events, i.Diamonds, i.LastSeen.Format("2006-01-02 15:04"))
+}
+
+func (i streamerListItem) FilterValue() string { return i.Username }
+
+// browser is a drill-down view of logged streams: streamers, their sessions,
+// and a session's events either as a filterable log or replayed live
+type browser struct {
+	source    BrowserSource
+	layout    Layout
+	level     browserLevel
+	streamers list.Model
+	sessions  table.Model
+	items     []SessionItem
+	username  string
+	session   SessionItem
+	view      model
+	help      help.Model
+	keys      browserKeyMap
+	width     int
+	height    int
+	loading   bool
+	err       error
+
+	// Playback state
+	events  []Event
+	next    int
+	speed   int
+	playing bool
+	gen     int
+}
+
+// NewBrowser returns the history browser program model. Replays use the
+// given dashboard layout. A non-empty username opens that streamer's
+// sessions directly.
+func NewBrowser(source BrowserSource, layout Layout, username string) tea.Model {
+	l := list.New(nil, list.NewDefaultDelegate(), 0, 0)
+	l.Title = "Logged Streamers"
+	l.Styles.Title = titleStyle
+	l.DisableQuitKeybindings()
+	keys := newBrowserKeyMap()
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{keys.Open, keys.Quit}
+	}
+
+	t := table.New(
+		table.WithColumns([]table.Column{
+			{Title: "ID", Width: 6},
+			{Title: "Started", Width: 16},
+			{Title: "Duration", Width: 10},
+			{Title: "Events", Width: 8},
+			{Title: "Peak viewers", Width: 12},
+			{Title: "Diamonds", Width: 10},
+			{Title: "", Width: 8},
+		}),
+		table.WithFocused(true),
+	)
+
+	b := &browser{
+		source:    source,
+		layout:    layout,
+		streamers: l,
+		sessions:  t,
+		help:      help.New(),
+		keys:      keys,
+		loading:   true,
+	}
+	if username != "" {
+		b.username = username
+		b.level = levelSessions
+	}
+	return b
+}
+
+func (b *browser) Init() tea.Cmd {
+	if b.level == levelSessions {
+		return tea.Batch(b.loadStreamers(), b.loadSessions(b.username))
+	}
+	return b.loadStreamers()
+}
+
+func (b *browser) loadStreamers() tea.Cmd {
+	source := b.source
+	return func() tea.Msg {
+		streamers, err := source.Streamers()
+		if err != nil {
+			return browserErrorMsg(fmt.Errorf("failed to load streamers: %w", err))
+		}
+		return streamersMsg(streamers)
+	}
+}
+
+func (b *browser) loadSessions(username string) tea.Cmd {
+	source := b.source
+	return func() tea.Msg {
+		sessions, err := source.Sessions(username)
+		if err != nil {
+			return browserErrorMsg(fmt.Errorf("failed to load sessions: %w", err))
+		}
+		return sessionsMsg(sessions)
+	}
+}
+
+func (b *browser) loadEvents(sessionID int64, play bool) tea.Cmd {
+	source := b.source
+	return func() tea.Msg {
+		events, err := source.Events(sessionID)
+		if err != nil {
+			return browserErrorMsg(fmt.Errorf("failed to load events: %w", err))
+		}
+		return sessionEventsMsg{events: events, play: play}
+	}
+}
+
+func (b *browser) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		b.width, b.height = msg.Width, msg.Height
+		b.resize()
+		return b, nil
+	case streamersMsg:
+		// Sessions opened directly are still loading
+		if b.level == levelStreamers {
+			b.loading = false
+		}
+		items := make([]list.Item, len(msg))
+		for i, s := range msg {
+			items[i] = streamerListItem(s)
+		}
+		return b, b.streamers.SetItems(items)
+	case sessionsMsg:
+		b.loading = false
+		b.items = msg
+		rows := make([]table.Row, len(msg))
+		for i, s := range msg {
+			rows[i] = table.Row{
+				fmt.Sprintf("%d", s.ID),
+				s.StartedAt.Format("2006-01-02 15:04"),
+				s.Duration.Round(time.Second).String(),
+				fmt.Sprintf("%d", s.Events),
+				fmt.Sprintf("%d", s.PeakViewers),
+				fmt.Sprintf("%d", s.Diamonds),
+				"",
+			}
+			if s.Archived {
+				rows[i][6] = "archived"
+			}
+		}
+		b.sessions.SetRows(rows)
+		b.sessions.SetCursor(0)
+		return b, nil
+	case sessionEventsMsg:
+		b.loading = false
+		if msg.play {
+			return b, b.startPlayback(msg.events)
+		}
+		b.openLog(msg.events)
+		return b, nil
+	case browserErrorMsg:
+		b.loading = false
+		b.err = error(msg)
+		return b, nil
+	case playbackTickMsg:
+		if msg.gen != b.gen || !b.playing || b.level != levelPlayback {
+			return b, nil
+		}
+		b.view.addEvent(b.events[b.next])
+		b.next++
+		return b, b.scheduleNext()
+	case tea.KeyMsg:
+		if msg.Type == tea.KeyCtrlC {
+			return b, tea.Quit
+		}
+		if b.err != nil {
+			// Any key dismisses an error
+			b.err = nil
+			return b, nil
+		}
+		return b.updateKeys(msg)
+	}
+
+	// Let the list finish filtering
+	if b.level == levelStreamers {
+		var cmd tea.Cmd
+		b.streamers, cmd = b.streamers.Update(msg)
+		return b, cmd
+	}
+	return b, nil
+}
+
+func (b *browser) updateKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch b.level {
+	case levelStreamers:
+		filtering := b.streamers.FilterState() == list.Filtering
+		switch {
+		case filtering:
+		case key.Matches(msg, b.keys.Quit):
+			return b, tea.Quit
+		case key.Matches(msg, b.keys.Back) && b.streamers.FilterState() == list.Unfiltered:
+			return b, tea.Quit
+		case key.Matches(msg, b.keys.Open):
+			item, ok := b.streamers.SelectedItem().(streamerListItem)
+			if !ok {
+				return b, nil
+			}
+			b.username = item.Username
+			b.level = levelSessions
+			b.loading = true
+			b.sessions.SetRows(nil)
+			return b, b.loadSessions(item.Username)
+		}
+		var cmd tea.Cmd
+		b.streamers, cmd = b.streamers.Update(msg)
+		return b, cmd
+
+	case levelSessions:
+		switch {
+		case key.Matches(msg, b.keys.Quit):
+			return b, tea.Quit
+		case key.Matches(msg, b.keys.Back):
+			b.level = levelStreamers
+			return b, nil
+		case key.Matches(msg, b.keys.Open), key.Matches(msg, b.keys.Play):
+			if len(b.items) == 0 || b.loading {
+				return b, nil
+			}
+			b.session = b.items[b.sessions.Cursor()]
+			b.loading = true
+			return b, b.loadEvents(b.session.ID, key.Matches(msg, b.keys.Play))
+		}
+		var cmd tea.Cmd
+		b.sessions, cmd = b.sessions.Update(msg)
+		return b, cmd
+
+	case levelEvents, levelPlayback:
+		// Leave the session with the live view's quit keys, unless they
+		// belong to the search prompt
+		if !b.view.searching && (key.Matches(msg, b.view.keys.Quit) || key.Matches(msg, b.keys.Back)) {
+			b.level = levelSessions
+			b.playing = false
+			b.events = nil
+			return b, nil
+		}
+		if b.level == levelPlayback && !b.view.searching {
+			switch {
+			case key.Matches(msg, b.keys.Toggle):
+				if b.next >= len(b.events) {
+					return b, nil
+				}
+				b.playing = !b.playing
+				b.gen++
+				return b, b.scheduleNext()
+			case key.Matches(msg, b.keys.Faster):
+				b.setSpeed(b.speed + 1)
+				return b, b.scheduleNext()
+			case key.Matches(msg, b.keys.Slower):
+				b.setSpeed(b.speed - 1)
+				return b, b.scheduleNext()
+			}
+		}
+		v, cmd := b.view.Update(msg)
+		b.view = v.(model)
+		return b, cmd
+	}
+	return b, nil
+}
+
+// sessionView builds a dashboard for the selected session
+func (b *browser) sessionView(title string) model {
+	m := NewModel(b.session.Username)
+	m.SetTitle(title)
+	m.SetLayout(b.layout)
+	m.keys.Quit.SetHelp("q/esc", "back")
+	return m
+}
+
+// openLog shows all events of the session as a scrollable, filterable log
+func (b *browser) openLog(events []Event) {
+	b.view = b.sessionView(fmt.Sprintf("Session #%d: @%s, %s", b.session.ID, b.session.Username,
+		b.session.StartedAt.Format("2006-01-02 15:04")))
+	b.view.SetBufferSize(len(events))
+	b.view.focus = paneChat
+	b.view.zoomed = true
+	b.view.setPaused(true)
+	for _, e := range events {
+		b.view.addEvent(e)
+	}
+	b.level = levelEvents
+	b.resize()
+}
+
+// startPlayback replays the session's events into a fresh dashboard
+func (b *browser) startPlayback(events []Event) tea.Cmd {
+	b.view = b.sessionView(fmt.Sprintf("Replay: @%s, session #%d", b.session.Username, b.session.ID))
+	b.view.SetBufferSize(max(len(events), DefaultBufferSize))
+	b.events = events
+	b.next = 0
+	b.playing = true
+	b.gen++
+	b.level = levelPlayback
+	b.resize()
+	return b.scheduleNext()
+}
+
+func (b *browser) setSpeed(speed int) {
+	b.speed = min(max(speed, 0), len(playbackSpeeds)-1)
+	b.gen++
+}
+
+// scheduleNext waits for the gap before the next event, scaled by the speed
+func (b *browser) scheduleNext() tea.Cmd {
+	if !b.playing {
+		return nil
+	}
+	if b.next >= len(b.events) {
+		b.playing = false
+		return nil
+	}
+
+	var delay time.Duration
+	if b.next > 0 {
+		delay = b.events[b.next].Timestamp.Sub(b.events[b.next-1].Timestamp) /
+			time.Duration(playbackSpeeds[b.speed])
+	}
+	delay = min(max(delay, 0), maxPlaybackDelay)
+
+	gen := b.gen
+	return tea.Tick(delay, func(time.Time) tea.Msg {
+		return playbackTickMsg{gen: gen}
+	})
+}
+
+func (b *browser) resize() {
+	if b.width == 0 {
+		return
+	}
+	b.help.Width = b.width
+	b.streamers.SetSize(b.width, b.height)
+	b.sessions.SetWidth(b.width)
+	b.sessions.SetHeight(b.height - 3) // title and help line
+	if b.level == levelEvents || b.level == levelPlayback {
+		height := b.height
+		if b.level == levelPlayback {
+			height-- // playback bar
+		}
+		v, _ := b.view.Update(tea.WindowSizeMsg{Width: b.width, Height: height})
+		b.view = v.(model)
+	}
+}
+
+func (b *browser) View() string {
+	if b.err != nil {
+		return errorStyle.Render(fmt.Sprintf("Error: %v", b.err)) + "\n" +
+			infoStyle.Render("Press any key to continue")
+	}
+
+	switch b.level {
+	case levelStreamers:
+		if b.loading {
+			return infoStyle.Render("Loading streamers...")
+		}
+		if len(b.streamers.Items()) == 0 {
+			return infoStyle.Render("No logged streams yet") + "\n" + b.helpView(b.keys.Quit)
+		}
+		return b.streamers.View()
+	case levelSessions:
+		title := titleStyle.Render(fmt.Sprintf("Sessions: @%s", b.username))
+		if b.loading {
+			return title + "\n\n" + infoStyle.Render("Loading...")
+		}
+		return title + "\n\n" + b.sessions.View() + "\n" +
+			b.helpView(b.keys.Open, b.keys.Play, b.keys.Back, b.keys.Quit)
+	case levelEvents:
+		return b.view.View()
+	case levelPlayback:
+		return b.view.View() + "\n" + b.playbackBar()
+	}
+	return ""
+}
+
+func (b *browser) helpView(bindings ...key.Binding) string {
+	return b.help.ShortHelpView(bindings)
+}
+
+// playbackBar shows the replay position, speed and playback keys
+func (b *browser) playbackBar() string {
+	state := "▶"
+	switch {
+	case b.next >= len(b.events):
+		state = "■ finished"
+	case !b.playing:
+		state = "⏸"
+	}
+
+	var position time.Duration
+	if b.next > 0 {
+		position = b.events[b.next-1].Timestamp.Sub(b.session.StartedAt)
+	}
+	status := fmt.Sprintf("%s %dx  %s / %s  %d/%d events", state, playbackSpeeds[b.speed],
+		formatClock(position), formatClock(b.session.Duration), b.next, len(b.events))
+	return lipgloss.JoinHorizontal(lipgloss.Top,
+		successStyle.Render(status), "  ", b.helpView(b.keys.Toggle, b.keys.Faster))
+}
+
+// formatClock formats a duration as h:mm:ss
+func formatClock(d time.Duration) string {
+	d = max(d, 0).Round(time.Second)
+	h := int(d.Hours())
+	m := int(d.Minutes()) % 60
+	s := int(d.Seconds()) % 60
+	return strings.TrimPrefix(fmt.Sprintf("%d:%02d:%02d", h, m, s), "0:")
+}
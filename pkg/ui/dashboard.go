@@ -0,0 +1,293 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// pane identifies one of the dashboard panes
+type pane int
+
+const (
+	paneChat pane = iota
+	paneFeed
+	paneStats
+	paneLeaderboard
+	paneCount
+)
+
+var paneTitles = [paneCount]string{"Chat", "Gifts & Follows", "Stats", "Top Gifters"}
+
+// Layout is the user-adjustable dashboard layout. It is persisted in the
+// config file between runs.
+type Layout struct {
+	// ChatWidth is the chat pane's share of the terminal width in percent
+	ChatWidth int `json:"chat_width"`
+	// FeedHeight and StatsHeight are the feed and stats panes' share of the
+	// side column in percent; the leaderboard gets the rest
+	FeedHeight  int `json:"feed_height"`
+	StatsHeight int `json:"stats_height"`
+	// Focus is the pane focused on start: chat, feed, stats or leaderboard
+	Focus string `json:"focus"`
+}
+
+// DefaultLayout returns the layout used when none is configured
+func DefaultLayout() Layout {
+	return Layout{
+		ChatWidth:   60,
+		FeedHeight:  40,
+		StatsHeight: 30,
+		Focus:       "chat",
+	}
+}
+
+var paneNames = [paneCount]string{"chat", "feed", "stats", "leaderboard"}
+
+func (l Layout) focus() pane {
+	for i, name := range paneNames {
+		if name == l.Focus {
+			return pane(i)
+		}
+	}
+	return paneChat
+}
+
+// normalize clamps the layout to usable proportions
+func (l Layout) normalize() Layout {
+	d := DefaultLayout()
+	if l.ChatWidth == 0 && l.FeedHeight == 0 && l.StatsHeight == 0 {
+		l.ChatWidth, l.FeedHeight, l.StatsHeight = d.ChatWidth, d.FeedHeight, d.StatsHeight
+	}
+	l.ChatWidth = clamp(l.ChatWidth, 30, 80)
+	l.FeedHeight = clamp(l.FeedHeight, 15, 70)
+	l.StatsHeight = clamp(l.StatsHeight, 15, 70)
+	if l.FeedHeight+l.StatsHeight > 85 {
+		l.StatsHeight = 85 - l.FeedHeight
+	}
+	return l
+}
+
+func clamp(v, low, high int) int {
+	if v < low {
+		return low
+	}
+	if v > high {
+		return high
+	}
+	return v
+}
+
+// Terminals smaller than this show one pane at a time
+const (
+	minDashboardWidth  = 90
+	minDashboardHeight = 20
+)
+
+// rect is the outer size of a pane including its border
+type rect struct {
+	width, height int
+}
+
+// compact reports whether only the focused pane fits on screen
+func (m *model) compact() bool {
+	return m.zoomed || m.width < minDashboardWidth || m.height < minDashboardHeight
+}
+
+// layoutPanes computes the outer size of every pane for a body of the given size
+func (m *model) layoutPanes(width, height int) [paneCount]rect {
+	var rects [paneCount]rect
+	if m.compact() {
+		rects[m.focus] = rect{width, height}
+		return rects
+	}
+
+	chatWidth := width * m.layout.ChatWidth / 100
+	sideWidth := width - chatWidth
+	feedHeight := height * m.layout.FeedHeight / 100
+	statsHeight := height * m.layout.StatsHeight / 100
+
+	rects[paneChat] = rect{chatWidth, height}
+	rects[paneFeed] = rect{sideWidth, feedHeight}
+	rects[paneStats] = rect{sideWidth, statsHeight}
+	rects[paneLeaderboard] = rect{sideWidth, height - feedHeight - statsHeight}
+	return rects
+}
+
+// innerSize returns the content size of a pane, leaving room for its border
+// and title line
+func innerSize(r rect) (int, int) {
+	w := r.width - panelStyle.GetHorizontalFrameSize()
+	h := r.height - panelStyle.GetVerticalFrameSize() - 1
+	return max(w, 1), max(h, 1)
+}
+
+func (m *model) renderPane(p pane, r rect) string {
+	if r.width == 0 || r.height == 0 {
+		return ""
+	}
+	w, h := innerSize(r)
+
+	var body string
+	switch p {
+	case paneChat:
+		body = m.events.View()
+	case paneFeed:
+		body = m.feed.View()
+	case paneStats:
+		body = m.statsView(w, h)
+	case paneLeaderboard:
+		body = m.leaderboardView(w, h)
+	}
+
+	style := panelStyle
+	if p == m.focus {
+		style = focusedPanelStyle
+	}
+	title := paneTitles[p]
+	if monochrome && p == m.focus {
+		title = "▶ " + title
+	}
+	title = paneTitleStyle.Render(title)
+	if m.compact() {
+		title = m.paneTabs()
+	}
+	content := lipgloss.NewStyle().Width(w).Height(h).MaxHeight(h).MaxWidth(w).Render(body)
+	return style.Render(title + "\n" + content)
+}
+
+// paneTabs lists all panes with the focused one highlighted, for compact mode
+func (m *model) paneTabs() string {
+	var tabs []string
+	for i, title := range paneTitles {
+		if pane(i) == m.focus {
+			tabs = append(tabs, paneTitleStyle.Render("["+title+"]"))
+		} else {
+			tabs = append(tabs, timestampStyle.Render(title))
+		}
+	}
+	return strings.Join(tabs, " ")
+}
+
+func (m *model) dashboardView(height int) string {
+	rects := m.layoutPanes(m.width, height)
+	if m.compact() {
+		return m.renderPane(m.focus, rects[m.focus])
+	}
+	side := lipgloss.JoinVertical(lipgloss.Left,
+		m.renderPane(paneFeed, rects[paneFeed]),
+		m.renderPane(paneStats, rects[paneStats]),
+		m.renderPane(paneLeaderboard, rects[paneLeaderboard]),
+	)
+	return lipgloss.JoinHorizontal(lipgloss.Top, m.renderPane(paneChat, rects[paneChat]), side)
+}
+
+// statRows lists the stats pane rows in display order
+var statRows = []struct{ label, key string }{
+	{"Viewers", "viewers"},
+	{"Peak viewers", "peak_viewers"},
+	{"Likes", "likes"},
+	{"Comments", "comments"},
+	{"Follows", "follows"},
+	{"Shares", "shares"},
+	{"Gifts", "gifts"},
+	{"Diamonds", "diamonds"},
+}
+
+func (m *model) statsView(width, height int) string {
+	// Reserve two lines for the viewer sparkline when there is room
+	showSparkline := len(m.viewerHistory) > 1 && height >= 4
+	rowLines := height
+	if showSparkline {
+		rowLines -= 2
+	}
+
+	// Lay the rows out in two columns when they don't fit in one
+	columns := 1
+	if len(statRows) > rowLines && width >= 30 {
+		columns = 2
+	}
+	colWidth := width / columns
+	perColumn := (len(statRows) + columns - 1) / columns
+
+	lines := make([]string, min(perColumn, rowLines))
+	for i, row := range statRows {
+		line := i % perColumn
+		if line >= len(lines) {
+			continue
+		}
+		value := fmt.Sprintf("%d", m.stats[row.key])
+		cell := fmt.Sprintf("%-*s%s", max(colWidth-len(value)-1, 1), row.label, value)
+		if i >= perColumn {
+			cell = " " + cell
+		}
+		lines[line] += cell
+	}
+
+	view := strings.Join(lines, "\n")
+	if showSparkline {
+		view += "\n" + timestampStyle.Render("Viewers") + "\n" + sparklineStyle.Render(sparkline(m.viewerHistory, width))
+	}
+	return view
+}
+
+func (m *model) leaderboardView(width, height int) string {
+	type gifter struct {
+		name     string
+		diamonds int64
+	}
+	gifters := make([]gifter, 0, len(m.gifters))
+	for name, diamonds := range m.gifters {
+		gifters = append(gifters, gifter{name, diamonds})
+	}
+	sort.Slice(gifters, func(i, j int) bool {
+		if gifters[i].diamonds != gifters[j].diamonds {
+			return gifters[i].diamonds > gifters[j].diamonds
+		}
+		return gifters[i].name < gifters[j].name
+	})
+
+	if len(gifters) == 0 {
+		return infoStyle.Render("No gifts yet")
+	}
+
+	var lines []string
+	for i, g := range gifters {
+		if i >= height {
+			break
+		}
+		value := fmt.Sprintf("%d", g.diamonds)
+		nameWidth := max(width-len(value)-5, 1)
+		name := g.name
+		if lipgloss.Width(name) > nameWidth {
+			name = string([]rune(name)[:max(nameWidth-1, 0)]) + "…"
+		}
+		lines = append(lines, fmt.Sprintf("%2d. %-*s %s", i+1, nameWidth, name, typeStyles["gift"].Render(value)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline renders the last width values as a single line of block characters
+func sparkline(values []int64, width int) string {
+	if len(values) > width {
+		values = values[len(values)-width:]
+	}
+	low, high := values[0], values[0]
+	for _, v := range values {
+		low, high = min(low, v), max(high, v)
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		level := 0
+		if high > low {
+			level = int((v - low) * int64(len(sparkBlocks)-1) / (high - low))
+		}
+		b.WriteRune(sparkBlocks[level])
+	}
+	return b.String()
+}
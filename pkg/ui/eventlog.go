@@ -0,0 +1,305 @@
+package ui
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// DefaultBufferSize is the number of events kept in memory by the live view
+const DefaultBufferSize = 5000
+
+// ring is a fixed-capacity FIFO of events that evicts the oldest event when full
+type ring struct {
+	items   []Event
+	start   int
+	size    int
+	dropped int
+}
+
+func newRing(capacity int) *ring {
+	return &ring{items: make([]Event, max(capacity, 1))}
+}
+
+// push appends an event and returns the evicted event, if any
+func (r *ring) push(e Event) (Event, bool) {
+	if r.size < len(r.items) {
+		r.items[(r.start+r.size)%len(r.items)] = e
+		r.size++
+		return Event{}, false
+	}
+	evicted := r.items[r.start]
+	r.items[r.start] = e
+	r.start = (r.start + 1) % len(r.items)
+	r.dropped++
+	return evicted, true
+}
+
+func (r *ring) len() int {
+	return r.size
+}
+
+func (r *ring) at(i int) Event {
+	return r.items[(r.start+i)%len(r.items)]
+}
+
+// eventLog is a scrollable list of events backed by a ring buffer. Events are
+// addressed by sequence number, which stays stable while the buffer evicts,
+// and only the lines in the visible window are rendered.
+type eventLog struct {
+	buffer *ring
+	// older holds events before the buffer, either loaded from the database
+	// or evicted from the buffer while they were being browsed
+	older   []Event
+	filter  eventFilter
+	visible []int // sequence numbers of events that pass the filter
+	matches []int // sequence numbers of visible events matching the query
+	current int   // sequence number of the selected match
+	jumped  bool
+	offset  int // index in visible of the top line
+	width   int
+	height  int
+	follow  bool
+	keys    viewport.KeyMap
+}
+
+func newEventLog(capacity int) *eventLog {
+	return &eventLog{
+		buffer: newRing(capacity),
+		filter: eventFilter{hidden: make(map[string]bool)},
+		height: 1,
+		follow: true,
+		keys:   viewport.DefaultKeyMap(),
+	}
+}
+
+// first and end bound the sequence numbers currently held
+func (l *eventLog) first() int {
+	return l.buffer.dropped - len(l.older)
+}
+
+func (l *eventLog) end() int {
+	return l.buffer.dropped + l.buffer.len()
+}
+
+func (l *eventLog) event(seq int) Event {
+	if seq < l.buffer.dropped {
+		return l.older[seq-l.first()]
+	}
+	return l.buffer.at(seq - l.buffer.dropped)
+}
+
+// oldestID returns the database ID of the earliest stored event held, if any
+func (l *eventLog) oldestID() (int64, bool) {
+	for seq := l.first(); seq < l.end(); seq++ {
+		if id := l.event(seq).ID; id != 0 {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+func (l *eventLog) matchesQuery(e Event) bool {
+	return l.filter.query != "" && len(matchSpans(displayText(e), l.filter.query)) > 0
+}
+
+func (l *eventLog) push(e Event) {
+	if evicted, ok := l.buffer.push(e); ok {
+		if len(l.older) > 0 {
+			// Keep history contiguous while the user is browsing it
+			l.older = append(l.older, evicted)
+		} else {
+			l.trim()
+		}
+	}
+
+	if seq := l.end() - 1; l.filter.shows(e) {
+		l.visible = append(l.visible, seq)
+		if l.matchesQuery(e) {
+			l.matches = append(l.matches, seq)
+		}
+	}
+	if l.follow {
+		l.gotoBottom()
+	}
+}
+
+// prepend adds events loaded from the database before the oldest one held
+func (l *eventLog) prepend(events []Event) {
+	if len(events) == 0 {
+		return
+	}
+	l.older = append(append([]Event(nil), events...), l.older...)
+
+	var visible, matches []int
+	for i, e := range events {
+		if !l.filter.shows(e) {
+			continue
+		}
+		seq := l.first() + i
+		visible = append(visible, seq)
+		if l.matchesQuery(e) {
+			matches = append(matches, seq)
+		}
+	}
+	l.visible = append(visible, l.visible...)
+	l.matches = append(matches, l.matches...)
+	l.offset += len(visible)
+}
+
+// dropOlder releases events outside the buffer once the user is back at the
+// live end of the log
+func (l *eventLog) dropOlder() {
+	if len(l.older) == 0 {
+		return
+	}
+	l.older = nil
+	l.trim()
+}
+
+// trim forgets sequence numbers that are no longer held
+func (l *eventLog) trim() {
+	first := l.first()
+	n := sort.SearchInts(l.visible, first)
+	l.visible = l.visible[n:]
+	l.offset = max(l.offset-n, 0)
+	l.matches = l.matches[sort.SearchInts(l.matches, first):]
+}
+
+// resizeBuffer changes the buffer capacity, keeping the newest events
+func (l *eventLog) resizeBuffer(capacity int) {
+	old := l.buffer
+	l.buffer = newRing(capacity)
+	l.buffer.dropped = old.dropped
+	l.older = nil
+	for i := 0; i < old.len(); i++ {
+		l.buffer.push(old.at(i))
+	}
+	l.rebuild()
+}
+
+func (l *eventLog) setFilter(filter eventFilter) {
+	if filter.query != l.filter.query {
+		l.jumped = false
+	}
+	l.filter = filter
+	l.rebuild()
+}
+
+// rebuild recomputes the visible lines and matches after the filter changed
+func (l *eventLog) rebuild() {
+	l.visible, l.matches = l.visible[:0], l.matches[:0]
+	for seq := l.first(); seq < l.end(); seq++ {
+		e := l.event(seq)
+		if !l.filter.shows(e) {
+			continue
+		}
+		l.visible = append(l.visible, seq)
+		if l.matchesQuery(e) {
+			l.matches = append(l.matches, seq)
+		}
+	}
+	if l.follow {
+		l.gotoBottom()
+	} else {
+		l.scroll(0)
+	}
+}
+
+func (l *eventLog) setSize(width, height int) {
+	l.width, l.height = width, max(height, 1)
+	if l.follow {
+		l.gotoBottom()
+	} else {
+		l.scroll(0)
+	}
+}
+
+func (l *eventLog) maxOffset() int {
+	return max(len(l.visible)-l.height, 0)
+}
+
+func (l *eventLog) scroll(lines int) {
+	l.offset = min(max(l.offset+lines, 0), l.maxOffset())
+}
+
+func (l *eventLog) gotoBottom() {
+	l.offset = l.maxOffset()
+}
+
+func (l *eventLog) atTop() bool {
+	return l.offset == 0
+}
+
+// update scrolls in response to the viewport key bindings and reports
+// whether the key moved towards the top
+func (l *eventLog) update(msg tea.KeyMsg) bool {
+	switch {
+	case key.Matches(msg, l.keys.Up):
+		l.scroll(-1)
+	case key.Matches(msg, l.keys.Down):
+		l.scroll(1)
+		return false
+	case key.Matches(msg, l.keys.PageUp):
+		l.scroll(-l.height)
+	case key.Matches(msg, l.keys.PageDown):
+		l.scroll(l.height)
+		return false
+	case key.Matches(msg, l.keys.HalfPageUp):
+		l.scroll(-l.height / 2)
+	case key.Matches(msg, l.keys.HalfPageDown):
+		l.scroll(l.height / 2)
+		return false
+	default:
+		return false
+	}
+	return true
+}
+
+// jumpToMatch scrolls to the next (dir 1) or previous (dir -1) match
+func (l *eventLog) jumpToMatch(dir int) bool {
+	if len(l.matches) == 0 {
+		return false
+	}
+
+	var next int
+	switch {
+	case dir > 0:
+		i := len(l.matches)
+		if l.jumped {
+			i = sort.SearchInts(l.matches, l.current+1)
+		}
+		if i == len(l.matches) {
+			i = 0
+		}
+		next = l.matches[i]
+	default:
+		i := len(l.matches) - 1
+		if l.jumped {
+			i = sort.SearchInts(l.matches, l.current) - 1
+		}
+		if i < 0 {
+			i = len(l.matches) - 1
+		}
+		next = l.matches[i]
+	}
+
+	l.current, l.jumped = next, true
+	l.offset = sort.SearchInts(l.visible, next)
+	l.scroll(0)
+	return true
+}
+
+// View renders the lines in the visible window
+func (l *eventLog) View() string {
+	end := min(l.offset+l.height, len(l.visible))
+	lines := make([]string, 0, end-l.offset)
+	for _, seq := range l.visible[l.offset:end] {
+		line, _ := renderEvent(l.event(seq), l.filter.query)
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,134 @@
+package ui
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRingWraparound(t *testing.T) {
+	r := newRing(3)
+	for i := 1; i <= 3; i++ {
+		if _, evicted := r.push(Event{ID: int64(i)}); evicted {
+			t.Fatalf("push %d evicted an event before the ring was full", i)
+		}
+	}
+	for i := 4; i <= 7; i++ {
+		evicted, ok := r.push(Event{ID: int64(i)})
+		if !ok || evicted.ID != int64(i-3) {
+			t.Errorf("push %d evicted %d (%v), want %d", i, evicted.ID, ok, i-3)
+		}
+	}
+	if r.len() != 3 || r.dropped != 4 {
+		t.Errorf("len = %d, dropped = %d, want 3 and 4", r.len(), r.dropped)
+	}
+	for i, want := range []int64{5, 6, 7} {
+		if got := r.at(i).ID; got != want {
+			t.Errorf("at(%d) = %d, want %d", i, got, want)
+		}
+	}
+
+	// A ring always holds at least one event
+	r = newRing(0)
+	r.push(Event{ID: 1})
+	if evicted, ok := r.push(Event{ID: 2}); !ok || evicted.ID != 1 || r.at(0).ID != 2 {
+		t.Errorf("zero capacity ring evicted %d (%v), holds %d", evicted.ID, ok, r.at(0).ID)
+	}
+}
+
+// ids returns the database IDs of the visible events, oldest first
+func ids(l *eventLog) []int64 {
+	var ids []int64
+	for _, seq := range l.visible {
+		ids = append(ids, l.event(seq).ID)
+	}
+	return ids
+}
+
+func TestEventLogTrimsAtCapacity(t *testing.T) {
+	l := newEventLog(4)
+	l.setSize(80, 2)
+	l.setFilter(eventFilter{hidden: map[string]bool{"like": true}, query: "hello"})
+
+	for i := 1; i <= 10; i++ {
+		e := Event{ID: int64(i), Type: "chat", Content: "hi"}
+		switch {
+		case i%3 == 0:
+			e.Type = "like"
+		case i%2 == 0:
+			e.Content = "hello"
+		}
+		l.push(e)
+	}
+
+	if l.first() != 6 || l.end() != 10 {
+		t.Errorf("log holds %d to %d, want 6 to 10", l.first(), l.end())
+	}
+	if got := ids(l); !slices.Equal(got, []int64{7, 8, 10}) {
+		t.Errorf("visible = %v, want 7 8 10", got)
+	}
+	if !slices.Equal(l.matches, []int{7, 9}) {
+		t.Errorf("matches = %v, want the sequence numbers of 8 and 10", l.matches)
+	}
+	// Following the log keeps the last lines in view
+	if l.offset != 1 {
+		t.Errorf("offset = %d, want 1", l.offset)
+	}
+}
+
+func TestEventLogPrepend(t *testing.T) {
+	l := newEventLog(3)
+	l.setSize(80, 2)
+	for i := int64(11); i <= 13; i++ {
+		l.push(Event{ID: i, Type: "chat"})
+	}
+	l.follow = false
+	l.offset = 0
+
+	// Pages loaded while scrolling up come from the database, older first
+	l.prepend([]Event{{ID: 8, Type: "chat"}, {ID: 9, Type: "like"}, {ID: 10, Type: "chat"}})
+	l.prepend([]Event{{ID: 6, Type: "chat"}, {ID: 7, Type: "chat"}})
+	if got := ids(l); !slices.Equal(got, []int64{6, 7, 8, 9, 10, 11, 12, 13}) {
+		t.Fatalf("visible = %v", got)
+	}
+	if id, ok := l.oldestID(); !ok || id != 6 {
+		t.Errorf("oldestID = %d, %v, want 6", id, ok)
+	}
+	// The lines in view stay in view
+	if l.offset != 5 || l.event(l.visible[l.offset]).ID != 11 {
+		t.Errorf("offset = %d, want 5 (event 11)", l.offset)
+	}
+
+	// New events keep history contiguous while it is being browsed
+	l.push(Event{ID: 14, Type: "chat"})
+	l.push(Event{ID: 15, Type: "chat"})
+	if got := ids(l); !slices.Equal(got, []int64{6, 7, 8, 9, 10, 11, 12, 13, 14, 15}) {
+		t.Errorf("visible after new events = %v", got)
+	}
+	if l.offset != 5 {
+		t.Errorf("offset moved to %d while browsing", l.offset)
+	}
+
+	// Back at the live end, only the buffer is kept
+	l.dropOlder()
+	if got := ids(l); !slices.Equal(got, []int64{13, 14, 15}) {
+		t.Errorf("visible after dropping older events = %v", got)
+	}
+	if l.offset != 0 {
+		t.Errorf("offset = %d after dropping older events, want 0", l.offset)
+	}
+}
+
+func TestEventLogResize(t *testing.T) {
+	l := newEventLog(5)
+	for i := int64(1); i <= 5; i++ {
+		l.push(Event{ID: i, Type: "chat"})
+	}
+	l.resizeBuffer(2)
+	if got := ids(l); !slices.Equal(got, []int64{4, 5}) {
+		t.Errorf("visible after shrinking = %v, want 4 5", got)
+	}
+	l.push(Event{ID: 6, Type: "chat"})
+	if got := ids(l); !slices.Equal(got, []int64{5, 6}) {
+		t.Errorf("visible after another event = %v, want 5 6", got)
+	}
+}
@@ -0,0 +1,118 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Event is a single entry in the live event view
+type Event struct {
+	// ID is the database ID, or zero if the event wasn't stored
+	ID        int64
+	Type      string
+	Content   string
+	Timestamp time.Time
+	// Nickname and Value feed the leaderboard and stats panes
+	Nickname string
+	Value    int64
+	// Flags holds moderation reasons; flagged events are highlighted
+	Flags []string
+	// Language is the detected language code of chat messages
+	Language string
+}
+
+func (e Event) flagged() bool {
+	return len(e.Flags) > 0
+}
+
+// eventFilter decides which events are shown and what is highlighted
+type eventFilter struct {
+	hidden      map[string]bool
+	flaggedOnly bool
+	// language keeps only chat messages in this language, if set
+	language string
+	query    string
+}
+
+func (f eventFilter) shows(e Event) bool {
+	if f.hidden[e.Type] {
+		return false
+	}
+	if f.language != "" && e.Type == "chat" && e.Language != f.language {
+		return false
+	}
+	return !f.flaggedOnly || e.flagged()
+}
+
+// renderEvent styles a single event, highlighting occurrences of query
+func renderEvent(event Event, query string) (string, bool) {
+	style, ok := typeStyles[event.Type]
+	if !ok {
+		style = lipgloss.NewStyle()
+	}
+	if event.flagged() {
+		style = flaggedStyle
+	}
+	content := displayText(event)
+
+	prefix := ""
+	if !event.Timestamp.IsZero() {
+		prefix = timestampStyle.Render(event.Timestamp.Format("15:04:05")) + " "
+	}
+
+	spans := matchSpans(content, query)
+	if len(spans) == 0 {
+		return prefix + style.Render(content), false
+	}
+
+	var b strings.Builder
+	b.WriteString(prefix)
+	pos := 0
+	for _, span := range spans {
+		b.WriteString(style.Render(content[pos:span[0]]))
+		match := content[span[0]:span[1]]
+		if monochrome {
+			match = "»" + match + "«"
+		}
+		b.WriteString(matchStyle.Render(match))
+		pos = span[1]
+	}
+	b.WriteString(style.Render(content[pos:]))
+	return b.String(), true
+}
+
+// displayText is the searchable text of an event as shown in the list
+func displayText(event Event) string {
+	if event.flagged() {
+		return fmt.Sprintf("%s [%s]", event.Content, strings.Join(event.Flags, ", "))
+	}
+	return event.Content
+}
+
+// matchSpans returns the byte ranges of case-insensitive occurrences of query
+func matchSpans(content, query string) [][2]int {
+	if query == "" {
+		return nil
+	}
+
+	haystack, needle := strings.ToLower(content), strings.ToLower(query)
+	if len(haystack) != len(content) {
+		// Lowercasing changed byte offsets, fall back to exact matching
+		haystack, needle = content, query
+	}
+
+	var spans [][2]int
+	for pos := 0; ; {
+		i := strings.Index(haystack[pos:], needle)
+		if i < 0 {
+			break
+		}
+		start := pos + i
+		spans = append(spans, [2]int{start, start + len(needle)})
+		pos = start + len(needle)
+	}
+	return spans
+}
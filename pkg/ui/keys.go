@@ -0,0 +1,97 @@
+package ui
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// eventTypes lists the toggleable event types in key order
+var eventTypes = []string{"chat", "gift", "like", "follow", "share", "stats"}
+
+// liveKeyMap holds the live view key bindings shown in the help bar
+type liveKeyMap struct {
+	ToggleType  []key.Binding
+	Search      key.Binding
+	NextMatch   key.Binding
+	PrevMatch   key.Binding
+	Pause       key.Binding
+	FlaggedOnly key.Binding
+	Language    key.Binding
+	NextPane    key.Binding
+	PrevPane    key.Binding
+	Wider       key.Binding
+	Narrower    key.Binding
+	Taller      key.Binding
+	Shorter     key.Binding
+	Zoom        key.Binding
+	ResetLayout key.Binding
+	Up          key.Binding
+	Down        key.Binding
+	Help        key.Binding
+	Quit        key.Binding
+}
+
+func newLiveKeyMap() liveKeyMap {
+	toggles := make([]key.Binding, len(eventTypes))
+	for i, t := range eventTypes {
+		k := string(rune('1' + i))
+		toggles[i] = key.NewBinding(key.WithKeys(k), key.WithHelp(k, t))
+	}
+
+	return liveKeyMap{
+		ToggleType:  toggles,
+		Search:      key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "search")),
+		NextMatch:   key.NewBinding(key.WithKeys("n"), key.WithHelp("n/N", "next/prev match")),
+		PrevMatch:   key.NewBinding(key.WithKeys("N")),
+		Pause:       key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "pause scroll")),
+		FlaggedOnly: key.NewBinding(key.WithKeys("m"), key.WithHelp("m", "flagged only")),
+		Language:    key.NewBinding(key.WithKeys("L"), key.WithHelp("L", "chat language")),
+		NextPane:    key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "next pane")),
+		PrevPane:    key.NewBinding(key.WithKeys("shift+tab"), key.WithHelp("shift+tab", "prev pane")),
+		Wider:       key.NewBinding(key.WithKeys("]"), key.WithHelp("]/[", "chat wider/narrower")),
+		Narrower:    key.NewBinding(key.WithKeys("[")),
+		Taller:      key.NewBinding(key.WithKeys("+", "="), key.WithHelp("+/-", "pane taller/shorter")),
+		Shorter:     key.NewBinding(key.WithKeys("-")),
+		Zoom:        key.NewBinding(key.WithKeys("z"), key.WithHelp("z", "zoom pane")),
+		ResetLayout: key.NewBinding(key.WithKeys("0"), key.WithHelp("0", "reset layout")),
+		Up:          key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/k", "scroll up")),
+		Down:        key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓/j", "scroll down")),
+		Help:        key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "more keys")),
+		Quit:        key.NewBinding(key.WithKeys("q", "esc", "ctrl+c"), key.WithHelp("q/esc", "quit")),
+	}
+}
+
+// ShortHelp implements help.KeyMap
+func (k liveKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.NextPane, k.Search, k.Pause, k.FlaggedOnly, k.Help, k.Quit}
+}
+
+// FullHelp implements help.KeyMap
+func (k liveKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		k.ToggleType,
+		{k.Search, k.NextMatch, k.Pause, k.FlaggedOnly, k.Language},
+		{k.NextPane, k.PrevPane, k.Wider, k.Taller, k.Zoom, k.ResetLayout},
+		{k.Up, k.Down, k.Help, k.Quit},
+	}
+}
+
+// searchKeyMap is shown while the search prompt is focused
+type searchKeyMap struct {
+	Confirm key.Binding
+	Cancel  key.Binding
+}
+
+func newSearchKeyMap() searchKeyMap {
+	return searchKeyMap{
+		Confirm: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "keep search")),
+		Cancel:  key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "clear search")),
+	}
+}
+
+func (k searchKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Confirm, k.Cancel}
+}
+
+func (k searchKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
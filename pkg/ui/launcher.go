@@ -0,0 +1,323 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// LiveStatus is the launcher's view of whether a streamer is live
+type LiveStatus struct {
+	Live    bool
+	Title   string
+	Viewers int64
+}
+
+// LauncherSource provides the launcher's completions and live checks
+type LauncherSource interface {
+	// Usernames returns the previously logged streamers
+	Usernames() ([]string, error)
+	CheckLive(username string) (LiveStatus, error)
+}
+
+// LaunchAction is what the user chose to do in the launcher
+type LaunchAction int
+
+const (
+	LaunchNone LaunchAction = iota
+	LaunchTrack
+	LaunchHistory
+)
+
+// LaunchChoice is the launcher's result
+type LaunchChoice struct {
+	Action   LaunchAction
+	Username string
+}
+
+// maxKnownShown bounds the logged streamers listed under the input
+const maxKnownShown = 5
+
+type (
+	usernamesMsg  []string
+	liveStatusMsg struct {
+		username string
+		status   LiveStatus
+		err      error
+	}
+)
+
+// launcherKeyMap holds the launcher keys shown in the help bar
+type launcherKeyMap struct {
+	Check    key.Binding
+	Complete key.Binding
+	Track    key.Binding
+	History  key.Binding
+	Edit     key.Binding
+	Cancel   key.Binding
+	Quit     key.Binding
+}
+
+func newLauncherKeyMap() launcherKeyMap {
+	return launcherKeyMap{
+		Check:    key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "check")),
+		Complete: key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "complete")),
+		Track:    key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "track live")),
+		History:  key.NewBinding(key.WithKeys("h"), key.WithHelp("h", "history")),
+		Edit:     key.NewBinding(key.WithKeys("e", "esc"), key.WithHelp("e/esc", "change user")),
+		Cancel:   key.NewBinding(key.WithKeys("esc", "ctrl+c"), key.WithHelp("esc", "quit")),
+		Quit:     key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "quit")),
+	}
+}
+
+// launcher asks for a username, checks whether the streamer is live and
+// lets the user start tracking or open the streamer's history
+type launcher struct {
+	source    LauncherSource
+	input     textinput.Model
+	spinner   spinner.Model
+	help      help.Model
+	keys      launcherKeyMap
+	usernames []string
+	// known holds the lowercased logged usernames
+	known    map[string]bool
+	checking bool
+	// username is the checked streamer, status is set once the check is done
+	username string
+	status   *LiveStatus
+	err      error
+	choice   LaunchChoice
+}
+
+// NewLauncher returns the program model shown when the tool is started
+// without a command. Read the result with Choice on the final model.
+func NewLauncher(source LauncherSource) tea.Model {
+	ti := textinput.New()
+	ti.Prompt = "@"
+	ti.Placeholder = "username"
+	ti.ShowSuggestions = true
+	ti.Focus()
+
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	s.Style = accentStyle
+
+	return &launcher{
+		source:  source,
+		input:   ti,
+		spinner: s,
+		help:    help.New(),
+		keys:    newLauncherKeyMap(),
+		known:   make(map[string]bool),
+	}
+}
+
+// Choice returns what the user picked, LaunchNone if they quit
+func (l *launcher) Choice() LaunchChoice {
+	return l.choice
+}
+
+func (l *launcher) Init() tea.Cmd {
+	source := l.source
+	return tea.Batch(textinput.Blink, func() tea.Msg {
+		usernames, err := source.Usernames()
+		if err != nil {
+			// Completion is optional, the launcher works without it
+			return usernamesMsg(nil)
+		}
+		return usernamesMsg(usernames)
+	})
+}
+
+func (l *launcher) checkLive(username string) tea.Cmd {
+	source := l.source
+	return func() tea.Msg {
+		status, err := source.CheckLive(username)
+		return liveStatusMsg{username: username, status: status, err: err}
+	}
+}
+
+func (l *launcher) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		l.input.Width = msg.Width - 4
+		l.help.Width = msg.Width
+		return l, nil
+	case usernamesMsg:
+		l.usernames = msg
+		for _, u := range msg {
+			l.known[strings.ToLower(u)] = true
+		}
+		l.input.SetSuggestions(msg)
+		return l, nil
+	case liveStatusMsg:
+		// Ignore results for a username that was edited in the meantime
+		if !l.checking || msg.username != l.username {
+			return l, nil
+		}
+		l.checking = false
+		if msg.err != nil {
+			l.err = msg.err
+			return l, nil
+		}
+		l.status = &msg.status
+		return l, nil
+	case spinner.TickMsg:
+		if !l.checking {
+			return l, nil
+		}
+		var cmd tea.Cmd
+		l.spinner, cmd = l.spinner.Update(msg)
+		return l, cmd
+	case tea.KeyMsg:
+		if msg.Type == tea.KeyCtrlC {
+			return l, tea.Quit
+		}
+		if l.checking || l.status != nil || l.err != nil {
+			return l.updateResult(msg)
+		}
+		switch {
+		case key.Matches(msg, l.keys.Check):
+			username := normalizeUsername(l.input.Value())
+			if username == "" {
+				return l, nil
+			}
+			l.username = username
+			l.input.SetValue(username)
+			l.input.Blur()
+			l.checking = true
+			return l, tea.Batch(l.spinner.Tick, l.checkLive(username))
+		case key.Matches(msg, l.keys.Cancel):
+			return l, tea.Quit
+		}
+	}
+
+	var cmd tea.Cmd
+	l.input, cmd = l.input.Update(msg)
+	return l, cmd
+}
+
+// updateResult handles keys while a username is being checked or once the
+// check is done
+func (l *launcher) updateResult(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, l.keys.Quit):
+		return l, tea.Quit
+	case key.Matches(msg, l.keys.Edit):
+		l.checking = false
+		l.status = nil
+		l.err = nil
+		return l, l.input.Focus()
+	case key.Matches(msg, l.keys.Track):
+		return l.launch(LaunchTrack)
+	case key.Matches(msg, l.keys.History) && l.isKnown():
+		return l.launch(LaunchHistory)
+	case key.Matches(msg, l.keys.Check) && !l.checking:
+		// Enter picks the obvious action: track a live streamer, otherwise
+		// look at what was logged before
+		switch {
+		case l.status != nil && l.status.Live:
+			return l.launch(LaunchTrack)
+		case l.isKnown():
+			return l.launch(LaunchHistory)
+		}
+	}
+	return l, nil
+}
+
+func (l *launcher) launch(action LaunchAction) (tea.Model, tea.Cmd) {
+	l.choice = LaunchChoice{Action: action, Username: l.username}
+	return l, tea.Quit
+}
+
+// isKnown tells whether the checked streamer has been logged before
+func (l *launcher) isKnown() bool {
+	return l.known[strings.ToLower(l.username)]
+}
+
+// matchingUsernames returns the logged streamers starting with the input
+func (l *launcher) matchingUsernames() []string {
+	prefix := strings.ToLower(normalizeUsername(l.input.Value()))
+	var matches []string
+	for _, u := range l.usernames {
+		if strings.HasPrefix(strings.ToLower(u), prefix) {
+			matches = append(matches, "@"+u)
+		}
+	}
+	return matches
+}
+
+func (l *launcher) View() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("TikTok Live Logger") + "\n\n")
+	b.WriteString(infoStyle.Render("Enter a TikTok username to track their live stream or view their logs:") + "\n\n")
+	b.WriteString(" " + l.input.View() + "\n\n")
+
+	switch {
+	case l.checking:
+		b.WriteString(fmt.Sprintf(" %s Checking whether @%s is live...\n", l.spinner.View(), l.username))
+		b.WriteString("\n" + l.help.ShortHelpView(l.resultKeys()))
+	case l.err != nil:
+		b.WriteString(errorStyle.Render(fmt.Sprintf("Could not check @%s: %v", l.username, l.err)) + "\n")
+		b.WriteString("\n" + l.help.ShortHelpView(l.resultKeys()))
+	case l.status != nil:
+		b.WriteString(l.statusView() + "\n")
+		b.WriteString("\n" + l.help.ShortHelpView(l.resultKeys()))
+	default:
+		matches := l.matchingUsernames()
+		if len(matches) > 0 {
+			if len(matches) > maxKnownShown {
+				matches = append(matches[:maxKnownShown], fmt.Sprintf("+%d more", len(matches)-maxKnownShown))
+			}
+			b.WriteString(infoStyle.Render("Logged before: "+strings.Join(matches, ", ")) + "\n")
+		}
+		b.WriteString("\n" + l.help.ShortHelpView([]key.Binding{l.keys.Check, l.keys.Complete, l.keys.Cancel}))
+	}
+	return b.String()
+}
+
+// statusView describes the finished live check
+func (l *launcher) statusView() string {
+	var lines []string
+	if l.status.Live {
+		line := fmt.Sprintf("● @%s is live with %d viewers", l.username, l.status.Viewers)
+		if l.status.Title != "" {
+			line += fmt.Sprintf(": %q", l.status.Title)
+		}
+		lines = append(lines, successStyle.Render(line))
+	} else {
+		lines = append(lines, infoStyle.Render(fmt.Sprintf("○ @%s is not live right now", l.username)))
+	}
+	if l.isKnown() {
+		lines = append(lines, infoStyle.Render("Logged before, press h to browse their sessions"))
+	} else {
+		lines = append(lines, infoStyle.Render("Not logged before"))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// resultKeys are the keys available after a username was entered
+func (l *launcher) resultKeys() []key.Binding {
+	keys := []key.Binding{l.keys.Track}
+	if l.isKnown() {
+		keys = append(keys, l.keys.History)
+	}
+	return append(keys, l.keys.Edit, l.keys.Quit)
+}
+
+// normalizeUsername accepts "user", "@user" and profile or live URLs
+func normalizeUsername(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndex(s, "/@"); i >= 0 {
+		s = s[i+2:]
+		if j := strings.IndexAny(s, "/?#"); j >= 0 {
+			s = s[:j]
+		}
+	}
+	return strings.TrimPrefix(s, "@")
+}
@@ -0,0 +1,171 @@
+package ui
+
+import (
+	"cmp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// tableKeyMap holds the sortable table's keys
+type tableKeyMap struct {
+	Left  key.Binding
+	Right key.Binding
+	Sort  key.Binding
+	Up    key.Binding
+	Down  key.Binding
+	Quit  key.Binding
+}
+
+func newTableKeyMap() tableKeyMap {
+	return tableKeyMap{
+		Left:  key.NewBinding(key.WithKeys("left", "h"), key.WithHelp("←/→", "pick column")),
+		Right: key.NewBinding(key.WithKeys("right", "l")),
+		Sort:  key.NewBinding(key.WithKeys("s", "enter"), key.WithHelp("s", "sort")),
+		Up:    key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/↓", "move")),
+		Down:  key.NewBinding(key.WithKeys("down", "j")),
+		Quit:  key.NewBinding(key.WithKeys("q", "esc", "ctrl+c"), key.WithHelp("q", "quit")),
+	}
+}
+
+// tableView shows rows in a table that can be sorted by any column
+type tableView struct {
+	title  string
+	header []string
+	rows   [][]string
+	table  table.Model
+	help   help.Model
+	keys   tableKeyMap
+	// column is the picked column, sorted the one the rows are sorted by
+	column int
+	sorted int
+	desc   bool
+}
+
+// NewTableView returns a program model showing a read-only table. Columns
+// holding numbers sort numerically, other columns alphabetically.
+func NewTableView(title string, header []string, rows [][]string) tea.Model {
+	t := table.New(table.WithFocused(true))
+	v := &tableView{
+		title:  title,
+		header: header,
+		rows:   rows,
+		table:  t,
+		help:   help.New(),
+		keys:   newTableKeyMap(),
+		sorted: -1,
+	}
+	v.refresh()
+	return v
+}
+
+func (v *tableView) Init() tea.Cmd {
+	return nil
+}
+
+func (v *tableView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		v.table.SetWidth(msg.Width)
+		v.table.SetHeight(msg.Height - 4) // title and help line
+		v.help.Width = msg.Width
+		return v, nil
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, v.keys.Quit):
+			return v, tea.Quit
+		case key.Matches(msg, v.keys.Left):
+			v.column = (v.column + len(v.header) - 1) % len(v.header)
+			v.refresh()
+			return v, nil
+		case key.Matches(msg, v.keys.Right):
+			v.column = (v.column + 1) % len(v.header)
+			v.refresh()
+			return v, nil
+		case key.Matches(msg, v.keys.Sort):
+			// Numbers are most interesting largest first
+			if v.sorted == v.column {
+				v.desc = !v.desc
+			} else {
+				v.sorted, v.desc = v.column, true
+			}
+			v.sort()
+			v.refresh()
+			return v, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	v.table, cmd = v.table.Update(msg)
+	return v, cmd
+}
+
+// sort orders the rows by the sorted column
+func (v *tableView) sort() {
+	c := v.sorted
+	sort.SliceStable(v.rows, func(i, j int) bool {
+		if v.desc {
+			return compareCells(v.rows[i][c], v.rows[j][c]) > 0
+		}
+		return compareCells(v.rows[i][c], v.rows[j][c]) < 0
+	})
+}
+
+// compareCells compares numbers numerically and text case-insensitively
+func compareCells(a, b string) int {
+	x, errA := parseNumber(a)
+	y, errB := parseNumber(b)
+	if errA == nil && errB == nil {
+		return cmp.Compare(x, y)
+	}
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// parseNumber reads cells such as "1,234", "12.5%" or "3.2h"
+func parseNumber(cell string) (float64, error) {
+	cell = strings.ReplaceAll(cell, ",", "")
+	cell = strings.TrimRight(cell, "%hms")
+	return strconv.ParseFloat(cell, 64)
+}
+
+// refresh rebuilds the columns with the sort markers and widths
+func (v *tableView) refresh() {
+	columns := make([]table.Column, len(v.header))
+	for i, title := range v.header {
+		switch {
+		case i == v.sorted && v.desc:
+			title += " ▼"
+		case i == v.sorted:
+			title += " ▲"
+		}
+		if i == v.column {
+			title = "[" + title + "]"
+		}
+		// Leave room for the markers so columns don't shift
+		width := lipgloss.Width(v.header[i]) + 4
+		for _, row := range v.rows {
+			width = max(width, lipgloss.Width(row[i]))
+		}
+		columns[i] = table.Column{Title: title, Width: width}
+	}
+
+	rows := make([]table.Row, len(v.rows))
+	for i, row := range v.rows {
+		rows[i] = row
+	}
+	// Columns must be set before rows since rows are rendered for them
+	v.table.SetRows(nil)
+	v.table.SetColumns(columns)
+	v.table.SetRows(rows)
+}
+
+func (v *tableView) View() string {
+	return titleStyle.Render(v.title) + "\n\n" + v.table.View() + "\n" +
+		v.help.ShortHelpView([]key.Binding{v.keys.Left, v.keys.Sort, v.keys.Up, v.keys.Quit})
+}
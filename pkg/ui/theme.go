@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"tiktok-live-logger/pkg/theme"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Styles are built from the active theme by SetTheme
+var (
+	titleStyle        lipgloss.Style
+	infoStyle         lipgloss.Style
+	errorStyle        lipgloss.Style
+	successStyle      lipgloss.Style
+	accentStyle       lipgloss.Style
+	panelStyle        lipgloss.Style
+	focusedPanelStyle lipgloss.Style
+	paneTitleStyle    lipgloss.Style
+	sparklineStyle    lipgloss.Style
+	flaggedStyle      lipgloss.Style
+	matchStyle        lipgloss.Style
+	timestampStyle    lipgloss.Style
+	hiddenStyle       lipgloss.Style
+
+	// Colors per event type
+	typeStyles map[string]lipgloss.Style
+
+	// monochrome marks highlights with symbols as well, since the terminal
+	// may not render any attributes
+	monochrome bool
+)
+
+func init() {
+	SetTheme(theme.Default())
+}
+
+// SetTheme rebuilds the UI styles from a theme. It must be called before
+// any program is started.
+func SetTheme(t theme.Theme) {
+	c := theme.Color
+	fg := func(color string) lipgloss.Style {
+		return lipgloss.NewStyle().Foreground(c(color))
+	}
+
+	monochrome = t.Monochrome
+	titleStyle = fg(t.Title).Bold(true).Padding(0, 1)
+	infoStyle = fg(t.Muted).Padding(0, 1)
+	errorStyle = fg(t.Error).Padding(0, 1)
+	successStyle = fg(t.Success).Padding(0, 1)
+	accentStyle = fg(t.Accent)
+	// Border around each dashboard pane
+	panelStyle = lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(c(t.Border)).
+		Padding(0, 1)
+	focusedPanelStyle = panelStyle.BorderForeground(c(t.Focus))
+	paneTitleStyle = fg(t.Title).Bold(true)
+	sparklineStyle = fg(t.Share)
+	flaggedStyle = fg(t.FlaggedText).Background(c(t.FlaggedBackground))
+	matchStyle = fg(t.MatchText).Background(c(t.MatchBackground))
+	timestampStyle = fg(t.Muted)
+	hiddenStyle = fg(t.Muted)
+
+	typeStyles = map[string]lipgloss.Style{
+		"chat":   fg(t.Chat),
+		"gift":   fg(t.Gift),
+		"like":   fg(t.Like),
+		"follow": fg(t.Follow),
+		"share":  fg(t.Share),
+		"stats":  fg(t.Stats),
+	}
+
+	if t.Monochrome {
+		// Use attributes where colors carried meaning
+		focusedPanelStyle = panelStyle.Border(lipgloss.ThickBorder())
+		flaggedStyle = lipgloss.NewStyle().Reverse(true)
+		matchStyle = lipgloss.NewStyle().Underline(true).Bold(true)
+		hiddenStyle = lipgloss.NewStyle().Strikethrough(true)
+	}
+}
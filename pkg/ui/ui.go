@@ -2,148 +2,134 @@ package ui
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
-	"github.com/charmbracelet/bubbles/list"
-	"github.com/charmbracelet/bubbles/spinner"
-	"github.com/charmbracelet/bubbles/table"
+	"tiktok-live-logger/pkg/metrics"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textinput"
-	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
 // Message types for the UI
 type (
-	startTrackingMsg struct{}
-	eventMsg        string
-	statsMsg        map[string]int64
-	errorMsg        error
+	// EventMsg delivers a live event to a running program
+	EventMsg Event
+	// StatsMsg replaces the live statistics
+	StatsMsg map[string]int64
+	// ErrorMsg reports an error to a running program. The dashboard keeps
+	// running and shows the last one in the footer.
+	ErrorMsg error
+	// olderEventsMsg delivers events loaded by a HistoryFunc
+	olderEventsMsg struct {
+		events []Event
+		err    error
+	}
 )
 
-var (
-	titleStyle = lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("#FAFAFA")).
-		Padding(0, 1)
+// HistoryFunc loads up to limit events stored before the event with the
+// given ID, in the order they were stored. It is used to scroll back past the
+// in-memory buffer.
+type HistoryFunc func(beforeID int64, limit int) ([]Event, error)
 
-	infoStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#A7A7A7")).
-		Padding(0, 1)
+// historyPageSize is the number of older events loaded at a time
+const historyPageSize = 200
 
-	errorStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#FF0000")).
-		Padding(0, 1)
+// feedBufferSize is the number of events kept by the gift and follow feed
+const feedBufferSize = 500
 
-	successStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#00FF00")).
-		Padding(0, 1)
-)
+// maxViewerHistory bounds the viewer counts kept for the sparkline
+const maxViewerHistory = 512
 
 type model struct {
-	spinner     spinner.Model
-	list        list.Model
-	viewport    viewport.Model
-	textinput   textinput.Model
-	table       table.Model
-	events      []string
-	username    string
-	stats       map[string]int64
-	err         error
-	loading     bool
-	showList    bool
-	showViewer  bool
+	feed       *eventLog
+	help       help.Model
+	search     textinput.Model
+	keys       liveKeyMap
+	searchKeys searchKeyMap
+	events     *eventLog
+	filter     eventFilter
+	layout     Layout
+	focus      pane
+	zoomed     bool
+	searching  bool
+	paused     bool
+	width      int
+	height     int
+	username   string
+	title      string
+	stats      map[string]int64
+	// gifters maps nicknames to diamonds sent, viewerHistory holds every
+	// viewer count update for the sparkline
+	gifters       map[string]int64
+	viewerHistory []int64
+	// languages counts chat messages per detected language for the filter
+	languages map[string]int64
+	// history loads events evicted from the buffer when scrolling back
+	history        HistoryFunc
+	loadingHistory bool
+	historyDone    bool
+	historyErr     error
+	// lastErr is the last ErrorMsg, received at lastErrAt
+	lastErr   error
+	lastErrAt time.Time
+	err       error
 }
 
 func NewModel(username string) model {
-	s := spinner.New()
-	s.Spinner = spinner.Dot
-	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
-
-	l := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
-	l.Title = "Live Events"
-	l.SetShowStatusBar(false)
-	l.SetFilteringEnabled(false)
-	l.Styles.Title = titleStyle
-
-	v := viewport.New(80, 24)
-	v.Style = lipgloss.NewStyle().
-		BorderStyle(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("62")).
-		Padding(1)
-
-	ti := textinput.New()
-	ti.Placeholder = "Enter username..."
-	ti.Focus()
-
-	t := table.New(
-		table.WithColumns([]table.Column{
-			{Title: "Metric", Width: 20},
-			{Title: "Value", Width: 20},
-		}),
-		table.WithFocused(true),
-		table.WithHeight(7),
-	)
+	search := textinput.New()
+	search.Prompt = "/"
+	search.Placeholder = "search events"
 
-	return model{
-		spinner:   s,
-		list:      l,
-		viewport:  v,
-		textinput: ti,
-		table:     t,
-		username:  username,
-		stats:     make(map[string]int64),
-		showViewer: true,
+	m := model{
+		events:     newEventLog(DefaultBufferSize),
+		feed:       newEventLog(feedBufferSize),
+		help:       help.New(),
+		search:     search,
+		keys:       newLiveKeyMap(),
+		searchKeys: newSearchKeyMap(),
+		filter:     eventFilter{hidden: make(map[string]bool)},
+		username:   username,
+		stats:      make(map[string]int64),
+		gifters:    make(map[string]int64),
+		languages:  make(map[string]int64),
 	}
+	m.SetLayout(DefaultLayout())
+	return m
 }
 
 func (m model) Init() tea.Cmd {
-	return tea.Batch(
-		spinner.Tick,
-		textinput.Blink,
-	)
+	return nil
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	var (
-		cmd  tea.Cmd
-		cmds []tea.Cmd
-	)
-
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.Type {
-		case tea.KeyCtrlC, tea.KeyEsc:
+		if msg.Type == tea.KeyCtrlC {
 			return m, tea.Quit
 		}
+		return m.updateLive(msg)
 	case tea.WindowSizeMsg:
-		h, v := lipgloss.NewStyle().Margin(1, 2).GetFrameSize()
-		m.viewport.Width = msg.Width - h
-		m.viewport.Height = msg.Height - v
-		m.list.SetWidth(msg.Width - h)
-		m.list.SetHeight(msg.Height - v)
-	case eventMsg:
-		m.events = append(m.events, string(msg))
-		m.viewport.SetContent(m.formatEvents())
-		m.viewport.GotoBottom()
-	case statsMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.resize()
+	case EventMsg:
+		m.AddEvent(Event(msg))
+	case StatsMsg:
 		m.stats = map[string]int64(msg)
-		m.updateStats()
-	case errorMsg:
-		m.err = error(msg)
-	}
-
-	if m.showViewer {
-		m.viewport, cmd = m.viewport.Update(msg)
-		cmds = append(cmds, cmd)
-	} else if m.showList {
-		m.list, cmd = m.list.Update(msg)
-		cmds = append(cmds, cmd)
-	} else {
-		m.textinput, cmd = m.textinput.Update(msg)
-		cmds = append(cmds, cmd)
+	case ErrorMsg:
+		m.lastErr, m.lastErrAt = error(msg), time.Now()
+	case olderEventsMsg:
+		m.loadingHistory = false
+		m.historyErr = msg.err
+		m.historyDone = msg.err != nil || len(msg.events) < historyPageSize
+		m.events.prepend(msg.events)
 	}
 
-	return m, tea.Batch(cmds...)
+	return m, nil
 }
 
 func (m model) View() string {
@@ -151,65 +137,375 @@ func (m model) View() string {
 		return errorStyle.Render(fmt.Sprintf("Error: %v", m.err))
 	}
 
-	if m.showViewer {
-		return fmt.Sprintf(
-			"%s\n\n%s\n\n%s",
-			titleStyle.Render(fmt.Sprintf("Live Stream: @%s", m.username)),
-			m.table.View(),
-			m.viewport.View(),
-		)
+	return lipgloss.JoinVertical(lipgloss.Left,
+		m.liveHeader(),
+		m.dashboardView(m.bodyHeight()),
+		m.liveFooter(),
+	)
+}
+
+// updateLive handles keys in the live event view
+func (m model) updateLive(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.searching {
+		switch {
+		case key.Matches(msg, m.searchKeys.Confirm):
+			m.searching = false
+			m.search.Blur()
+		case key.Matches(msg, m.searchKeys.Cancel):
+			m.searching = false
+			m.search.Blur()
+			m.search.SetValue("")
+		default:
+			var cmd tea.Cmd
+			m.search, cmd = m.search.Update(msg)
+			m.setQuery(m.search.Value())
+			return m, cmd
+		}
+		m.setQuery(m.search.Value())
+		m.resize()
+		return m, nil
 	}
 
-	if m.showList {
-		return m.list.View()
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		return m, tea.Quit
+	case key.Matches(msg, m.keys.Search):
+		m.searching = true
+		m.resize()
+		return m, m.search.Focus()
+	case key.Matches(msg, m.keys.NextMatch):
+		m.jumpToMatch(1)
+		return m, nil
+	case key.Matches(msg, m.keys.PrevMatch):
+		m.jumpToMatch(-1)
+		return m, nil
+	case key.Matches(msg, m.keys.Pause):
+		m.setPaused(!m.paused)
+		return m, nil
+	case key.Matches(msg, m.keys.FlaggedOnly):
+		m.filter.flaggedOnly = !m.filter.flaggedOnly
+		m.refresh()
+		return m, nil
+	case key.Matches(msg, m.keys.Language):
+		m.filter.language = m.nextLanguage()
+		m.refresh()
+		return m, nil
+	case key.Matches(msg, m.keys.Help):
+		m.help.ShowAll = !m.help.ShowAll
+		m.resize()
+		return m, nil
+	case key.Matches(msg, m.keys.NextPane):
+		m.focus = (m.focus + 1) % paneCount
+		m.resize()
+		return m, nil
+	case key.Matches(msg, m.keys.PrevPane):
+		m.focus = (m.focus + paneCount - 1) % paneCount
+		m.resize()
+		return m, nil
+	case key.Matches(msg, m.keys.Wider):
+		m.adjustLayout(5, 0)
+		return m, nil
+	case key.Matches(msg, m.keys.Narrower):
+		m.adjustLayout(-5, 0)
+		return m, nil
+	case key.Matches(msg, m.keys.Taller):
+		m.adjustLayout(0, 5)
+		return m, nil
+	case key.Matches(msg, m.keys.Shorter):
+		m.adjustLayout(0, -5)
+		return m, nil
+	case key.Matches(msg, m.keys.Zoom):
+		m.zoomed = !m.zoomed
+		m.resize()
+		return m, nil
+	case key.Matches(msg, m.keys.ResetLayout):
+		m.zoomed = false
+		m.SetLayout(DefaultLayout())
+		return m, nil
 	}
 
-	return fmt.Sprintf(
-		"%s\n\n%s\n\n%s",
-		titleStyle.Render("TikTok Live Logger"),
-		infoStyle.Render("Enter a TikTok username to start tracking their live stream:"),
-		m.textinput.View(),
-	)
+	for i, binding := range m.keys.ToggleType {
+		if key.Matches(msg, binding) {
+			t := eventTypes[i]
+			m.filter.hidden[t] = !m.filter.hidden[t]
+			m.refresh()
+			return m, nil
+		}
+	}
+
+	// Remaining keys scroll the focused pane
+	switch m.focus {
+	case paneChat:
+		if m.events.update(msg) && m.events.atTop() {
+			return m, m.loadOlder()
+		}
+	case paneFeed:
+		m.feed.update(msg)
+	}
+	return m, nil
+}
+
+// loadOlder fetches the events before the oldest one held, once the user
+// scrolls past the top of the buffer
+func (m *model) loadOlder() tea.Cmd {
+	if m.history == nil || m.loadingHistory || m.historyDone || m.events.buffer.dropped == 0 {
+		return nil
+	}
+	oldest, ok := m.events.oldestID()
+	if !ok {
+		return nil
+	}
+
+	// Keep the view still while browsing history
+	m.setPaused(true)
+	m.loadingHistory = true
+	history := m.history
+	return func() tea.Msg {
+		events, err := history(oldest, historyPageSize)
+		return olderEventsMsg{events: events, err: err}
+	}
+}
+
+// setPaused stops or resumes auto-scroll. Resuming returns to the newest
+// events and releases any history loaded from the database.
+func (m *model) setPaused(paused bool) {
+	m.paused = paused
+	m.events.follow = !paused
+	if !paused {
+		m.events.dropOlder()
+		m.events.gotoBottom()
+		m.historyDone = false
+		m.historyErr = nil
+	}
+}
+
+func (m *model) setQuery(query string) {
+	m.filter.query = query
+	m.refresh()
+}
+
+// jumpToMatch scrolls to the next (dir 1) or previous (dir -1) search match
+// and pauses auto-scroll so the match stays on screen
+func (m *model) jumpToMatch(dir int) {
+	if len(m.events.matches) == 0 {
+		return
+	}
+	m.setPaused(true)
+	m.events.jumpToMatch(dir)
+}
+
+func (m *model) liveHeader() string {
+	if m.title != "" {
+		return titleStyle.Render(m.title)
+	}
+	return titleStyle.Render(fmt.Sprintf("Live Stream: @%s", m.username))
+}
+
+func (m *model) liveFooter() string {
+	var status []string
+	for i, t := range eventTypes {
+		label := fmt.Sprintf("%d:%s", i+1, t)
+		if m.filter.hidden[t] {
+			if monochrome {
+				label = "(" + label + ")"
+			}
+			status = append(status, hiddenStyle.Render(label))
+		} else {
+			status = append(status, typeStyles[t].Render(label))
+		}
+	}
+	if m.filter.flaggedOnly {
+		status = append(status, flaggedStyle.Render("flagged only"))
+	}
+	if m.filter.language != "" {
+		status = append(status, successStyle.Render("chat: "+m.filter.language))
+	}
+	if m.paused {
+		status = append(status, successStyle.Render("PAUSED"))
+	}
+	switch {
+	case m.loadingHistory:
+		status = append(status, "loading older events…")
+	case m.historyErr != nil:
+		status = append(status, errorStyle.Render(fmt.Sprintf("failed to load older events: %v", m.historyErr)))
+	case m.historyDone && m.events.atTop():
+		status = append(status, "start of stream")
+	}
+	if m.filter.query != "" {
+		status = append(status, fmt.Sprintf("%d matches for %q", len(m.events.matches), m.filter.query))
+	}
+	if m.lastErr != nil {
+		// Cut long errors short instead of wrapping the footer
+		style := errorStyle
+		if m.width > 0 {
+			style = style.MaxWidth(m.width / 2)
+		}
+		status = append(status, style.Render(fmt.Sprintf("%s %v", m.lastErrAt.Format("15:04:05"), m.lastErr)))
+	}
+
+	bottom := m.help.View(m.keys)
+	if m.searching {
+		bottom = m.search.View() + "  " + m.help.View(m.searchKeys)
+	}
+	return infoStyle.Render(strings.Join(status, " ")) + "\n" + bottom
+}
+
+// bodyHeight is the height left for the panes between the header and footer
+func (m *model) bodyHeight() int {
+	height := m.height - lipgloss.Height(m.liveHeader()) - lipgloss.Height(m.liveFooter())
+	return max(height, panelStyle.GetVerticalFrameSize()+2)
 }
 
-func (m *model) updateStats() {
-	rows := []table.Row{
-		{"Viewers", fmt.Sprintf("%d", m.stats["viewers"])},
-		{"Likes", fmt.Sprintf("%d", m.stats["likes"])},
-		{"Shares", fmt.Sprintf("%d", m.stats["shares"])},
-		{"Comments", fmt.Sprintf("%d", m.stats["comments"])},
+// resize fits the pane viewports into the current layout
+func (m *model) resize() {
+	if m.width == 0 {
+		return
+	}
+	m.help.Width = m.width
+	rects := m.layoutPanes(m.width, m.bodyHeight())
+	if r := rects[paneChat]; r.width > 0 {
+		m.events.setSize(innerSize(r))
+	}
+	if r := rects[paneFeed]; r.width > 0 {
+		m.feed.setSize(innerSize(r))
+	}
+}
+
+// adjustLayout grows the chat pane by dw and the focused side pane by dh
+// percentage points
+func (m *model) adjustLayout(dw, dh int) {
+	m.layout.ChatWidth += dw
+	switch m.focus {
+	case paneFeed:
+		m.layout.FeedHeight += dh
+	case paneStats:
+		m.layout.StatsHeight += dh
+	case paneLeaderboard:
+		// The leaderboard takes what the other two leave
+		m.layout.FeedHeight -= dh / 2
+		m.layout.StatsHeight -= dh - dh/2
+	}
+	m.layout = m.layout.normalize()
+	m.resize()
+}
+
+// nextLanguage cycles the language filter through the languages seen in
+// chat, most used first, and back to all languages
+func (m *model) nextLanguage() string {
+	languages := make([]string, 0, len(m.languages))
+	for l := range m.languages {
+		languages = append(languages, l)
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		if m.languages[languages[i]] != m.languages[languages[j]] {
+			return m.languages[languages[i]] > m.languages[languages[j]]
+		}
+		return languages[i] < languages[j]
+	})
+
+	if m.filter.language == "" {
+		if len(languages) == 0 {
+			return ""
+		}
+		return languages[0]
 	}
-	m.table.SetRows(rows)
+	for i, l := range languages {
+		if l == m.filter.language && i+1 < len(languages) {
+			return languages[i+1]
+		}
+	}
+	return ""
+}
+
+// refresh applies filter changes to the event list
+func (m *model) refresh() {
+	m.events.setFilter(m.filter)
 }
 
-func (m *model) formatEvents() string {
-	var s string
-	for _, event := range m.events {
-		s += fmt.Sprintf("%s\n", event)
+// tally updates the stats, leaderboard and feed with a new event
+func (m *model) tally(event Event) {
+	switch event.Type {
+	case "chat":
+		m.stats["comments"]++
+		// Messages of undetermined language ("und") aren't worth a filter
+		if event.Language != "" && event.Language != "und" {
+			m.languages[event.Language]++
+		}
+	case "gift":
+		m.stats["gifts"]++
+		m.stats["diamonds"] += event.Value
+		if event.Nickname != "" {
+			m.gifters[event.Nickname] += event.Value
+		}
+	case "like":
+		m.stats["likes"] += event.Value
+	case "follow":
+		m.stats["follows"]++
+	case "share":
+		m.stats["shares"]++
+	case "stats":
+		m.stats["viewers"] = event.Value
+		m.stats["peak_viewers"] = max(m.stats["peak_viewers"], event.Value)
+		m.viewerHistory = append(m.viewerHistory, event.Value)
+		if len(m.viewerHistory) > maxViewerHistory {
+			m.viewerHistory = m.viewerHistory[len(m.viewerHistory)-maxViewerHistory:]
+		}
+	}
+
+	switch event.Type {
+	case "gift", "follow", "share":
+		m.feed.push(event)
 	}
-	return s
 }
 
-func (m *model) AddEvent(event string) {
-	m.events = append(m.events, event)
-	m.viewport.SetContent(m.formatEvents())
-	m.viewport.GotoBottom()
+func (m *model) AddEvent(event Event) {
+	metrics.EventsRendered.WithLabelValues(m.username).Inc()
+	m.addEvent(event)
+}
+
+// addEvent shows an event without counting it as rendered live
+func (m *model) addEvent(event Event) {
+	m.events.push(event)
+	m.tally(event)
 }
 
 func (m *model) UpdateStats(stats map[string]int64) {
 	m.stats = stats
-	m.updateStats()
 }
 
+// SetBufferSize sets how many events the live view keeps in memory
+func (m *model) SetBufferSize(size int) {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	m.events.resizeBuffer(size)
+}
+
+// SetHistory sets the source of events older than the in-memory buffer
+func (m *model) SetHistory(history HistoryFunc) {
+	m.history = history
+}
+
+// SetLayout applies a dashboard layout, e.g. one loaded from the config file
+func (m *model) SetLayout(layout Layout) {
+	m.layout = layout.normalize()
+	m.focus = layout.focus()
+	m.resize()
+}
+
+// Layout returns the current dashboard layout including the focused pane
+func (m model) Layout() Layout {
+	layout := m.layout
+	layout.Focus = paneNames[m.focus]
+	return layout
+}
+
+// SetError replaces the dashboard with an error that ends the program
 func (m *model) SetError(err error) {
 	m.err = err
-	m.loading = false
 }
 
-func (m *model) SetListItems(items []list.Item) {
-	m.list.SetItems(items)
+// SetTitle replaces the "Live Stream" title, e.g. for replays
+func (m *model) SetTitle(title string) {
+	m.title = title
 }
-
-func (m *model) SetViewMode(showList bool) {
-	m.showList = showList
-} 
\ No newline at end of file